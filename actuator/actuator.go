@@ -0,0 +1,136 @@
+package actuator
+
+import (
+	"fmt"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Charge/discharge limits pushed to the external charger or inverter
+type Limits struct {
+	ChargeVoltage         float32 `json:"charge_voltage"`          // V
+	ChargeCurrentLimit    float32 `json:"charge_current_limit"`    // A
+	DischargeCurrentLimit float32 `json:"discharge_current_limit"` // A
+	DischargeVoltage      float32 `json:"discharge_voltage"`       // V
+}
+
+// LimitsWriter delivers limits to a device (Modbus registers, CAN frames...)
+type LimitsWriter interface {
+	WriteLimits(limits Limits) error
+}
+
+// Taper configuration used by DefaultLimits
+type TaperConfig struct {
+	MaxChargeCurrent    float32 // A, applied below TaperStartVoltage
+	MaxDischargeCurrent float32 // A, applied above DischargeCutoffVoltage
+	TaperStartVoltage   float32 // V per cell, charge current starts decreasing here
+	CellFullVoltage     float32 // V per cell, charge current reaches zero here
+	ChargeVoltage       float32 // V, pack charge voltage reported to the charger
+	DischargeVoltage    float32 // V, pack minimum voltage reported to the inverter
+	CellEmptyVoltage    float32 // V per cell, discharge current reaches zero here
+	DischargeTaperStart float32 // V per cell, discharge current starts decreasing here
+}
+
+// Computes limits from BMS data
+type LimitsFunc func(data *dalybms.AllStatusData) Limits
+
+// DefaultLimits returns a LimitsFunc that tapers currents linearly on the highest/lowest cell voltage
+func DefaultLimits(config TaperConfig) LimitsFunc {
+	return func(data *dalybms.AllStatusData) Limits {
+		limits := Limits{
+			ChargeVoltage:         config.ChargeVoltage,
+			ChargeCurrentLimit:    config.MaxChargeCurrent,
+			DischargeCurrentLimit: config.MaxDischargeCurrent,
+			DischargeVoltage:      config.DischargeVoltage,
+		}
+		if data == nil || data.CellVoltageRange == nil {
+			return limits
+		}
+
+		highest := data.CellVoltageRange.HighestVoltage
+		limits.ChargeCurrentLimit = taper(config.MaxChargeCurrent, highest, config.TaperStartVoltage, config.CellFullVoltage)
+
+		lowest := data.CellVoltageRange.LowestVoltage
+		limits.DischargeCurrentLimit = taper(config.MaxDischargeCurrent, lowest, config.DischargeTaperStart, config.CellEmptyVoltage)
+
+		// never ask for current the BMS won't allow
+		if data.MosfetStatus != nil {
+			if !data.MosfetStatus.ChargingMosfet {
+				limits.ChargeCurrentLimit = 0
+			}
+			if !data.MosfetStatus.DischargingMosfet {
+				limits.DischargeCurrentLimit = 0
+			}
+		}
+		return limits
+	}
+}
+
+// ChargeLimitsFunc returns a LimitsFunc using the currents of AllStatusData.ChargeLimits(config)
+// and fixed charge/discharge voltages
+func ChargeLimitsFunc(config dalybms.ChargeLimitConfig, chargeVoltage, dischargeVoltage float32) LimitsFunc {
+	return func(data *dalybms.AllStatusData) Limits {
+		limits := Limits{
+			ChargeVoltage:    chargeVoltage,
+			DischargeVoltage: dischargeVoltage,
+		}
+		if data == nil {
+			return limits
+		}
+		currents := data.ChargeLimits(config)
+		limits.ChargeCurrentLimit = float32(currents.ChargeCurrentLimit)
+		limits.DischargeCurrentLimit = float32(currents.DischargeCurrentLimit)
+		return limits
+	}
+}
+
+// taper scales maxCurrent linearly from start (full current) to end (zero current).
+// Works in both directions, so it serves charge (rising) and discharge (falling) voltages.
+func taper(maxCurrent, voltage, start, end float32) float32 {
+	if start == end {
+		return maxCurrent
+	}
+	ratio := (end - voltage) / (end - start)
+	if ratio >= 1 {
+		return maxCurrent
+	}
+	if ratio <= 0 {
+		return 0
+	}
+	return maxCurrent * ratio
+}
+
+// Actuator computes limits from BMS data and pushes them to one or more devices
+type Actuator struct {
+	Limits  LimitsFunc
+	Writers []LimitsWriter
+	latest  *Limits
+}
+
+func New(limits LimitsFunc, writers ...LimitsWriter) *Actuator {
+	return &Actuator{
+		Limits:  limits,
+		Writers: writers,
+	}
+}
+
+// Apply computes the limits for the given data and writes them to every device
+func (act *Actuator) Apply(data *dalybms.AllStatusData) (*Limits, error) {
+	if act.Limits == nil {
+		return nil, fmt.Errorf("no limits function configured")
+	}
+
+	limits := act.Limits(data)
+	for writerIndex, writer := range act.Writers {
+		if err := writer.WriteLimits(limits); err != nil {
+			return &limits, fmt.Errorf("writer %d failed: %w", writerIndex, err)
+		}
+	}
+	act.latest = &limits
+	return &limits, nil
+}
+
+// Latest limits successfully written, nil if none yet
+func (act *Actuator) Latest() *Limits {
+	return act.latest
+}
@@ -0,0 +1,38 @@
+package actuator
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CANSender sends a single CAN frame, eg through a SocketCAN socket opened by the caller
+type CANSender interface {
+	SendFrame(id uint32, data []byte) error
+}
+
+// CANWriter sends the limits as a single frame using the common 0x351 layout
+// (charge voltage, CCL, DCL, discharge voltage; little-endian, 0.1 units)
+type CANWriter struct {
+	Sender CANSender
+	ID     uint32 // 0x351 by default
+}
+
+func NewCANWriter(sender CANSender) *CANWriter {
+	return &CANWriter{
+		Sender: sender,
+		ID:     0x351,
+	}
+}
+
+func (writer *CANWriter) WriteLimits(limits Limits) error {
+	frame := make([]byte, 8)
+	binary.LittleEndian.PutUint16(frame[0:2], uint16(int16(limits.ChargeVoltage*10)))
+	binary.LittleEndian.PutUint16(frame[2:4], uint16(int16(limits.ChargeCurrentLimit*10)))
+	binary.LittleEndian.PutUint16(frame[4:6], uint16(int16(limits.DischargeCurrentLimit*10)))
+	binary.LittleEndian.PutUint16(frame[6:8], uint16(int16(limits.DischargeVoltage*10)))
+
+	if err := writer.Sender.SendFrame(writer.ID, frame); err != nil {
+		return fmt.Errorf("failed to send CAN frame %03x: %w", writer.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package actuator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Holding register holding a limit. Value written is round(limit * Scale).
+type ModbusRegister struct {
+	Address uint16
+	Scale   float32
+}
+
+// Modbus RTU register map for the limits, nil registers aren't written
+type ModbusRegisters struct {
+	ChargeVoltage         *ModbusRegister
+	ChargeCurrentLimit    *ModbusRegister
+	DischargeCurrentLimit *ModbusRegister
+	DischargeVoltage      *ModbusRegister
+}
+
+// ModbusException is returned when the device rejects a register write
+type ModbusException struct {
+	Register uint16
+	Code     byte // eg 2 = illegal data address, 3 = illegal data value
+}
+
+func (exception *ModbusException) Error() string {
+	return fmt.Sprintf("modbus exception %d writing register %d", exception.Code, exception.Register)
+}
+
+// ModbusWriter writes limits to holding registers with function 0x06 (write single register)
+type ModbusWriter struct {
+	Port      io.ReadWriter // eg a serial port opened by the caller
+	SlaveID   byte
+	Registers ModbusRegisters
+}
+
+func NewModbusWriter(port io.ReadWriter, slaveID byte, registers ModbusRegisters) *ModbusWriter {
+	return &ModbusWriter{
+		Port:      port,
+		SlaveID:   slaveID,
+		Registers: registers,
+	}
+}
+
+func (writer *ModbusWriter) WriteLimits(limits Limits) error {
+	values := []struct {
+		register *ModbusRegister
+		value    float32
+	}{
+		{writer.Registers.ChargeVoltage, limits.ChargeVoltage},
+		{writer.Registers.ChargeCurrentLimit, limits.ChargeCurrentLimit},
+		{writer.Registers.DischargeCurrentLimit, limits.DischargeCurrentLimit},
+		{writer.Registers.DischargeVoltage, limits.DischargeVoltage},
+	}
+
+	for _, entry := range values {
+		if entry.register == nil {
+			continue
+		}
+		rawValue := uint16(int32(math.Round(dalybms.Widen(entry.value) * dalybms.Widen(entry.register.Scale))))
+		if err := writer.writeRegister(entry.register.Address, rawValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRegister sends a single register write and checks the echoed response
+func (writer *ModbusWriter) writeRegister(address uint16, value uint16) error {
+	request := make([]byte, 6, 8)
+	request[0] = writer.SlaveID
+	request[1] = 0x06
+	binary.BigEndian.PutUint16(request[2:4], address)
+	binary.BigEndian.PutUint16(request[4:6], value)
+	request = binary.LittleEndian.AppendUint16(request, modbusCRC(request))
+
+	if _, err := writer.Port.Write(request); err != nil {
+		return fmt.Errorf("failed to write modbus register %d: %w", address, err)
+	}
+
+	// A successful response echoes the request, an exception is shorter: slave ID,
+	// function | 0x80, exception code and CRC
+	response := make([]byte, 2, len(request))
+	if _, err := io.ReadFull(writer.Port, response); err != nil {
+		return fmt.Errorf("no modbus response for register %d: %w", address, err)
+	}
+	if response[1]&0x80 != 0 {
+		response = response[:5]
+		if _, err := io.ReadFull(writer.Port, response[2:]); err != nil {
+			return fmt.Errorf("incomplete modbus exception for register %d: %w", address, err)
+		}
+		if binary.LittleEndian.Uint16(response[3:]) != modbusCRC(response[:3]) {
+			return fmt.Errorf("invalid CRC in modbus exception for register %d: %x", address, response)
+		}
+		return &ModbusException{Register: address, Code: response[2]}
+	}
+	response = response[:len(request)]
+	if _, err := io.ReadFull(writer.Port, response[2:]); err != nil {
+		return fmt.Errorf("no modbus response for register %d: %w", address, err)
+	}
+	if string(response) != string(request) {
+		return fmt.Errorf("unexpected modbus response for register %d: %x", address, response)
+	}
+	return nil
+}
+
+// modbusCRC computes the Modbus RTU CRC16 (poly 0xA001, init 0xFFFF)
+func modbusCRC(message []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, singleByte := range message {
+		crc ^= uint16(singleByte)
+		for bitIndex := 0; bitIndex < 8; bitIndex++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
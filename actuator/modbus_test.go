@@ -0,0 +1,49 @@
+package actuator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// modbusPort records the requests and answers with canned responses
+type modbusPort struct {
+	written   bytes.Buffer
+	responses bytes.Buffer
+}
+
+func (port *modbusPort) Write(data []byte) (int, error) { return port.written.Write(data) }
+func (port *modbusPort) Read(data []byte) (int, error)  { return port.responses.Read(data) }
+
+func withCRC(message ...byte) []byte {
+	return binary.LittleEndian.AppendUint16(message, modbusCRC(message))
+}
+
+func TestWriteLimitsSkipsNilRegisters(t *testing.T) {
+	port := &modbusPort{}
+	port.responses.Write(withCRC(1, 0x06, 0x00, 0x10, 0x02, 0x0d)) // echo of 52.5 V * 10 to register 16
+	writer := NewModbusWriter(port, 1, ModbusRegisters{ChargeVoltage: &ModbusRegister{Address: 16, Scale: 10}})
+
+	if err := writer.WriteLimits(Limits{ChargeVoltage: 52.5, ChargeCurrentLimit: 50}); err != nil {
+		t.Fatalf("WriteLimits: %v", err)
+	}
+	if want := withCRC(1, 0x06, 0x00, 0x10, 0x02, 0x0d); !bytes.Equal(port.written.Bytes(), want) {
+		t.Errorf("written %x, want only %x", port.written.Bytes(), want)
+	}
+}
+
+func TestWriteLimitsException(t *testing.T) {
+	port := &modbusPort{}
+	port.responses.Write(withCRC(1, 0x86, 0x02)) // illegal data address
+	writer := NewModbusWriter(port, 1, ModbusRegisters{ChargeCurrentLimit: &ModbusRegister{Address: 17, Scale: 10}})
+
+	err := writer.WriteLimits(Limits{ChargeCurrentLimit: 50})
+	var exception *ModbusException
+	if !errors.As(err, &exception) {
+		t.Fatalf("WriteLimits error = %v, want a *ModbusException", err)
+	}
+	if exception.Code != 2 || exception.Register != 17 {
+		t.Errorf("exception = %+v, want code 2 for register 17", *exception)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Exit codes of check, the Nagios plugin convention
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStates = map[int]string{checkOK: "OK", checkWarning: "WARNING", checkCritical: "CRITICAL", checkUnknown: "UNKNOWN"}
+
+// checkSeverity orders the states, a missing value is less severe than a crossed threshold
+var checkSeverity = map[int]int{checkOK: 0, checkUnknown: 1, checkWarning: 2, checkCritical: 3}
+
+// exitError ends the CLI with code after printing its message, see main
+type exitError struct {
+	code    int
+	message string
+}
+
+func (exit *exitError) Error() string {
+	return exit.message
+}
+
+// checkThreshold is a metric compared to a warning and a critical level, 0 disables a level
+type checkThreshold struct {
+	name      string
+	metric    dalybms.AlarmMetric
+	condition dalybms.AlarmCondition
+	warning   float64
+	critical  float64
+	format    string // of the value and the levels, eg "%.1f %%"
+}
+
+// evaluate returns the state of the threshold for data and a description when it isn't OK
+func (threshold *checkThreshold) evaluate(data *dalybms.AllStatusData) (int, string) {
+	value, ok := threshold.metric(data)
+	if !ok {
+		return checkUnknown, threshold.name + " unavailable"
+	}
+	crossed := func(level float64) bool {
+		if level == 0 {
+			return false
+		}
+		if threshold.condition == dalybms.AlarmBelow {
+			return value < level
+		}
+		return value > level
+	}
+	comparison := ">"
+	if threshold.condition == dalybms.AlarmBelow {
+		comparison = "<"
+	}
+
+	describe := func(level float64) string {
+		return fmt.Sprintf("%s "+threshold.format+" %s "+threshold.format, threshold.name, value, comparison, level)
+	}
+	switch {
+	case crossed(threshold.critical):
+		return checkCritical, describe(threshold.critical)
+	case crossed(threshold.warning):
+		return checkWarning, describe(threshold.warning)
+	}
+	return checkOK, ""
+}
+
+// unknownCheck ends check with the UNKNOWN state for err
+func unknownCheck(err error) *exitError {
+	return &exitError{code: checkUnknown, message: fmt.Sprintf("%s - %v", checkStates[checkUnknown], err)}
+}
+
+func runCheck(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("check", &options)
+	soc := checkThreshold{name: "SOC", metric: dalybms.MetricSOC, condition: dalybms.AlarmBelow, format: "%.1f%%"}
+	delta := checkThreshold{name: "cell delta", metric: dalybms.MetricCellVoltageDelta, format: "%.3f V"}
+	flags.Float64Var(&soc.warning, "warn-soc", 20, "warning below this SOC in %, 0 = never")
+	flags.Float64Var(&soc.critical, "crit-soc", 10, "critical below this SOC in %, 0 = never")
+	flags.Float64Var(&delta.warning, "warn-delta", 0.1, "warning above this cell voltage delta in V, 0 = never")
+	flags.Float64Var(&delta.critical, "crit-delta", 0.2, "critical above this cell voltage delta in V, 0 = never")
+	ignoreErrors := flags.Bool("ignore-errors", false, "don't report the error flags of the BMS")
+	// usage errors are UNKNOWN too, exit 1 would read as WARNING
+	if err := options.parse(flags, args, 0); err != nil {
+		return unknownCheck(err)
+	}
+	if options.port == "" {
+		return unknownCheck(fmt.Errorf("no serial port, set -port or DALYBMS_PORT"))
+	}
+
+	state, problems := checkOK, []string(nil)
+	report := func(problemState int, problem string) {
+		if checkSeverity[problemState] > checkSeverity[state] {
+			state = problemState
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return unknownCheck(err)
+	}
+	defer bms.Disconnect()
+	data, err := bms.GetAllData()
+	if err != nil {
+		return unknownCheck(err)
+	}
+
+	report(soc.evaluate(data))
+	report(delta.evaluate(data))
+	if !*ignoreErrors {
+		// warning flags are warnings, alarms and hardware failures critical
+		for _, bmsError := range data.Errors {
+			errorState := checkWarning
+			if bmsError.Severity == dalybms.SeverityAlarm {
+				errorState = checkCritical
+			}
+			report(errorState, bmsError.Message)
+		}
+	}
+
+	summary := fmt.Sprintf("SOC %.1f%%, %.1f V, %.1f A, cell delta %.3f V", data.SOC.SOCPercent, data.SOC.TotalVoltage, data.SOC.Current, data.Stats().CellVoltageDelta)
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ")
+	}
+	// performance data after the pipe, graphed by Nagios compatible tools
+	message := fmt.Sprintf("%s - %s | soc=%.1f%%;%g;%g voltage=%.1fV current=%.1fA cell_delta=%.3fV;%g;%g errors=%d",
+		checkStates[state], summary,
+		data.SOC.SOCPercent, soc.warning, soc.critical, data.SOC.TotalVoltage, data.SOC.Current,
+		data.Stats().CellVoltageDelta, delta.warning, delta.critical, len(data.Errors))
+	if state == checkOK {
+		fmt.Println(message)
+		return nil
+	}
+	return &exitError{code: state, message: message}
+}
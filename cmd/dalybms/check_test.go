@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckUsageErrorsAreUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown flag", []string{"-bogus"}},
+		{"invalid value", []string{"-warn-soc", "low"}},
+		{"extra argument", []string{"extra"}},
+		{"empty port", []string{"-port", ""}},
+	}
+	for _, test := range tests {
+		err := runCheck(test.args)
+		var exit *exitError
+		if !errors.As(err, &exit) {
+			t.Errorf("%s: error = %v, want an *exitError", test.name, err)
+			continue
+		}
+		if exit.code != checkUnknown {
+			t.Errorf("%s: exit code %d, want %d (UNKNOWN)", test.name, exit.code, checkUnknown)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/datalogger"
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+func runStatus(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("status", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	data, err := bms.GetAllData()
+	if err != nil {
+		return err
+	}
+	return printValue(options.format, data, func(writer io.Writer) {
+		printStatus(writer, data)
+	}, func() [][]string {
+		return sampleTable(sampleColumns(data), dalybms.PollResult{Data: data, Time: data.Time})
+	})
+}
+
+func runCells(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("cells", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	status, err := bms.GetStatus()
+	if err != nil {
+		return err
+	}
+	cellVoltages, err := bms.GetCellVoltages()
+	if err != nil {
+		return err
+	}
+	balancing, err := bms.GetBalancingStatus()
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[int]string, status.NumberOfCells)
+	for cellIndex := range cellVoltages {
+		labels[cellIndex] = bms.CellLabel(cellIndex)
+	}
+
+	output := struct {
+		CellVoltages    map[int]float64 `json:"cell_voltages"`
+		BalancingStatus map[int]bool    `json:"balancing_status"`
+		CellLabels      map[int]string  `json:"cell_labels"`
+	}{cellVoltages, balancing, labels}
+	return printValue(options.format, output, func(writer io.Writer) {
+		printCells(writer, cellVoltages, balancing, labels)
+	}, func() [][]string {
+		table := [][]string{{"cell", "label", "voltage", "balancing"}}
+		for _, cellIndex := range sortedKeys(cellVoltages) {
+			table = append(table, []string{strconv.Itoa(cellIndex), labels[cellIndex], formatFloat(cellVoltages[cellIndex]), strconv.FormatBool(balancing[cellIndex])})
+		}
+		return table
+	})
+}
+
+func runTemperatures(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("temps", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	temperatures, err := bms.GetTemperatures()
+	if err != nil {
+		return err
+	}
+	return printValue(options.format, temperatures, func(writer io.Writer) {
+		printTemperatures(writer, temperatures, options.tempUnit)
+	}, func() [][]string {
+		table := [][]string{{"sensor", "temperature", "unit"}}
+		for _, sensorIndex := range sortedKeys(temperatures) {
+			table = append(table, []string{strconv.Itoa(sensorIndex), formatFloat(temperatures[sensorIndex]), options.tempUnit.Symbol()})
+		}
+		return table
+	})
+}
+
+func runErrors(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("errors", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	errorsList, err := bms.GetErrors()
+	if err != nil {
+		return err
+	}
+	return printValue(options.format, errorsList, func(writer io.Writer) {
+		if len(errorsList) == 0 {
+			fmt.Fprintln(writer, "no errors")
+		}
+		for _, bmsError := range errorsList {
+			fmt.Fprintf(writer, "%-7s %-11s %s\n", bmsError.Severity, bmsError.Category, bmsError.Message)
+		}
+	}, func() [][]string {
+		table := [][]string{{"code", "byte", "bit", "severity", "category", "message"}}
+		for _, bmsError := range errorsList {
+			table = append(table, []string{strconv.Itoa(bmsError.Code), strconv.Itoa(bmsError.Byte), strconv.Itoa(bmsError.Bit),
+				string(bmsError.Severity), string(bmsError.Category), bmsError.Message})
+		}
+		return table
+	})
+}
+
+func runInfo(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("info", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	var info struct {
+		FirmwareVersion string `json:"firmware_version"`
+		HardwareVersion string `json:"hardware_version"`
+		BatteryCode     string `json:"battery_code"`
+		*dalybms.RatedParams
+	}
+	if info.FirmwareVersion, err = bms.GetFirmwareVersion(); err != nil {
+		return err
+	}
+	if info.HardwareVersion, err = bms.GetHardwareVersion(); err != nil {
+		return err
+	}
+	if info.BatteryCode, err = bms.GetBatteryCode(); err != nil {
+		return err
+	}
+	if info.RatedParams, err = bms.GetRatedParams(); err != nil {
+		return err
+	}
+	return printValue(options.format, info, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Firmware:     %s\n", info.FirmwareVersion)
+		fmt.Fprintf(writer, "Hardware:     %s\n", info.HardwareVersion)
+		fmt.Fprintf(writer, "Battery code: %s\n", info.BatteryCode)
+		fmt.Fprintf(writer, "Rated:        %.1f Ah, %.3f V per cell\n", info.RatedCapacityAh, info.NominalCellVoltage)
+	}, func() [][]string {
+		return [][]string{
+			{"firmware_version", "hardware_version", "battery_code", "rated_capacity_ah", "nominal_cell_voltage"},
+			{info.FirmwareVersion, info.HardwareVersion, info.BatteryCode, formatFloat32(info.RatedCapacityAh), formatFloat32(info.NominalCellVoltage)},
+		}
+	})
+}
+
+func runSetSOC(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("set-soc", &options)
+	if err := options.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	socPercent, err := strconv.ParseFloat(flags.Arg(0), 64)
+	if err != nil || socPercent < 0 || socPercent > 100 {
+		return fmt.Errorf("invalid SOC percentage: %s", flags.Arg(0))
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	return bms.SetSOC(socPercent)
+}
+
+func runMosfet(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("mosfet", &options)
+	if err := options.parse(flags, args, 2); err != nil {
+		return err
+	}
+
+	var isOn bool
+	switch flags.Arg(1) {
+	case "on":
+		isOn = true
+	case "off":
+		isOn = false
+	default:
+		return fmt.Errorf("expected on or off, got %s", flags.Arg(1))
+	}
+
+	var setMosfet func(bms *dalybms.DalyBMSIstance) error
+	switch flags.Arg(0) {
+	case "charge":
+		setMosfet = func(bms *dalybms.DalyBMSIstance) error { return bms.EnableChargeMosfet(isOn) }
+	case "discharge":
+		setMosfet = func(bms *dalybms.DalyBMSIstance) error { return bms.EnableDischargeMosfet(isOn) }
+	default:
+		return fmt.Errorf("expected charge or discharge, got %s", flags.Arg(0))
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	return setMosfet(bms)
+}
+
+func runRaw(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("raw", &options)
+	payloadHex := flags.String("payload", "", "request data bytes in hex, eg 0102")
+	if err := options.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	command, err := strconv.ParseUint(strings.TrimPrefix(flags.Arg(0), "0x"), 16, 8)
+	if err != nil {
+		return fmt.Errorf("invalid command code: %s", flags.Arg(0))
+	}
+	payload, err := hex.DecodeString(*payloadHex)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	frames, err := bms.SendRaw(byte(command), payload)
+	if err != nil {
+		return err
+	}
+	hexFrames := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		hexFrames = append(hexFrames, hex.EncodeToString(frame.Data))
+	}
+	return printValue(options.format, hexFrames, func(writer io.Writer) {
+		for _, hexFrame := range hexFrames {
+			fmt.Fprintln(writer, hexFrame)
+		}
+	}, func() [][]string {
+		table := [][]string{{"frame", "data"}}
+		for frameIndex, hexFrame := range hexFrames {
+			table = append(table, []string{strconv.Itoa(frameIndex + 1), hexFrame})
+		}
+		return table
+	})
+}
+
+func runRestart(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("restart", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	return bms.Restart()
+}
+
+func runSleep(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("sleep", &options)
+	if err := options.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	command, err := strconv.ParseUint(strings.TrimPrefix(flags.Arg(0), "0x"), 16, 8)
+	if err != nil {
+		return fmt.Errorf("invalid command code: %s", flags.Arg(0))
+	}
+
+	bms, err := options.connect(dalybms.WithSleepCommand(protocol.Command(command)))
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	return bms.Sleep()
+}
+
+func runWake(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("wake", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	bms, err := options.connect()
+	if err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	return bms.Wake()
+}
+
+func runProbe(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("probe", &options)
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	result, err := dalybms.Probe(options.port)
+	if err != nil {
+		return err
+	}
+	return printValue(options.format, result, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Address:   %d\n", result.Address)
+		fmt.Fprintf(writer, "Baud rate: %d\n", result.BaudRate)
+		fmt.Fprintf(writer, "Protocol:  %s\n", result.Protocol)
+	}, func() [][]string {
+		return [][]string{
+			{"address", "baud_rate", "protocol"},
+			{strconv.Itoa(result.Address), strconv.Itoa(result.BaudRate), fmt.Sprint(result.Protocol)},
+		}
+	})
+}
+
+func runPorts(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("ports", &options)
+	probe := flags.Bool("probe", false, "probe each port for a BMS, a few seconds per silent port")
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	ports, err := dalybms.ListSerialPorts()
+	if err != nil {
+		return err
+	}
+	type portOutput struct {
+		dalybms.SerialPortInfo
+		Probe *dalybms.ProbeResult `json:"probe,omitempty"`
+	}
+	output := make([]portOutput, 0, len(ports))
+	for _, port := range ports {
+		entry := portOutput{SerialPortInfo: port}
+		if *probe {
+			entry.Probe, _ = dalybms.Probe(port.Path)
+		}
+		output = append(output, entry)
+	}
+
+	// the BMS found on a port, empty when not probed or silent
+	probed := func(entry portOutput) string {
+		if entry.Probe == nil {
+			return ""
+		}
+		return fmt.Sprintf("address %d, %d baud, %s", entry.Probe.Address, entry.Probe.BaudRate, entry.Probe.Protocol)
+	}
+	return printValue(options.format, output, func(writer io.Writer) {
+		if len(output) == 0 {
+			fmt.Fprintln(writer, "no serial ports found")
+		}
+		for _, entry := range output {
+			if bms := probed(entry); bms != "" {
+				fmt.Fprintf(writer, "%s: BMS at %s\n", entry.SerialPortInfo, bms)
+				continue
+			}
+			fmt.Fprintln(writer, entry.SerialPortInfo)
+		}
+	}, func() [][]string {
+		table := [][]string{{"path", "description", "usb", "vendor_id", "product_id", "serial_number", "bms"}}
+		for _, entry := range output {
+			table = append(table, []string{entry.Path, entry.Description, strconv.FormatBool(entry.USB),
+				entry.VendorID, entry.ProductID, entry.SerialNumber, probed(entry)})
+		}
+		return table
+	})
+}
+
+func runWatch(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("watch", &options)
+	interval := flags.Int("interval", 5, "seconds between samples")
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %d", *interval)
+	}
+
+	bms := options.newBMS()
+	poller := dalybms.NewPoller(bms, dalybms.SerialConnectorWithConfig(options.port, options.serial), time.Duration(*interval)*time.Second)
+	results := poller.Subscribe(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go poller.Run(ctx)
+
+	// the channel is closed once interrupted and the port is closed
+	stream := newStreamPrinter(options.format)
+	var columns []datalogger.Column // of the first sample, the CSV header is written once
+	for result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", result.Err)
+			continue
+		}
+		if columns == nil {
+			columns = sampleColumns(result.Data)
+		}
+		err := stream.print(result, func(writer io.Writer) {
+			fmt.Fprintf(writer, "--- %s\n", result.Time.Format(time.RFC3339))
+			printStatus(writer, result.Data)
+		}, func() [][]string {
+			return sampleTable(columns, result)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
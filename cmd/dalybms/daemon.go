@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/datalogger"
+	"github.com/jonamat/go-daly-bms/httpserver"
+	"github.com/jonamat/go-daly-bms/influx"
+	"github.com/jonamat/go-daly-bms/modbus"
+	"github.com/jonamat/go-daly-bms/mqtt"
+	"github.com/jonamat/go-daly-bms/prometheus"
+)
+
+// daemonConfig is the YAML configuration of the daemon command, see the README for an example
+type daemonConfig struct {
+	Serial struct {
+		Port    string   `json:"port"`
+		Baud    int      `json:"baud"`
+		Address int      `json:"address"`
+		Timeout duration `json:"timeout"`
+	} `json:"serial"`
+	Interval duration `json:"interval"`
+
+	// per group intervals: soc, cells, temperatures, status
+	Intervals map[string]duration `json:"intervals"`
+
+	// language of the error messages, see dalybms.ErrorLanguages(), default en
+	Language string `json:"language"`
+
+	// celsius, fahrenheit or kelvin, default celsius
+	TemperatureUnit dalybms.TemperatureUnit `json:"temperature_unit"`
+
+	// temperature sensor of the MOSFETs, 0 = none
+	MOSTemperatureSensor int `json:"mos_temperature_sensor"`
+
+	// the latest successful sample must be more recent for /healthz and the systemd
+	// watchdog, default 3 intervals, at least 30s
+	MaxSampleAge duration `json:"max_sample_age"`
+
+	MQTT *struct {
+		Broker          string `json:"broker"`
+		Username        string `json:"username"`
+		Password        string `json:"password"`
+		ClientID        string `json:"client_id"`
+		Topic           string `json:"topic"`
+		Retain          bool   `json:"retain"`
+		HomeAssistant   bool   `json:"home_assistant"`
+		DiscoveryPrefix string `json:"discovery_prefix"`
+
+		// publish each field on its own topic when it moved beyond its deadband
+		ChangesOnly bool            `json:"changes_only"`
+		Deadbands   *deadbandConfig `json:"deadbands"`
+	} `json:"mqtt"`
+
+	HTTP *struct {
+		Listen   string `json:"listen"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Metrics  bool   `json:"metrics"` // Prometheus metrics on /metrics
+	} `json:"http"`
+
+	CSV *struct {
+		Directory   string   `json:"directory"`
+		RotateEvery duration `json:"rotate_every"`
+		MaxFiles    int      `json:"max_files"`
+	} `json:"csv"`
+
+	Influx *struct {
+		URL    string            `json:"url"`
+		Org    string            `json:"org"`
+		Bucket string            `json:"bucket"`
+		Token  string            `json:"token"`
+		Tags   map[string]string `json:"tags"`
+	} `json:"influx"`
+
+	Modbus *struct {
+		Listen string `json:"listen"`
+	} `json:"modbus"`
+
+	Alarms []alarmConfig `json:"alarms"`
+}
+
+// A threshold, exactly one of Above and Below is set
+type alarmConfig struct {
+	Name       string   `json:"name"`
+	Metric     string   `json:"metric"`
+	Above      *float64 `json:"above"`
+	Below      *float64 `json:"below"`
+	Hysteresis float64  `json:"hysteresis"`
+	Debounce   duration `json:"debounce"`
+}
+
+// Overrides of dalybms.DefaultDeadbands()
+type deadbandConfig struct {
+	CellVoltage *float64 `json:"cell_voltage"`
+	PackVoltage *float64 `json:"pack_voltage"`
+	Current     *float64 `json:"current"`
+	SOC         *float64 `json:"soc"`
+	Temperature *float64 `json:"temperature"`
+	Capacity    *float64 `json:"capacity"`
+	Power       *float64 `json:"power"`
+	Energy      *float64 `json:"energy"`
+}
+
+func (config *deadbandConfig) deadbands() dalybms.Deadbands {
+	deadbands := dalybms.DefaultDeadbands()
+	if config == nil {
+		return deadbands
+	}
+	for _, override := range []struct {
+		value  *float64
+		target *float64
+	}{
+		{config.CellVoltage, &deadbands.CellVoltage},
+		{config.PackVoltage, &deadbands.PackVoltage},
+		{config.Current, &deadbands.Current},
+		{config.SOC, &deadbands.SOC},
+		{config.Temperature, &deadbands.Temperature},
+		{config.Capacity, &deadbands.Capacity},
+		{config.Power, &deadbands.Power},
+		{config.Energy, &deadbands.Energy},
+	} {
+		if override.value != nil {
+			*override.target = *override.value
+		}
+	}
+	return deadbands
+}
+
+var pollGroups = map[string]dalybms.PollGroup{
+	"soc":          dalybms.PollSOC,
+	"cells":        dalybms.PollCells,
+	"temperatures": dalybms.PollTemperatures,
+	"status":       dalybms.PollStatus,
+}
+
+var alarmMetrics = map[string]dalybms.AlarmMetric{
+	"soc":                  dalybms.MetricSOC,
+	"pack_voltage":         dalybms.MetricPackVoltage,
+	"current":              dalybms.MetricCurrent,
+	"highest_cell_voltage": dalybms.MetricHighestCellVoltage,
+	"lowest_cell_voltage":  dalybms.MetricLowestCellVoltage,
+	"cell_voltage_delta":   dalybms.MetricCellVoltageDelta,
+	"highest_temperature":  dalybms.MetricHighestTemperature,
+	"lowest_temperature":   dalybms.MetricLowestTemperature,
+	"mos_temperature":      dalybms.MetricMOSTemperature,
+}
+
+// duration accepts "5s", "1m30s" or a number of seconds
+type duration time.Duration
+
+func (value *duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		seconds, parseErr := strconv.ParseFloat(string(data), 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid duration %s", data)
+		}
+		*value = duration(seconds * float64(time.Second))
+		return nil
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*value = duration(parsed)
+	return nil
+}
+
+func loadDaemonConfig(path string) (*daemonConfig, error) {
+	document, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &daemonConfig{}
+	if err := decodeYAML(document, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if config.Serial.Port == "" {
+		config.Serial.Port = "/dev/ttyUSB0"
+	}
+	if config.Serial.Address == 0 {
+		config.Serial.Address = 4
+	}
+	if config.Interval <= 0 {
+		config.Interval = duration(5 * time.Second)
+	}
+	for group := range config.Intervals {
+		if _, ok := pollGroups[group]; !ok {
+			return nil, fmt.Errorf("%s: unknown interval group %q, use soc, cells, temperatures or status", path, group)
+		}
+	}
+	if config.Language != "" && config.Language != "en" && !slices.Contains(dalybms.ErrorLanguages(), config.Language) {
+		return nil, fmt.Errorf("%s: unknown language %q, use en or one of %v", path, config.Language, dalybms.ErrorLanguages())
+	}
+	if config.MaxSampleAge <= 0 {
+		config.MaxSampleAge = max(3*config.Interval, duration(30*time.Second))
+	}
+	if config.MQTT != nil && config.MQTT.Broker == "" {
+		return nil, fmt.Errorf("%s: mqtt.broker is required", path)
+	}
+	if config.HTTP != nil && config.HTTP.Listen == "" {
+		config.HTTP.Listen = ":8080"
+	}
+	if config.CSV != nil && config.CSV.Directory == "" {
+		return nil, fmt.Errorf("%s: csv.directory is required", path)
+	}
+	if config.Influx != nil && config.Influx.URL == "" {
+		return nil, fmt.Errorf("%s: influx.url is required", path)
+	}
+	if config.Modbus != nil && config.Modbus.Listen == "" {
+		config.Modbus.Listen = ":502"
+	}
+	for _, alarm := range config.Alarms {
+		if _, ok := alarmMetrics[alarm.Metric]; !ok {
+			return nil, fmt.Errorf("%s: alarm %q: unknown metric %q", path, alarm.Name, alarm.Metric)
+		}
+		if (alarm.Above == nil) == (alarm.Below == nil) {
+			return nil, fmt.Errorf("%s: alarm %q: set either above or below", path, alarm.Name)
+		}
+	}
+	return config, nil
+}
+
+// alarmRules converts the configured thresholds
+func (config *daemonConfig) alarmRules() []dalybms.AlarmRule {
+	rules := make([]dalybms.AlarmRule, 0, len(config.Alarms))
+	for _, alarm := range config.Alarms {
+		rule := dalybms.AlarmRule{
+			Name:       alarm.Name,
+			Metric:     alarmMetrics[alarm.Metric],
+			Hysteresis: alarm.Hysteresis,
+			Debounce:   time.Duration(alarm.Debounce),
+		}
+		if alarm.Above != nil {
+			rule.Condition, rule.Threshold = dalybms.AlarmAbove, *alarm.Above
+		} else {
+			rule.Condition, rule.Threshold = dalybms.AlarmBelow, *alarm.Below
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func runDaemon(args []string) error {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	configPath := flags.String("config", "config.yaml", "YAML configuration file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("daemon expects 0 argument(s), got %d", flags.NArg())
+	}
+	config, err := loadDaemonConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bms := dalybms.NewClient(dalybms.WithAddress(config.Serial.Address), dalybms.WithLanguage(config.Language),
+		dalybms.WithTemperatureUnit(config.TemperatureUnit), dalybms.WithMOSTemperatureSensor(config.MOSTemperatureSensor))
+	serialConfig := dalybms.DefaultSerialConfig()
+	if config.Serial.Baud > 0 {
+		serialConfig.BaudRate = config.Serial.Baud
+	}
+	if config.Serial.Timeout > 0 {
+		serialConfig.ReadTimeout = time.Duration(config.Serial.Timeout)
+	}
+	poller := dalybms.NewPoller(bms, dalybms.SerialConnectorWithConfig(config.Serial.Port, serialConfig), time.Duration(config.Interval))
+	if len(config.Intervals) > 0 {
+		poller.GroupIntervals = map[dalybms.PollGroup]time.Duration{}
+		for group, interval := range config.Intervals {
+			poller.GroupIntervals[pollGroups[group]] = time.Duration(interval)
+		}
+	}
+
+	var outputs []output // Update errors are reported once per change
+	var services []func(ctx context.Context) error
+	var closers []func() error
+
+	var publisher *mqtt.Publisher
+	if config.MQTT != nil {
+		publisher = mqtt.NewPublisher(mqtt.Config{
+			Broker:          config.MQTT.Broker,
+			ClientID:        config.MQTT.ClientID,
+			Username:        config.MQTT.Username,
+			Password:        config.MQTT.Password,
+			Topic:           config.MQTT.Topic,
+			Retain:          config.MQTT.Retain,
+			HomeAssistant:   config.MQTT.HomeAssistant,
+			DiscoveryPrefix: config.MQTT.DiscoveryPrefix,
+			ChangesOnly:     config.MQTT.ChangesOnly,
+			Deadbands:       config.MQTT.Deadbands.deadbands(),
+			TemperatureUnit: config.TemperatureUnit,
+		})
+		poller.OnResult(publisher.Update)
+		outputs = append(outputs, output{name: "mqtt", err: publisher.Err})
+		closers = append(closers, publisher.Close)
+	}
+
+	if config.HTTP != nil {
+		server := httpserver.New(bms)
+		server.Username, server.Password = config.HTTP.Username, config.HTTP.Password
+		server.MaxSampleAge = time.Duration(config.MaxSampleAge)
+		if config.HTTP.Metrics {
+			collector := prometheus.NewCollector("dalybms")
+			server.Handle("GET /metrics", collector)
+			poller.OnResult(collector.Update)
+		}
+		poller.OnResult(server.Update)
+		listen := config.HTTP.Listen
+		services = append(services, func(ctx context.Context) error { return server.Run(ctx, listen) })
+	}
+
+	if config.CSV != nil {
+		csvLogger, err := datalogger.New(datalogger.Config{
+			Directory:   config.CSV.Directory,
+			RotateEvery: time.Duration(config.CSV.RotateEvery),
+			MaxFiles:    config.CSV.MaxFiles,
+		})
+		if err != nil {
+			return err
+		}
+		poller.OnResult(csvLogger.Update)
+		outputs = append(outputs, output{name: "csv", err: csvLogger.Err})
+		closers = append(closers, csvLogger.Close)
+	}
+
+	if config.Influx != nil {
+		writer := influx.NewWriter(influx.Config{
+			URL:    config.Influx.URL,
+			Org:    config.Influx.Org,
+			Bucket: config.Influx.Bucket,
+			Token:  config.Influx.Token,
+			Tags:   config.Influx.Tags,
+		})
+		poller.OnResult(writer.Update)
+		outputs = append(outputs, output{name: "influx", err: writer.Err})
+		closers = append(closers, func() error {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return writer.Flush(flushCtx)
+		})
+	}
+
+	if config.Modbus != nil {
+		gateway := modbus.NewGateway()
+		poller.OnResult(gateway.Update)
+		listen := config.Modbus.Listen
+		services = append(services, func(ctx context.Context) error { return gateway.ListenAndServeTCPCtx(ctx, listen) })
+	}
+
+	if len(config.Alarms) > 0 {
+		alarms := dalybms.NewAlarms(config.alarmRules()...)
+		events := alarms.Subscribe(16)
+		poller.OnResult(alarms.Update)
+		closers = append(closers, func() error { alarms.Close(); return nil })
+		go func() {
+			for event := range events {
+				fmt.Fprintf(os.Stderr, "alarm %s active=%t value=%g threshold=%g\n", event.Rule, event.Active, event.Value, event.Threshold)
+				if publisher != nil {
+					if payload, err := json.Marshal(event); err == nil {
+						publisher.Publish(publisher.Topic()+"/alarms/"+event.Rule, payload, true)
+					}
+				}
+			}
+		}()
+	}
+
+	health := &linkHealth{started: time.Now(), maxAge: time.Duration(config.MaxSampleAge)}
+	poller.OnResult(func(result dalybms.PollResult) {
+		health.record(result.Err == nil)
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", result.Err)
+		}
+		for index := range outputs {
+			outputs[index].report()
+		}
+	})
+
+	// run the services and the poller until interrupted or a service fails
+	serviceErr := make(chan error, len(services))
+	var waitGroup sync.WaitGroup
+	for _, service := range services {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := service(ctx); err != nil {
+				serviceErr <- err
+				stop()
+			}
+		}()
+	}
+	go sdWatchdog(ctx, health.healthy)
+	sdNotify("READY=1")
+	poller.Run(ctx)
+	sdNotify("STOPPING=1")
+	waitGroup.Wait()
+
+	for _, closer := range closers {
+		if err := closer(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+	select {
+	case err := <-serviceErr:
+		return err
+	default:
+		return nil
+	}
+}
+
+// output reports the errors of an Update consumer when they change
+type output struct {
+	name    string
+	err     func() error
+	lastErr string
+}
+
+func (output *output) report() {
+	message := ""
+	if err := output.err(); err != nil {
+		message = err.Error()
+	}
+	if message != output.lastErr && message != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", output.name, message)
+	}
+	output.lastErr = message
+}
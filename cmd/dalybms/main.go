@@ -0,0 +1,92 @@
+// Command dalybms queries and controls a Daly BMS.
+//
+//	dalybms status
+//	dalybms cells
+//	dalybms set-soc 80
+//	dalybms mosfet charge on
+//	dalybms watch --interval 5 --format json
+//	dalybms check --warn-soc 20 --crit-soc 10
+//	dalybms top
+//	dalybms record --out session.jsonl
+//	dalybms replay session.jsonl
+//	dalybms serve --listen :8080
+//	dalybms daemon --config config.yaml
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// A CLI subcommand. run receives the arguments following the subcommand name.
+type command struct {
+	usage       string
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"status":  {"status [flags]", "Show a summary of the pack", runStatus},
+	"cells":   {"cells [flags]", "Show cell voltages and balancing", runCells},
+	"temps":   {"temps [flags]", "Show temperature sensors", runTemperatures},
+	"errors":  {"errors [flags]", "Show active error flags", runErrors},
+	"info":    {"info [flags]", "Show firmware/hardware version and battery code", runInfo},
+	"set-soc": {"set-soc [flags] <percent>", "Set the state of charge", runSetSOC},
+	"mosfet":  {"mosfet [flags] <charge|discharge> <on|off>", "Switch a MOSFET", runMosfet},
+	"restart": {"restart [flags]", "Restart the BMS", runRestart},
+	"sleep":   {"sleep [flags] <command hex>", "Put the BMS to sleep with the sleep command of the board", runSleep},
+	"wake":    {"wake [flags]", "Wake a sleeping BMS and check that it answers", runWake},
+	"check":   {"check [flags]", "Exit 1 (warning) or 2 (critical) on errors, low SOC or cell imbalance", runCheck},
+	"ports":   {"ports [flags]", "List serial ports, -probe looks for a BMS on each", runPorts},
+	"probe":   {"probe [flags]", "Find the address and baud rate the BMS answers to", runProbe},
+	"raw":     {"raw [flags] <command hex>", "Send a raw request and print the response data", runRaw},
+	"watch":   {"watch [flags]", "Print samples continuously", runWatch},
+	"top":     {"top [flags]", "Show a live dashboard of cells, pack, temperatures and errors", runTop},
+	"record":  {"record [flags] -out <file>", "Record the frames and samples of a session to a JSON lines file", runRecord},
+	"replay":  {"replay [flags] <file>", "Decode the frames of a recorded session again and print the samples", runReplay},
+	"serve":   {"serve [flags]", "Serve data and controls over HTTP", runServe},
+	"daemon":  {"daemon -config <file>", "Run the outputs declared in a YAML file (MQTT, HTTP, CSV...)", runDaemon},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		printUsage()
+		os.Exit(2)
+	}
+
+	subcommand, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := subcommand.run(os.Args[2:]); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			fmt.Println(exit.message)
+			os.Exit(exit.code)
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: dalybms <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-45s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'dalybms <command> -h' for the flags of a command.")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Flags shared by every subcommand
+type commonOptions struct {
+	port           string
+	address        int
+	format         string
+	modules        string
+	cellsPerModule int
+	serial         dalybms.SerialConfig
+	tempUnit       dalybms.TemperatureUnit
+}
+
+func newFlagSet(name string, options *commonOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	defaultPort := os.Getenv("DALYBMS_PORT")
+	if defaultPort == "" {
+		defaultPort = "/dev/ttyUSB0"
+		if runtime.GOOS == "windows" {
+			defaultPort = "COM3" // usual first USB serial adapter
+		}
+	}
+	flags.StringVar(&options.port, "port", defaultPort, "serial device or tcp:// / rfc2217:// address (env DALYBMS_PORT)")
+	flags.IntVar(&options.address, "address", 4, "BMS address, 4 for UART/RS485, 8 for Bluetooth modules, see probe")
+	flags.StringVar(&options.format, "format", formatTable, "output format of read commands: table, json or csv")
+	options.serial = dalybms.DefaultSerialConfig()
+	flags.IntVar(&options.serial.BaudRate, "baud", options.serial.BaudRate, "serial baud rate")
+	flags.DurationVar(&options.serial.ReadTimeout, "timeout", options.serial.ReadTimeout, "serial read timeout")
+	flags.StringVar(&options.modules, "modules", "", "comma separated module names in wiring order, eg A,B,C,D")
+	flags.IntVar(&options.cellsPerModule, "cells-per-module", 0, "cells in each module, used with -modules")
+	flags.TextVar(&options.tempUnit, "temp-unit", dalybms.Celsius, "temperature unit: celsius, fahrenheit or kelvin (C, F, K)")
+	return flags
+}
+
+// parse parses the flags and checks the number of positional arguments
+func (options *commonOptions) parse(flags *flag.FlagSet, args []string, positional int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != positional {
+		return fmt.Errorf("%s expects %d argument(s), got %d", flags.Name(), positional, flags.NArg())
+	}
+	switch options.format {
+	case "text": // name of the table format before CSV
+		options.format = formatTable
+	case formatTable, formatJSON, formatCSV:
+	default:
+		return fmt.Errorf("unknown format: %s", options.format)
+	}
+	return nil
+}
+
+// newBMS returns a client configured from the options and extra, not connected yet
+func (options *commonOptions) newBMS(extra ...dalybms.Option) *dalybms.DalyBMSIstance {
+	clientOptions := append([]dalybms.Option{dalybms.WithAddress(options.address), dalybms.WithTemperatureUnit(options.tempUnit)}, extra...)
+	bms := dalybms.NewClient(clientOptions...)
+	if options.modules != "" && options.cellsPerModule > 0 {
+		bms.SetCellMap(dalybms.NewModuleCellMap(options.cellsPerModule, strings.Split(options.modules, ",")))
+	}
+	return bms
+}
+
+// connect opens the BMS on the configured port
+func (options *commonOptions) connect(extra ...dalybms.Option) (*dalybms.DalyBMSIstance, error) {
+	bms := options.newBMS(extra...)
+	if err := bms.ConnectWithConfig(options.port, options.serial); err != nil {
+		return nil, err
+	}
+	return bms, nil
+}
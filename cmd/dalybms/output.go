@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/datalogger"
+)
+
+// Output formats of the read commands, see -format
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// printValue writes value as indented JSON, the records of csvTable (header first) as CSV, or
+// calls printText for the table format
+func printValue(format string, value any, printText func(writer io.Writer), csvTable func() [][]string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatCSV:
+		writer := csv.NewWriter(os.Stdout)
+		writer.WriteAll(csvTable())
+		return writer.Error()
+	}
+	printText(os.Stdout)
+	return nil
+}
+
+// streamPrinter is printValue for continuous output: one JSON document per line, or CSV records
+// under a single header
+type streamPrinter struct {
+	format     string
+	csv        *csv.Writer
+	headerDone bool
+}
+
+func newStreamPrinter(format string) *streamPrinter {
+	return &streamPrinter{format: format, csv: csv.NewWriter(os.Stdout)}
+}
+
+// print writes a value, csvTable returns the header and the records of the value
+func (stream *streamPrinter) print(value any, printText func(writer io.Writer), csvTable func() [][]string) error {
+	switch stream.format {
+	case formatJSON:
+		return json.NewEncoder(os.Stdout).Encode(value)
+	case formatCSV:
+		records := csvTable()
+		if stream.headerDone {
+			records = records[1:]
+		}
+		stream.headerDone = true
+		stream.csv.WriteAll(records)
+		return stream.csv.Error()
+	}
+	printText(os.Stdout)
+	return nil
+}
+
+// sampleColumns returns the CSV columns of a sample: the pack values of the data logger, then
+// cell_1..cell_N and temperature_1..temperature_N, so the order is stable between samples
+func sampleColumns(data *dalybms.AllStatusData) []datalogger.Column {
+	columns := datalogger.DefaultColumns()
+	if data.Status != nil {
+		columns = append(columns, datalogger.CellColumns(data.Status.NumberOfCells)...)
+		columns = append(columns, datalogger.TemperatureColumns(data.Status.NumberOfTemperatureSensors)...)
+	}
+	return columns
+}
+
+// sampleTable returns the CSV header and the record of a poll result
+func sampleTable(columns []datalogger.Column, result dalybms.PollResult) [][]string {
+	header := make([]string, 0, len(columns))
+	record := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.Name)
+		record = append(record, column.Value(result))
+	}
+	return [][]string{header, record}
+}
+
+func printStatus(writer io.Writer, data *dalybms.AllStatusData) {
+	stats := data.Stats()
+	fmt.Fprintf(writer, "SOC:              %.1f%%\n", data.SOC.SOCPercent)
+	fmt.Fprintf(writer, "Voltage:          %.1f V\n", data.SOC.TotalVoltage)
+	fmt.Fprintf(writer, "Current:          %.1f A\n", data.SOC.Current)
+	fmt.Fprintf(writer, "Power:            %.0f W\n", stats.PowerW)
+	fmt.Fprintf(writer, "Mode:             %s\n", data.MosfetStatus.Mode)
+	fmt.Fprintf(writer, "Remaining:        %.3f Ah (%.0f Wh)\n", data.MosfetStatus.CapacityAh, stats.RemainingEnergyWh)
+	fmt.Fprintf(writer, "Charge MOSFET:    %s\n", onOff(data.MosfetStatus.ChargingMosfet))
+	fmt.Fprintf(writer, "Discharge MOSFET: %s\n", onOff(data.MosfetStatus.DischargingMosfet))
+	fmt.Fprintf(writer, "Cycles:           %d\n", data.Status.CycleCount)
+	fmt.Fprintf(writer, "Cells:            %d (%.3f V %s .. %.3f V %s)\n",
+		data.Status.NumberOfCells,
+		data.CellVoltageRange.LowestVoltage, data.CellVoltageRange.LowestCellLabel,
+		data.CellVoltageRange.HighestVoltage, data.CellVoltageRange.HighestCellLabel)
+	fmt.Fprintf(writer, "Cell delta:       %.3f V (average %.3f V)\n", stats.CellVoltageDelta, stats.AverageCellVoltage)
+	fmt.Fprintf(writer, "Temperature:      %.0f .. %.0f %s\n",
+		data.TemperatureRange.LowestTemperature, data.TemperatureRange.HighestTemperature, data.TemperatureRange.Unit.Symbol())
+	fmt.Fprintf(writer, "Errors:           %s\n", joinOrNone(data.Errors.Strings()))
+}
+
+func printCells(writer io.Writer, cellVoltages map[int]float64, balancing map[int]bool, labels map[int]string) {
+	for _, cellIndex := range sortedKeys(cellVoltages) {
+		balancingText := ""
+		if balancing[cellIndex] {
+			balancingText = " (balancing)"
+		}
+		fmt.Fprintf(writer, "%-20s %.3f V%s\n", labels[cellIndex], cellVoltages[cellIndex], balancingText)
+	}
+}
+
+func printTemperatures(writer io.Writer, temperatures map[int]float64, unit dalybms.TemperatureUnit) {
+	for _, sensorIndex := range sortedKeys(temperatures) {
+		fmt.Fprintf(writer, "sensor %-3d %.0f %s\n", sensorIndex, temperatures[sensorIndex], unit.Symbol())
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatFloat32 prints the shortest float32 representation, eg 52.4 instead of 52.400001525878906
+func formatFloat32(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func sortedKeys[V any](values map[int]V) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// systemd notification protocol, see sd_notify(3). All functions are no-ops when the
+// daemon is not started by systemd with Type=notify.
+
+// sdNotify sends a state, eg "READY=1", to the service manager
+func sdNotify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+	if strings.HasPrefix(socketPath, "@") {
+		// abstract namespace socket
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	connection, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	_, err = connection.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns how often the watchdog must be pinged, 0 when WatchdogSec is not set
+func sdWatchdogInterval() time.Duration {
+	microseconds, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || microseconds <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(microseconds) * time.Microsecond / 2
+}
+
+// sdWatchdog pings the watchdog while healthy returns true, so systemd restarts the
+// service when the BMS link is dead. Returns when ctx is done.
+func sdWatchdog(ctx context.Context, healthy func() bool) {
+	interval := sdWatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy() {
+				sdNotify("WATCHDOG=1")
+			}
+		}
+	}
+}
+
+// linkHealth tracks the latest successful sample of the daemon
+type linkHealth struct {
+	mutex       sync.Mutex
+	started     time.Time
+	lastSuccess time.Time
+	maxAge      time.Duration
+}
+
+func (health *linkHealth) record(success bool) {
+	if !success {
+		return
+	}
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+	health.lastSuccess = time.Now()
+}
+
+// healthy is true when the latest success, or the start while none, is within maxAge
+func (health *linkHealth) healthy() bool {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+	since := health.lastSuccess
+	if since.IsZero() {
+		since = health.started
+	}
+	return time.Since(since) <= health.maxAge
+}
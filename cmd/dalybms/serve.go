@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/httpserver"
+	"github.com/jonamat/go-daly-bms/prometheus"
+)
+
+func runServe(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("serve", &options)
+	listen := flags.String("listen", ":8080", "HTTP listen address")
+	interval := flags.Int("interval", 5, "seconds between samples")
+	username := flags.String("user", "", "basic auth user, requires -password")
+	password := flags.String("password", os.Getenv("DALYBMS_PASSWORD"), "basic auth password (env DALYBMS_PASSWORD)")
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %d", *interval)
+	}
+	if *username != "" && *password == "" {
+		return fmt.Errorf("-user requires -password")
+	}
+
+	bms := options.newBMS()
+	server := httpserver.New(bms)
+	server.Username, server.Password = *username, *password
+	collector := prometheus.NewCollector("dalybms")
+	server.Handle("GET /metrics", collector)
+
+	poller := dalybms.NewPoller(bms, dalybms.SerialConnectorWithConfig(options.port, options.serial), time.Duration(*interval)*time.Second)
+	poller.OnResult(server.Update)
+	poller.OnResult(collector.Update)
+	poller.OnResult(func(result dalybms.PollResult) {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", result.Err)
+		}
+	})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Run(ctx, *listen)
+		stop() // stop polling if the server fails
+	}()
+	poller.Run(ctx)
+	return <-serveErr
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/datalogger"
+)
+
+// Types of the lines of a session file
+const (
+	sessionHeader = "session" // first line, how the session was recorded
+	sessionFrame  = "frame"   // a frame sent or received
+	sessionSample = "sample"  // a poll result decoded while recording
+)
+
+// A line of a session file, one JSON document per line
+type sessionEntry struct {
+	Type      string                 `json:"type"`
+	Time      time.Time              `json:"time"`
+	Address   int                    `json:"address,omitempty"`   // header
+	Interval  string                 `json:"interval,omitempty"`  // header
+	Direction string                 `json:"direction,omitempty"` // frame: tx or rx
+	Frame     string                 `json:"frame,omitempty"`     // frame: hex
+	Data      *dalybms.AllStatusData `json:"data,omitempty"`      // sample
+	Error     string                 `json:"error,omitempty"`     // sample
+}
+
+// sessionWriter writes the lines of a session file from the observer and the poller
+type sessionWriter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error // first write error
+}
+
+func (writer *sessionWriter) write(entry sessionEntry) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if err := writer.encoder.Encode(entry); err != nil && writer.err == nil {
+		writer.err = err
+	}
+}
+
+func runRecord(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("record", &options)
+	out := flags.String("out", "session.jsonl", "session file to write")
+	interval := flags.Int("interval", 5, "seconds between samples")
+	duration := flags.Duration("duration", 0, "stop after this long, 0 = until interrupted")
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %d", *interval)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	buffered := bufio.NewWriter(file)
+	session := &sessionWriter{encoder: json.NewEncoder(buffered)}
+	pollInterval := time.Duration(*interval) * time.Second
+	session.write(sessionEntry{Type: sessionHeader, Time: time.Now(), Address: options.address, Interval: pollInterval.String()})
+
+	bms := options.newBMS()
+	bms.SetFrameObserver(func(direction dalybms.Direction, frame []byte) {
+		session.write(sessionEntry{Type: sessionFrame, Time: time.Now(), Direction: direction.String(), Frame: hex.EncodeToString(frame)})
+	})
+	poller := dalybms.NewPoller(bms, dalybms.SerialConnectorWithConfig(options.port, options.serial), pollInterval)
+	samples := 0
+	poller.OnResult(func(result dalybms.PollResult) {
+		entry := sessionEntry{Type: sessionSample, Time: result.Time, Data: result.Data}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+			fmt.Fprintf(os.Stderr, "error: %v\n", result.Err)
+		}
+		session.write(entry)
+		samples++
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	fmt.Fprintf(os.Stderr, "recording to %s, interrupt to stop\n", *out)
+	poller.Run(ctx)
+
+	if session.err != nil {
+		return session.err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d samples recorded\n", samples)
+	return file.Close()
+}
+
+// readSession parses a session file written by record
+func readSession(reader io.Reader) (header sessionEntry, records []dalybms.TraceRecord, samples []sessionEntry, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20) // samples of large packs exceed the default line length
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry sessionEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return header, nil, nil, fmt.Errorf("session line %d: %w", lineNumber, err)
+		}
+
+		switch entry.Type {
+		case sessionHeader:
+			header = entry
+		case sessionFrame:
+			direction := dalybms.DirectionRX
+			if entry.Direction == dalybms.DirectionTX.String() {
+				direction = dalybms.DirectionTX
+			}
+			frame, err := hex.DecodeString(entry.Frame)
+			if err != nil {
+				return header, nil, nil, fmt.Errorf("session line %d: %w", lineNumber, err)
+			}
+			records = append(records, dalybms.TraceRecord{Time: entry.Time, Direction: direction, Frame: frame})
+		case sessionSample:
+			samples = append(samples, entry)
+		default:
+			return header, nil, nil, fmt.Errorf("session line %d: unknown type %q", lineNumber, entry.Type)
+		}
+	}
+	return header, records, samples, scanner.Err()
+}
+
+func runReplay(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("replay", &options)
+	if err := options.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	header, records, samples, err := readSession(file)
+	if err != nil {
+		return err
+	}
+	if header.Type == "" {
+		return fmt.Errorf("%s is not a session file", flags.Arg(0))
+	}
+
+	// decode the recorded frames again with this version of the library
+	options.address = header.Address
+	bms := options.newBMS()
+	replay := dalybms.NewReplayTransport(records)
+	if err := bms.ConnectTransport(replay); err != nil {
+		return err
+	}
+	defer bms.Disconnect()
+
+	stream := newStreamPrinter(options.format)
+	var columns []datalogger.Column // of the first sample, the CSV header is written once
+	for sampleIndex := 0; replay.Remaining() > 0; sampleIndex++ {
+		remaining := replay.Remaining()
+		data, err := bms.GetAllData()
+		if replay.Remaining() == remaining {
+			return errors.New("the recorded requests don't match the requests of this version")
+		}
+
+		// report the time of the recorded sample, not of the replay
+		result := dalybms.PollResult{Data: data, Err: err, Time: header.Time}
+		if sampleIndex < len(samples) {
+			result.Time = samples[sampleIndex].Time
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			continue
+		}
+		if columns == nil {
+			columns = sampleColumns(result.Data)
+		}
+		err = stream.print(result, func(writer io.Writer) {
+			fmt.Fprintf(writer, "--- %s\n", result.Time.Format(time.RFC3339))
+			printStatus(writer, result.Data)
+		}, func() [][]string {
+			return sampleTable(columns, result)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
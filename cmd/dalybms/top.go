@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// ANSI escape sequences of the dashboard
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearBelow = "\x1b[J"
+	ansiReset      = "\x1b[0m"
+	ansiBold       = "\x1b[1m"
+	ansiRed        = "\x1b[31m"
+	ansiGreen      = "\x1b[32m"
+	ansiYellow     = "\x1b[33m"
+	ansiCyan       = "\x1b[36m"
+	ansiDim        = "\x1b[2m"
+)
+
+const (
+	minCellBarSpan  = 0.1 // V, so the bars of a balanced pack don't magnify millivolts
+	dashboardIndent = "  "
+)
+
+// dashboard renders poll results as a full screen view
+type dashboard struct {
+	port     string
+	barWidth int
+	color    bool
+
+	latest  *dalybms.AllStatusData // last successful sample
+	updated time.Time
+	lastErr error // of the latest poll, nil once a sample succeeds again
+}
+
+func runTop(args []string) error {
+	var options commonOptions
+	flags := newFlagSet("top", &options)
+	interval := flags.Int("interval", 2, "seconds between samples")
+	barWidth := flags.Int("width", 30, "width of the bars in characters")
+	if err := options.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %d", *interval)
+	}
+
+	bms := options.newBMS(dalybms.WithLogger(nil)) // log lines would scroll the dashboard
+	poller := dalybms.NewPoller(bms, dalybms.SerialConnectorWithConfig(options.port, options.serial), time.Duration(*interval)*time.Second)
+	results := poller.Subscribe(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go poller.Run(ctx)
+
+	view := &dashboard{port: options.port, barWidth: max(*barWidth, 10), color: os.Getenv("NO_COLOR") == ""}
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer fmt.Print(ansiShowCursor + ansiMainScreen)
+	view.draw(os.Stdout)
+
+	// the channel is closed once interrupted and the port is closed
+	for result := range results {
+		if result.Err != nil {
+			view.lastErr = result.Err
+		} else {
+			view.latest, view.updated, view.lastErr = result.Data, result.Time, nil
+		}
+		view.draw(os.Stdout)
+	}
+	return nil
+}
+
+// draw redraws the screen from the top left corner, clearing what the previous frame left
+func (view *dashboard) draw(writer io.Writer) {
+	var frame bytes.Buffer
+	view.render(&frame)
+	output := strings.ReplaceAll(frame.String(), "\n", ansiClearLine+"\n")
+	fmt.Fprint(writer, ansiHome+output+ansiClearBelow)
+}
+
+func (view *dashboard) render(writer io.Writer) {
+	fmt.Fprintf(writer, "%s %s%s\n\n", view.paint(ansiBold, "dalybms top"), view.port, view.paint(ansiDim, "   Ctrl-C to quit"))
+	data := view.latest
+	if data == nil {
+		if view.lastErr != nil {
+			fmt.Fprintf(writer, "%s\n", view.paint(ansiRed, "error: "+view.lastErr.Error()))
+		} else {
+			fmt.Fprintln(writer, "connecting...")
+		}
+		return
+	}
+
+	stats := data.Stats()
+	fmt.Fprintf(writer, "SOC      %s %5.1f %%\n", view.bar(float64(data.SOC.SOCPercent)/100, ansiGreen), data.SOC.SOCPercent)
+	fmt.Fprintf(writer, "Pack     %.1f V  %.1f A  %.0f W  %s\n", data.SOC.TotalVoltage, data.SOC.Current, stats.PowerW, data.MosfetStatus.Mode)
+	fmt.Fprintf(writer, "Capacity %.1f Ah  %.0f Wh  %d cycles\n", data.MosfetStatus.CapacityAh, stats.RemainingEnergyWh, data.Status.CycleCount)
+	fmt.Fprintf(writer, "MOSFETs  charge %s  discharge %s\n", view.state(data.MosfetStatus.ChargingMosfet), view.state(data.MosfetStatus.DischargingMosfet))
+
+	low, high := data.CellVoltageRange.LowestVoltage, data.CellVoltageRange.HighestVoltage
+	fmt.Fprintf(writer, "\nCells    delta %.3f V  average %.3f V\n", stats.CellVoltageDelta, stats.AverageCellVoltage)
+	center := (float64(low) + float64(high)) / 2
+	span := math.Max(float64(high-low), minCellBarSpan)
+	for _, cellIndex := range sortedKeys(data.CellVoltages) {
+		voltage := data.CellVoltages[cellIndex]
+		barColor := ansiGreen
+		switch {
+		case cellIndex == int(data.CellVoltageRange.HighestCell):
+			barColor = ansiCyan
+		case cellIndex == int(data.CellVoltageRange.LowestCell):
+			barColor = ansiYellow
+		}
+		balancing := ""
+		if data.BalancingStatus[cellIndex] {
+			balancing = view.paint(ansiYellow, " balancing")
+		}
+		label := data.CellLabels[cellIndex]
+		if label == "" {
+			label = fmt.Sprintf("cell %d", cellIndex)
+		}
+		fraction := (voltage-center)/span + 0.5
+		fmt.Fprintf(writer, "%s%-12s %s %.3f V%s\n", dashboardIndent, label, view.bar(fraction, barColor), voltage, balancing)
+	}
+
+	unit := data.TemperatureUnit.Symbol()
+	fmt.Fprintf(writer, "\nTemperatures\n")
+	for _, sensorIndex := range sortedKeys(data.Temperatures) {
+		fmt.Fprintf(writer, "%ssensor %-5d %.0f %s\n", dashboardIndent, sensorIndex, data.Temperatures[sensorIndex], unit)
+	}
+	if data.MOSTemperature != nil {
+		fmt.Fprintf(writer, "%sMOSFETs      %.0f %s\n", dashboardIndent, *data.MOSTemperature, unit)
+	}
+
+	fmt.Fprintf(writer, "\nErrors\n")
+	if len(data.Errors) == 0 {
+		fmt.Fprintf(writer, "%s%s\n", dashboardIndent, view.paint(ansiGreen, "none"))
+	}
+	for _, bmsError := range data.Errors {
+		errorColor := ansiYellow
+		if bmsError.Severity == dalybms.SeverityAlarm {
+			errorColor = ansiRed
+		}
+		fmt.Fprintf(writer, "%s%s\n", dashboardIndent, view.paint(errorColor, fmt.Sprintf("%-7s %s", bmsError.Severity, bmsError.Message)))
+	}
+
+	fmt.Fprintf(writer, "\n%s\n", view.paint(ansiDim, "updated "+view.updated.Format(time.TimeOnly)))
+	if view.lastErr != nil {
+		fmt.Fprintf(writer, "%s\n", view.paint(ansiRed, "error: "+view.lastErr.Error()))
+	}
+}
+
+// bar returns a bar filled to fraction, clamped to 0..1
+func (view *dashboard) bar(fraction float64, color string) string {
+	filled := int(math.Round(math.Min(math.Max(fraction, 0), 1) * float64(view.barWidth)))
+	return view.paint(color, strings.Repeat("█", filled)) + view.paint(ansiDim, strings.Repeat("░", view.barWidth-filled))
+}
+
+func (view *dashboard) state(isOn bool) string {
+	if isOn {
+		return view.paint(ansiGreen, "on")
+	}
+	return view.paint(ansiRed, "off")
+}
+
+// paint wraps text in an ANSI color unless colors are disabled (NO_COLOR)
+func (view *dashboard) paint(color string, text string) string {
+	if !view.color {
+		return text
+	}
+	return color + text + ansiReset
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Minimal YAML reader for the daemon configuration: block mappings and sequences,
+// flow sequences of scalars ([a, b]), quoted and plain scalars, comments.
+// Anchors, multi-line strings and flow mappings are not supported.
+
+type yamlLine struct {
+	number  int
+	indent  int
+	content string
+}
+
+// yamlPlain is an unquoted scalar, typed by resolveYAML once the target field is known
+type yamlPlain string
+
+// decodeYAML parses a document and stores it in value through encoding/json,
+// so value uses json tags. Unknown keys are rejected. Plain scalars keep their text
+// in string fields, so password: 1234 is the string "1234".
+func decodeYAML(document []byte, value any) error {
+	lines, err := yamlLines(string(document))
+	if err != nil {
+		return err
+	}
+	var tree any = map[string]any{}
+	if len(lines) > 0 {
+		parser := &yamlParser{lines: lines}
+		if tree, err = parser.node(lines[0].indent); err != nil {
+			return err
+		}
+		if parser.index < len(lines) {
+			return fmt.Errorf("line %d: unexpected indentation", lines[parser.index].number)
+		}
+	}
+
+	encoded, err := json.Marshal(resolveYAML(tree, reflect.TypeOf(value)))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(encoded)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// yamlLines drops blank lines and comments and measures indentation
+func yamlLines(document string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for index, text := range strings.Split(document, "\n") {
+		text = strings.TrimRight(stripComment(text), " \r")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", index+1)
+		}
+		lines = append(lines, yamlLine{number: index + 1, indent: len(text) - len(content), content: content})
+	}
+	return lines, nil
+}
+
+// stripComment removes a # comment outside quotes
+func stripComment(text string) string {
+	var quote rune
+	for index, char := range text {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '#' && (index == 0 || text[index-1] == ' '):
+			return text[:index]
+		}
+	}
+	return text
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	index int
+}
+
+// node parses the mapping or sequence starting at the current line
+func (parser *yamlParser) node(indent int) (any, error) {
+	if isSequenceItem(parser.lines[parser.index].content) {
+		return parser.sequence(indent)
+	}
+	return parser.mapping(indent)
+}
+
+func (parser *yamlParser) sequence(indent int) (any, error) {
+	items := []any{}
+	for parser.index < len(parser.lines) {
+		line := parser.lines[parser.index]
+		if line.indent != indent || !isSequenceItem(line.content) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.content, "-"), " ")
+
+		switch {
+		case rest == "":
+			// nested block on the following lines
+			parser.index++
+			item, err := parser.child(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case isMappingEntry(rest):
+			// "- key: value", the mapping continues at the indentation of key
+			itemIndent := indent + len(line.content) - len(rest)
+			parser.lines[parser.index] = yamlLine{number: line.number, indent: itemIndent, content: rest}
+			item, err := parser.mapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			value, err := yamlScalar(rest, line.number)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			parser.index++
+		}
+	}
+	return items, nil
+}
+
+func (parser *yamlParser) mapping(indent int) (any, error) {
+	entries := map[string]any{}
+	for parser.index < len(parser.lines) {
+		line := parser.lines[parser.index]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		if isSequenceItem(line.content) {
+			break
+		}
+		if !isMappingEntry(line.content) {
+			return nil, fmt.Errorf("line %d: expected key: value", line.number)
+		}
+
+		key, rest := splitMappingEntry(line.content)
+		if _, duplicate := entries[key]; duplicate {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		parser.index++
+
+		if rest != "" {
+			value, err := yamlScalar(rest, line.number)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = value
+			continue
+		}
+
+		// a sequence may start at the indentation of its key
+		if parser.index < len(parser.lines) && parser.lines[parser.index].indent == indent &&
+			isSequenceItem(parser.lines[parser.index].content) {
+			value, err := parser.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = value
+			continue
+		}
+		value, err := parser.child(indent)
+		if err != nil {
+			return nil, err
+		}
+		entries[key] = value
+	}
+	return entries, nil
+}
+
+// child parses the block nested below a line, nil when there is none
+func (parser *yamlParser) child(indent int) (any, error) {
+	if parser.index >= len(parser.lines) || parser.lines[parser.index].indent <= indent {
+		return nil, nil
+	}
+	return parser.node(parser.lines[parser.index].indent)
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func isMappingEntry(content string) bool {
+	if strings.HasPrefix(content, "\"") || strings.HasPrefix(content, "'") || strings.HasPrefix(content, "[") {
+		return false
+	}
+	return strings.Contains(content, ": ") || strings.HasSuffix(content, ":")
+}
+
+func splitMappingEntry(content string) (string, string) {
+	if strings.HasSuffix(content, ":") && !strings.Contains(content, ": ") {
+		return strings.TrimSpace(strings.TrimSuffix(content, ":")), ""
+	}
+	key, rest, _ := strings.Cut(content, ": ")
+	return strings.TrimSpace(key), strings.TrimSpace(rest)
+}
+
+// yamlScalar converts a plain, quoted or flow sequence value
+func yamlScalar(text string, lineNumber int) (any, error) {
+	switch {
+	case strings.HasPrefix(text, "\""):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", lineNumber, text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", lineNumber, text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated sequence %s", lineNumber, text)
+		}
+		items := []any{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			value, err := yamlScalar(strings.TrimSpace(item), lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, fmt.Errorf("line %d: flow mappings are not supported", lineNumber)
+	}
+
+	return yamlPlain(text), nil
+}
+
+// resolveYAML converts the plain scalars of node for the target type: raw text for a
+// string, else bool, number, null or text as YAML reads them.
+func resolveYAML(node any, target reflect.Type) any {
+	for target != nil && target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	switch node := node.(type) {
+	case yamlPlain:
+		if target != nil && target.Kind() == reflect.String {
+			return string(node)
+		}
+		return plainValue(string(node))
+	case []any:
+		var element reflect.Type
+		if target != nil && (target.Kind() == reflect.Slice || target.Kind() == reflect.Array) {
+			element = target.Elem()
+		}
+		for index, item := range node {
+			node[index] = resolveYAML(item, element)
+		}
+	case map[string]any:
+		for key, value := range node {
+			node[key] = resolveYAML(value, fieldType(target, key))
+		}
+	}
+	return node
+}
+
+// fieldType returns the type of the value stored under key, nil when unknown
+func fieldType(target reflect.Type, key string) reflect.Type {
+	if target == nil {
+		return nil
+	}
+	switch target.Kind() {
+	case reflect.Map:
+		return target.Elem()
+	case reflect.Struct:
+		for index := 0; index < target.NumField(); index++ {
+			field := target.Field(index)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" {
+				name = field.Name
+			}
+			// encoding/json matches keys case-insensitively
+			if strings.EqualFold(name, key) {
+				return field.Type
+			}
+		}
+	}
+	return nil
+}
+
+func plainValue(text string) any {
+	switch text {
+	case "true", "True":
+		return true
+	case "false", "False":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if integer, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return integer
+	}
+	if float, err := strconv.ParseFloat(text, 64); err == nil {
+		return float
+	}
+	return text
+}
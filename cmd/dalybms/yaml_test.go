@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeYAMLPlainScalars(t *testing.T) {
+	document := `
+serial:
+  baud: 9600
+interval: 30
+mqtt:
+  broker: tcp://localhost:1883
+  username: 0042
+  password: 1234
+  client_id: 42
+  retain: true
+http:
+  password: true
+`
+	config := &daemonConfig{}
+	if err := decodeYAML([]byte(document), config); err != nil {
+		t.Fatalf("decodeYAML: %v", err)
+	}
+	for _, test := range []struct{ name, got, want string }{
+		{"mqtt.username", config.MQTT.Username, "0042"},
+		{"mqtt.password", config.MQTT.Password, "1234"},
+		{"mqtt.client_id", config.MQTT.ClientID, "42"},
+		{"http.password", config.HTTP.Password, "true"},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s = %q, want %q", test.name, test.got, test.want)
+		}
+	}
+	if config.Serial.Baud != 9600 {
+		t.Errorf("serial.baud = %d, want 9600", config.Serial.Baud)
+	}
+	if time.Duration(config.Interval) != 30*time.Second {
+		t.Errorf("interval = %v, want 30s", time.Duration(config.Interval))
+	}
+	if !config.MQTT.Retain {
+		t.Error("mqtt.retain = false, want true")
+	}
+}
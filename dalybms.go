@@ -4,8 +4,120 @@ import (
 	_dalybms "github.com/jonamat/go-daly-bms/internal/bms"
 )
 
+// Deprecated: use NewClient, which accepts options.
 var DalyBMS = _dalybms.DalyBMS
 
+var NewClient = _dalybms.NewClient
+var WithAddress = _dalybms.WithAddress
+var WithRetries = _dalybms.WithRetries
+var WithLogger = _dalybms.WithLogger
+var WithTransport = _dalybms.WithTransport
+var WithFrameObserver = _dalybms.WithFrameObserver
+var WithResponseTimeout = _dalybms.WithResponseTimeout
+var WithRetryPolicy = _dalybms.WithRetryPolicy
+var WithSleepCommand = _dalybms.WithSleepCommand
+var WithWakeOnIdle = _dalybms.WithWakeOnIdle
+var WithErrorHistory = _dalybms.WithErrorHistory
+var WithErrorHistoryFile = _dalybms.WithErrorHistoryFile
+var WithProtocol = _dalybms.WithProtocol
+var WithResponseAddress = _dalybms.WithResponseAddress
+var WithForeignFrameHandler = _dalybms.WithForeignFrameHandler
+var WithStrictFrames = _dalybms.WithStrictFrames
+var WithKeepEcho = _dalybms.WithKeepEcho
+var WithUnsolicitedFrames = _dalybms.WithUnsolicitedFrames
+var WithBackgroundReader = _dalybms.WithBackgroundReader
+var WithCurrentSignConvention = _dalybms.WithCurrentSignConvention
+var WithTemperatureUnit = _dalybms.WithTemperatureUnit
+var AtAddress = _dalybms.AtAddress
+var AtBoard = _dalybms.AtBoard
+var WithHeaterCommands = _dalybms.WithHeaterCommands
+var WithMOSTemperatureSensor = _dalybms.WithMOSTemperatureSensor
+var WithDangerousCommands = _dalybms.WithDangerousCommands
+var WithSaveCommand = _dalybms.WithSaveCommand
+var WithPassword = _dalybms.WithPassword
+var WithWriteVerification = _dalybms.WithWriteVerification
+var WithControlRateLimit = _dalybms.WithControlRateLimit
+var WithSerialOpener = _dalybms.WithSerialOpener
+var OpenSerialPort = _dalybms.OpenSerialPort
+var WithInterCommandDelay = _dalybms.WithInterCommandDelay
+var WithLatency = _dalybms.WithLatency
+var WithWiFiBridge = _dalybms.WithWiFiBridge
+var WithRounding = _dalybms.WithRounding
+var WithLanguage = _dalybms.WithLanguage
+var WithErrorMessages = _dalybms.WithErrorMessages
+var Probe = _dalybms.Probe
+var ListSerialPorts = _dalybms.ListSerialPorts
+var ProbeCtx = _dalybms.ProbeCtx
+var ProbeTransport = _dalybms.ProbeTransport
+var ProbeTransportCtx = _dalybms.ProbeTransportCtx
+var DefaultRetryPolicy = _dalybms.DefaultRetryPolicy
+var BackoffRetryPolicy = _dalybms.BackoffRetryPolicy
+
+var ErrorLanguages = _dalybms.ErrorLanguages
+var DalyErrorCodes = _dalybms.DalyErrorCodes
+
+var ErrTimeout = _dalybms.ErrTimeout
+var ErrUnsupported = _dalybms.ErrUnsupported
+var ErrCRCMismatch = _dalybms.ErrCRCMismatch
+var ErrHeaderMismatch = _dalybms.ErrHeaderMismatch
+var ErrMissingFrames = _dalybms.ErrMissingFrames
+var ErrDangerousCommand = _dalybms.ErrDangerousCommand
+var ErrAuthenticationFailed = _dalybms.ErrAuthenticationFailed
+var ErrWriteNotApplied = _dalybms.ErrWriteNotApplied
+var ErrRateLimited = _dalybms.ErrRateLimited
+var NewFrameTraceWriter = _dalybms.NewFrameTraceWriter
+var ReadFrameTrace = _dalybms.ReadFrameTrace
+var NewReplayTransport = _dalybms.NewReplayTransport
+
+const (
+	ProtocolDaly       = _dalybms.ProtocolDaly
+	ProtocolSinowealth = _dalybms.ProtocolSinowealth
+	ProtocolAuto       = _dalybms.ProtocolAuto
+)
+
+const (
+	DirectionTX = _dalybms.DirectionTX
+	DirectionRX = _dalybms.DirectionRX
+)
+
+const (
+	SeverityWarning = _dalybms.SeverityWarning
+	SeverityAlarm   = _dalybms.SeverityAlarm
+)
+
+const (
+	CategoryVoltage     = _dalybms.CategoryVoltage
+	CategoryTemperature = _dalybms.CategoryTemperature
+	CategoryCurrent     = _dalybms.CategoryCurrent
+	CategorySOC         = _dalybms.CategorySOC
+	CategoryMOS         = _dalybms.CategoryMOS
+	CategoryHardware    = _dalybms.CategoryHardware
+)
+
+type Option = _dalybms.Option
+type Direction = _dalybms.Direction
+type Protocol = _dalybms.Protocol
+type ProbeResult = _dalybms.ProbeResult
+type SerialPortInfo = _dalybms.SerialPortInfo
+type AutoConnectResult = _dalybms.AutoConnectResult
+type Frame = _dalybms.Frame
+type FrameObserver = _dalybms.FrameObserver
+type ForeignFrameHandler = _dalybms.ForeignFrameHandler
+type BMSClient = _dalybms.BMSClient
+type FrameError = _dalybms.FrameError
+type MissingFramesError = _dalybms.MissingFramesError
+type WriteNotAppliedError = _dalybms.WriteNotAppliedError
+type SerialOpener = _dalybms.SerialOpener
+type TraceRecord = _dalybms.TraceRecord
+type ReplayTransport = _dalybms.ReplayTransport
+type Logger = _dalybms.Logger
+type RetryPolicy = _dalybms.RetryPolicy
+type ErrorHistoryEntry = _dalybms.ErrorHistoryEntry
+type BMSError = _dalybms.BMSError
+type BMSErrors = _dalybms.BMSErrors
+type ErrorSeverity = _dalybms.ErrorSeverity
+type ErrorCategory = _dalybms.ErrorCategory
+
 type DalyBMSIstance = _dalybms.DalyBMSIstance
 type StatusData = _dalybms.StatusData
 type AllStatusData = _dalybms.AllBMSData
@@ -13,3 +125,181 @@ type CellVoltageRangeData = _dalybms.CellVoltageRangeData
 type MosfetStatusData = _dalybms.MosfetStatusData
 type SOCData = _dalybms.SOCData
 type TemperatureRangeData = _dalybms.TemperatureRangeData
+type CellMap = _dalybms.CellMap
+type Stats = _dalybms.Stats
+type PackSummary = _dalybms.PackSummary
+type LinkStats = _dalybms.LinkStats
+type Voltage = _dalybms.Voltage
+type Current = _dalybms.Current
+type CurrentSign = _dalybms.CurrentSign
+type TemperatureUnit = _dalybms.TemperatureUnit
+type Temperature = _dalybms.Temperature
+type EnergyCounters = _dalybms.EnergyCounters
+type EnergySnapshot = _dalybms.EnergySnapshot
+type EnergyStore = _dalybms.EnergyStore
+type FileEnergyStore = _dalybms.FileEnergyStore
+type EnergyMeter = _dalybms.EnergyMeter
+type RatedParams = _dalybms.RatedParams
+type BalanceSettings = _dalybms.BalanceSettings
+type BatteryConfig = _dalybms.BatteryConfig
+type VoltageThresholds = _dalybms.VoltageThresholds
+type PackVoltageThresholds = _dalybms.PackVoltageThresholds
+type CurrentThresholds = _dalybms.CurrentThresholds
+type TemperatureThresholds = _dalybms.TemperatureThresholds
+type DifferenceThresholds = _dalybms.DifferenceThresholds
+type LimitMode = _dalybms.LimitMode
+type LimitPoint = _dalybms.LimitPoint
+type LimitCurve = _dalybms.LimitCurve
+type ChargeLimitConfig = _dalybms.ChargeLimitConfig
+type ChargeLimits = _dalybms.ChargeLimits
+type OpenWireStatus = _dalybms.OpenWireStatus
+type HeaterCommands = _dalybms.HeaterCommands
+type HeaterStatus = _dalybms.HeaterStatus
+type AlarmMetric = _dalybms.AlarmMetric
+type AlarmCondition = _dalybms.AlarmCondition
+type AlarmRule = _dalybms.AlarmRule
+type AlarmEvent = _dalybms.AlarmEvent
+type Alarms = _dalybms.Alarms
+type EventKind = _dalybms.EventKind
+type Event = _dalybms.Event
+type EventBus = _dalybms.EventBus
+type WatchdogRule = _dalybms.WatchdogRule
+type WatchdogEvent = _dalybms.WatchdogEvent
+type Watchdog = _dalybms.Watchdog
+type CellSample = _dalybms.CellSample
+type ImbalanceReport = _dalybms.ImbalanceReport
+type ImbalanceTracker = _dalybms.ImbalanceTracker
+
+const (
+	LimitModeLinear = _dalybms.LimitModeLinear
+	LimitModeStep   = _dalybms.LimitModeStep
+
+	LimitedByCellVoltage = _dalybms.LimitedByCellVoltage
+	LimitedByTemperature = _dalybms.LimitedByTemperature
+	LimitedByAlarm       = _dalybms.LimitedByAlarm
+	LimitedByMosfet      = _dalybms.LimitedByMosfet
+
+	AlarmAbove = _dalybms.AlarmAbove
+	AlarmBelow = _dalybms.AlarmBelow
+
+	ChargeStarted    = _dalybms.ChargeStarted
+	ChargeStopped    = _dalybms.ChargeStopped
+	DischargeStarted = _dalybms.DischargeStarted
+	DischargeStopped = _dalybms.DischargeStopped
+	MosfetEnabled    = _dalybms.MosfetEnabled
+	MosfetDisabled   = _dalybms.MosfetDisabled
+	ErrorRaised      = _dalybms.ErrorRaised
+	ErrorCleared     = _dalybms.ErrorCleared
+	CellOvervoltage  = _dalybms.CellOvervoltage
+	CellUndervoltage = _dalybms.CellUndervoltage
+
+	ChargePositive    = _dalybms.ChargePositive
+	DischargePositive = _dalybms.DischargePositive
+
+	Celsius    = _dalybms.Celsius
+	Fahrenheit = _dalybms.Fahrenheit
+	Kelvin     = _dalybms.Kelvin
+)
+
+var (
+	MetricSOC                = _dalybms.MetricSOC
+	MetricPackVoltage        = _dalybms.MetricPackVoltage
+	MetricCurrent            = _dalybms.MetricCurrent
+	MetricHighestCellVoltage = _dalybms.MetricHighestCellVoltage
+	MetricLowestCellVoltage  = _dalybms.MetricLowestCellVoltage
+	MetricCellVoltageDelta   = _dalybms.MetricCellVoltageDelta
+	MetricHighestTemperature = _dalybms.MetricHighestTemperature
+	MetricLowestTemperature  = _dalybms.MetricLowestTemperature
+	MetricMOSTemperature     = _dalybms.MetricMOSTemperature
+)
+
+var NewModuleCellMap = _dalybms.NewModuleCellMap
+var GetPackTemperatureAverage = _dalybms.GetPackTemperatureAverage
+var Widen = _dalybms.Widen
+var DefaultChargeLimitConfig = _dalybms.DefaultChargeLimitConfig
+var NewAlarms = _dalybms.NewAlarms
+var NewEventBus = _dalybms.NewEventBus
+var NewWatchdog = _dalybms.NewWatchdog
+var NewCache = _dalybms.NewCache
+var NewChangeDetector = _dalybms.NewChangeDetector
+var DefaultDeadbands = _dalybms.DefaultDeadbands
+var AnalyzeImbalance = _dalybms.AnalyzeImbalance
+var NewImbalanceTracker = _dalybms.NewImbalanceTracker
+var NewEnergyMeter = _dalybms.NewEnergyMeter
+var OrderedValues = _dalybms.OrderedValues
+var VoltageFromVolts = _dalybms.VoltageFromVolts
+var CurrentFromAmps = _dalybms.CurrentFromAmps
+var TemperatureFromCelsius = _dalybms.TemperatureFromCelsius
+var DefaultSerialConfig = _dalybms.DefaultSerialConfig
+var NewCANTransport = _dalybms.NewCANTransport
+var OpenSocketCAN = _dalybms.OpenSocketCAN
+var DialTCPTransport = _dalybms.DialTCPTransport
+var IsNetworkAddress = _dalybms.IsNetworkAddress
+var NewPoller = _dalybms.NewPoller
+var SerialConnector = _dalybms.SerialConnector
+var SerialConnectorWithConfig = _dalybms.SerialConnectorWithConfig
+var NewSimulator = _dalybms.NewSimulator
+var DefaultSimulatorConfig = _dalybms.DefaultSimulatorConfig
+
+const CANDefaultBMSIndex = _dalybms.CANDefaultBMSIndex
+const DefaultWakeDelay = _dalybms.DefaultWakeDelay
+const SchemaVersion = _dalybms.SchemaVersion
+
+const (
+	PollSOC          = _dalybms.PollSOC
+	PollCells        = _dalybms.PollCells
+	PollTemperatures = _dalybms.PollTemperatures
+	PollStatus       = _dalybms.PollStatus
+)
+const DefaultResponseAddress = _dalybms.DefaultResponseAddress
+const BalancedDelta = _dalybms.BalancedDelta
+const MaxPasswordLength = _dalybms.MaxPasswordLength
+const DefaultControlInterval = _dalybms.DefaultControlInterval
+
+const (
+	ParityNone = _dalybms.ParityNone
+	ParityOdd  = _dalybms.ParityOdd
+	ParityEven = _dalybms.ParityEven
+	StopBits1  = _dalybms.StopBits1
+	StopBits2  = _dalybms.StopBits2
+)
+
+type Transport = _dalybms.Transport
+type SerialConfig = _dalybms.SerialConfig
+type Parity = _dalybms.Parity
+type StopBits = _dalybms.StopBits
+type CANBus = _dalybms.CANBus
+type ConnectFunc = _dalybms.ConnectFunc
+type Poller = _dalybms.Poller
+type PollGroup = _dalybms.PollGroup
+type Cache = _dalybms.Cache
+type CacheConfig = _dalybms.CacheConfig
+type ChangeDetector = _dalybms.ChangeDetector
+type Deadbands = _dalybms.Deadbands
+type Cached[T any] = _dalybms.Cached[T]
+type PollResult = _dalybms.PollResult
+type Simulator = _dalybms.Simulator
+type SimulatorConfig = _dalybms.SimulatorConfig
+type BLEDevice = _dalybms.BLEDevice
+type BLEConnector = _dalybms.BLEConnector
+
+const (
+	BLEServiceUUID    = _dalybms.BLEServiceUUID
+	BLENotifyCharUUID = _dalybms.BLENotifyCharUUID
+	BLEWriteCharUUID  = _dalybms.BLEWriteCharUUID
+)
+
+type ActionEventKind = _dalybms.ActionEventKind
+type ActionEvent = _dalybms.ActionEvent
+type ActionHook = _dalybms.ActionHook
+type ActionHookFunc = _dalybms.ActionHookFunc
+type RelayHook = _dalybms.RelayHook
+
+const (
+	ChargeMosfetDisabled    = _dalybms.ChargeMosfetDisabled
+	ChargeMosfetEnabled     = _dalybms.ChargeMosfetEnabled
+	DischargeMosfetDisabled = _dalybms.DischargeMosfetDisabled
+	DischargeMosfetEnabled  = _dalybms.DischargeMosfetEnabled
+	AlarmRaised             = _dalybms.AlarmRaised
+	AlarmCleared            = _dalybms.AlarmCleared
+)
@@ -0,0 +1,125 @@
+package datalogger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Column extracts one CSV field from a sample. Value returns "" when the data is missing.
+type Column struct {
+	Name  string
+	Value func(result dalybms.PollResult) string
+}
+
+// Pack level columns: time, soc, voltage, current, power, remaining capacity, cell and temperature ranges, errors
+func DefaultColumns() []Column {
+	return []Column{
+		{Name: "time", Value: func(result dalybms.PollResult) string {
+			return result.Time.Format("2006-01-02T15:04:05.000Z07:00")
+		}},
+		socColumn("soc_percent", func(soc *dalybms.SOCData) float32 { return soc.SOCPercent }),
+		socColumn("voltage", func(soc *dalybms.SOCData) float32 { return soc.TotalVoltage }),
+		socColumn("current", func(soc *dalybms.SOCData) float32 { return soc.Current }),
+		{Name: "power_w", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.SOC == nil {
+				return ""
+			}
+			return strconv.FormatFloat(result.Data.Stats().PowerW, 'f', 1, 64)
+		}},
+		{Name: "remaining_ah", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.MosfetStatus == nil {
+				return ""
+			}
+			return formatFloat32(result.Data.MosfetStatus.CapacityAh)
+		}},
+		{Name: "cell_min", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.CellVoltageRange == nil {
+				return ""
+			}
+			return formatFloat32(result.Data.CellVoltageRange.LowestVoltage)
+		}},
+		{Name: "cell_max", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.CellVoltageRange == nil {
+				return ""
+			}
+			return formatFloat32(result.Data.CellVoltageRange.HighestVoltage)
+		}},
+		{Name: "temperature_min", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.TemperatureRange == nil {
+				return ""
+			}
+			return formatFloat32(result.Data.TemperatureRange.LowestTemperature)
+		}},
+		{Name: "temperature_max", Value: func(result dalybms.PollResult) string {
+			if result.Data == nil || result.Data.TemperatureRange == nil {
+				return ""
+			}
+			return formatFloat32(result.Data.TemperatureRange.HighestTemperature)
+		}},
+		{Name: "errors", Value: func(result dalybms.PollResult) string {
+			if result.Err != nil {
+				return "read failed: " + result.Err.Error()
+			}
+			if result.Data == nil {
+				return ""
+			}
+			return strings.Join(result.Data.Errors.Strings(), "; ")
+		}},
+	}
+}
+
+// One column per cell voltage, cell_1..cell_N
+func CellColumns(numberOfCells int) []Column {
+	columns := make([]Column, 0, numberOfCells)
+	for cellIndex := 1; cellIndex <= numberOfCells; cellIndex++ {
+		columns = append(columns, indexedColumn(fmt.Sprintf("cell_%d", cellIndex), cellIndex, func(data *dalybms.AllStatusData) map[int]float64 {
+			return data.CellVoltages
+		}))
+	}
+	return columns
+}
+
+// One column per temperature sensor, temperature_1..temperature_N
+func TemperatureColumns(numberOfSensors int) []Column {
+	columns := make([]Column, 0, numberOfSensors)
+	for sensorIndex := 1; sensorIndex <= numberOfSensors; sensorIndex++ {
+		columns = append(columns, indexedColumn(fmt.Sprintf("temperature_%d", sensorIndex), sensorIndex, func(data *dalybms.AllStatusData) map[int]float64 {
+			return data.Temperatures
+		}))
+	}
+	return columns
+}
+
+func socColumn(name string, field func(soc *dalybms.SOCData) float32) Column {
+	return Column{Name: name, Value: func(result dalybms.PollResult) string {
+		if result.Data == nil || result.Data.SOC == nil {
+			return ""
+		}
+		return formatFloat32(field(result.Data.SOC))
+	}}
+}
+
+func indexedColumn(name string, index int, values func(data *dalybms.AllStatusData) map[int]float64) Column {
+	return Column{Name: name, Value: func(result dalybms.PollResult) string {
+		if result.Data == nil {
+			return ""
+		}
+		value, ok := values(result.Data)[index]
+		if !ok {
+			return ""
+		}
+		return formatFloat(value)
+	}}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatFloat32 prints the shortest float32 representation, eg 52.4 instead of 52.400001525878906
+func formatFloat32(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
@@ -0,0 +1,262 @@
+// Package datalogger appends BMS samples to rotating CSV files, for long-term history without a database.
+package datalogger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// RowEncoder writes rows in a file format. CSV is built in, other formats (eg Parquet)
+// can be plugged through Config.Encoder.
+type RowEncoder interface {
+	WriteRow(fields []string) error
+	Flush() error
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (encoder *csvEncoder) WriteRow(fields []string) error {
+	return encoder.writer.Write(fields)
+}
+
+func (encoder *csvEncoder) Flush() error {
+	encoder.writer.Flush()
+	return encoder.writer.Error()
+}
+
+// NewCSVEncoder is the default encoder
+func NewCSVEncoder(writer io.Writer) RowEncoder {
+	return &csvEncoder{writer: csv.NewWriter(writer)}
+}
+
+type Config struct {
+	Directory string
+	Prefix    string   // file name prefix, default "dalybms"
+	Columns   []Column // default DefaultColumns()
+
+	RotateEvery time.Duration // start a new file every period, eg 24 * time.Hour. 0 = never
+	MaxBytes    int64         // start a new file when the current one reaches this size. 0 = no limit
+	MaxFiles    int           // oldest files beyond this count are deleted. 0 = keep all
+
+	Encoder   func(writer io.Writer) RowEncoder // default NewCSVEncoder
+	Extension string                            // default ".csv"
+}
+
+// Logger writes one row per poll result. Feed it with poller.OnResult(logger.Update).
+type Logger struct {
+	config Config
+
+	mutex    sync.Mutex
+	file     *os.File
+	encoder  RowEncoder
+	counter  *countingWriter
+	period   time.Time // start of the period of the current file
+	sequence int       // size rotations within the period
+	lastErr  error
+}
+
+func New(config Config) (*Logger, error) {
+	if config.Prefix == "" {
+		config.Prefix = "dalybms"
+	}
+	if len(config.Columns) == 0 {
+		config.Columns = DefaultColumns()
+	}
+	if config.Encoder == nil {
+		config.Encoder = NewCSVEncoder
+	}
+	if config.Extension == "" {
+		config.Extension = ".csv"
+	}
+	if err := os.MkdirAll(config.Directory, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return &Logger{config: config}, nil
+}
+
+// Update writes a poll result, errors are available from Err()
+func (logger *Logger) Update(result dalybms.PollResult) {
+	err := logger.Write(result)
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.lastErr = err
+}
+
+// Latest write error, nil if the latest write succeeded
+func (logger *Logger) Err() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	return logger.lastErr
+}
+
+// Write appends a row for a poll result, rotating files as configured
+func (logger *Logger) Write(result dalybms.PollResult) error {
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if err := logger.rotateIfNeeded(result.Time); err != nil {
+		return err
+	}
+
+	fields := make([]string, len(logger.config.Columns))
+	for columnIndex, column := range logger.config.Columns {
+		fields[columnIndex] = column.Value(result)
+	}
+	if err := logger.encoder.WriteRow(fields); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+	return logger.encoder.Flush()
+}
+
+// Close the current file
+func (logger *Logger) Close() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	return logger.closeFile()
+}
+
+func (logger *Logger) closeFile() error {
+	if logger.file == nil {
+		return nil
+	}
+	err := logger.file.Close()
+	logger.file = nil
+	return err
+}
+
+// rotateIfNeeded opens the file for the period of now, or the next one when the current is full
+func (logger *Logger) rotateIfNeeded(now time.Time) error {
+	period := logger.period
+	if logger.config.RotateEvery > 0 {
+		period = now.Truncate(logger.config.RotateEvery)
+	}
+
+	switch {
+	case logger.file == nil && period.IsZero():
+		// no periodic rotation, files are named after the logger start
+		period = now
+		logger.sequence = 0
+	case logger.file == nil || !period.Equal(logger.period):
+		logger.sequence = 0
+	case logger.config.MaxBytes > 0 && logger.counter.size >= logger.config.MaxBytes:
+		logger.sequence++
+	default:
+		return nil
+	}
+
+	if err := logger.closeFile(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	logger.period = period
+	return logger.openFile()
+}
+
+// openFile appends to the file of the current period and sequence, skipping full files
+func (logger *Logger) openFile() error {
+	for {
+		path := logger.filePath()
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		if logger.config.MaxBytes > 0 && info.Size() >= logger.config.MaxBytes {
+			file.Close()
+			logger.sequence++
+			continue
+		}
+
+		logger.file = file
+		logger.counter = &countingWriter{writer: file, size: info.Size()}
+		logger.encoder = logger.config.Encoder(logger.counter)
+		if info.Size() == 0 {
+			if err := logger.writeHeader(); err != nil {
+				return err
+			}
+		}
+		return logger.pruneFiles()
+	}
+}
+
+func (logger *Logger) writeHeader() error {
+	names := make([]string, len(logger.config.Columns))
+	for columnIndex, column := range logger.config.Columns {
+		names[columnIndex] = column.Name
+	}
+	if err := logger.encoder.WriteRow(names); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	return logger.encoder.Flush()
+}
+
+// filePath eg "dalybms-20240531-000000.csv", "dalybms-20240531-000000-1.csv" after a size rotation
+func (logger *Logger) filePath() string {
+	name := logger.config.Prefix + "-" + logger.period.Format("20060102-150405")
+	if logger.sequence > 0 {
+		name += fmt.Sprintf("-%d", logger.sequence)
+	}
+	return filepath.Join(logger.config.Directory, name+logger.config.Extension)
+}
+
+// pruneFiles deletes the least recently written files beyond MaxFiles
+func (logger *Logger) pruneFiles() error {
+	if logger.config.MaxFiles <= 0 {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(logger.config.Directory, logger.config.Prefix+"-*"+logger.config.Extension))
+	if err != nil {
+		return err
+	}
+
+	modTimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return modTimes[paths[i]].Before(modTimes[paths[j]])
+	})
+
+	current := logger.file.Name()
+	for len(paths) > logger.config.MaxFiles {
+		if paths[0] != current {
+			if err := os.Remove(paths[0]); err != nil {
+				return fmt.Errorf("failed to delete old log file: %w", err)
+			}
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+type countingWriter struct {
+	writer io.Writer
+	size   int64
+}
+
+func (counter *countingWriter) Write(data []byte) (int, error) {
+	written, err := counter.writer.Write(data)
+	counter.size += int64(written)
+	return written, err
+}
@@ -0,0 +1,307 @@
+// Package httpserver exposes BMS data and controls over HTTP with JSON bodies:
+//
+//	GET  /status              full sample, see AllStatusData
+//	GET  /cells               cell voltages, balancing and labels
+//	GET  /temperatures        temperature by sensor
+//	GET  /errors              active error flags
+//	GET  /info                firmware/hardware version and battery code
+//	POST /mosfet/charge       {"on": true}
+//	POST /mosfet/discharge    {"on": false}
+//	POST /soc                 {"soc_percent": 80}, controls answer 429 when rate limited
+//	GET  /ws                  WebSocket, one JSON message per poll result
+//	GET  /ws?changes=1        WebSocket, only the fields that moved beyond Deadbands, see ChangeDetector
+//	GET  /healthz             time of the latest successful sample, 503 when too old (no auth)
+package httpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Server serves the BMS over HTTP. GET endpoints use the latest result given to Update,
+// or read the BMS on each request when no poller feeds the server.
+type Server struct {
+	Username string // basic auth is required when set
+	Password string
+
+	// /healthz fails when the latest successful sample is older, default 1 minute.
+	// Set it above the poll interval.
+	MaxSampleAge time.Duration
+
+	// Minimum changes streamed by /ws?changes=1, default DefaultDeadbands()
+	Deadbands dalybms.Deadbands
+
+	bms         dalybms.BMSClient
+	mux         *http.ServeMux
+	mutex       sync.Mutex
+	latest      *dalybms.PollResult
+	lastSuccess time.Time                            // of the latest successful result given to Update
+	subscribers map[chan dalybms.PollResult]struct{} // websocket clients
+}
+
+func New(bms dalybms.BMSClient) *Server {
+	server := &Server{
+		bms:          bms,
+		mux:          http.NewServeMux(),
+		subscribers:  make(map[chan dalybms.PollResult]struct{}),
+		MaxSampleAge: time.Minute,                // default
+		Deadbands:    dalybms.DefaultDeadbands(), // default
+	}
+	server.mux.HandleFunc("GET /status", server.handleStatus)
+	server.mux.HandleFunc("GET /cells", server.handleCells)
+	server.mux.HandleFunc("GET /temperatures", server.handleTemperatures)
+	server.mux.HandleFunc("GET /errors", server.handleErrors)
+	server.mux.HandleFunc("GET /info", server.handleInfo)
+	server.mux.HandleFunc("POST /mosfet/charge", server.handleMosfet(bms.EnableChargeMosfetCtx))
+	server.mux.HandleFunc("POST /mosfet/discharge", server.handleMosfet(bms.EnableDischargeMosfetCtx))
+	server.mux.HandleFunc("POST /soc", server.handleSOC)
+	server.mux.HandleFunc("GET /ws", server.handleWebSocket)
+	server.mux.HandleFunc("GET /healthz", server.handleHealth)
+	return server
+}
+
+// Time given to requests in flight when Run stops
+const ShutdownTimeout = 5 * time.Second
+
+// Run serves HTTP on address, eg ":8080", until ctx is done, then shuts down gracefully
+// (closing WebSocket streams) and returns nil.
+func (server *Server) Run(ctx context.Context, address string) error {
+	httpServer := &http.Server{
+		Addr:        address,
+		Handler:     server,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// Handle registers an extra handler, eg server.Handle("GET /metrics", collector)
+func (server *Server) Handle(pattern string, handler http.Handler) {
+	server.mux.Handle(pattern, handler)
+}
+
+// Update stores a poll result, served by the GET endpoints and pushed to WebSocket clients
+func (server *Server) Update(result dalybms.PollResult) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.latest = &result
+	if result.Err == nil {
+		server.lastSuccess = result.Time
+		if server.lastSuccess.IsZero() {
+			server.lastSuccess = time.Now()
+		}
+	}
+	server.broadcast(result)
+}
+
+func (server *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// orchestrators probe /healthz without credentials
+	if server.Username != "" && request.URL.Path != "/healthz" && !server.authorized(request) {
+		writer.Header().Set("WWW-Authenticate", `Basic realm="dalybms"`)
+		writeError(writer, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+	server.mux.ServeHTTP(writer, request)
+}
+
+func (server *Server) authorized(request *http.Request) bool {
+	username, password, ok := request.BasicAuth()
+	if !ok {
+		return false
+	}
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(server.Username)) == 1
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(server.Password)) == 1
+	return usernameMatch && passwordMatch
+}
+
+// sample returns the latest poll result, or reads the BMS when there is none
+func (server *Server) sample(request *http.Request) dalybms.PollResult {
+	server.mutex.Lock()
+	latest := server.latest
+	server.mutex.Unlock()
+	if latest != nil {
+		return *latest
+	}
+
+	data, err := server.bms.GetAllDataCtx(request.Context())
+	return dalybms.PollResult{Data: data, Err: err, Time: time.Now()}
+}
+
+// withSample writes the value selected from the sample, or a 502 when the BMS read failed
+func (server *Server) withSample(writer http.ResponseWriter, request *http.Request, selectValue func(result dalybms.PollResult) any) {
+	result := server.sample(request)
+	if result.Err != nil || result.Data == nil {
+		writeError(writer, http.StatusBadGateway, fmt.Errorf("bms read failed: %v", result.Err))
+		return
+	}
+	writeJSON(writer, http.StatusOK, selectValue(result))
+}
+
+// Health reported by /healthz
+type Health struct {
+	Healthy    bool       `json:"healthy"`
+	LastSample *time.Time `json:"last_sample"` // latest successful sample, null if none
+	AgeSeconds float64    `json:"age_seconds,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+func (server *Server) handleHealth(writer http.ResponseWriter, request *http.Request) {
+	server.mutex.Lock()
+	latest, lastSuccess := server.latest, server.lastSuccess
+	server.mutex.Unlock()
+
+	if latest == nil {
+		// no poller feeds the server, check the link now
+		if _, err := server.bms.GetSOCCtx(request.Context()); err != nil {
+			writeJSON(writer, http.StatusServiceUnavailable, Health{LastError: err.Error()})
+			return
+		}
+		now := time.Now()
+		writeJSON(writer, http.StatusOK, Health{Healthy: true, LastSample: &now})
+		return
+	}
+
+	health := Health{}
+	if latest.Err != nil {
+		health.LastError = latest.Err.Error()
+	}
+	if !lastSuccess.IsZero() {
+		age := time.Since(lastSuccess)
+		health.LastSample = &lastSuccess
+		health.AgeSeconds = age.Seconds()
+		health.Healthy = age <= server.MaxSampleAge
+	}
+	status := http.StatusOK
+	if !health.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(writer, status, health)
+}
+
+func (server *Server) handleStatus(writer http.ResponseWriter, request *http.Request) {
+	server.withSample(writer, request, func(result dalybms.PollResult) any {
+		return result // data and sample time
+	})
+}
+
+func (server *Server) handleCells(writer http.ResponseWriter, request *http.Request) {
+	server.withSample(writer, request, func(result dalybms.PollResult) any {
+		return struct {
+			CellVoltages    map[int]float64 `json:"cell_voltages"`
+			BalancingStatus map[int]bool    `json:"balancing_status"`
+			CellLabels      map[int]string  `json:"cell_labels,omitempty"`
+		}{result.Data.CellVoltages, result.Data.BalancingStatus, result.Data.CellLabels}
+	})
+}
+
+func (server *Server) handleTemperatures(writer http.ResponseWriter, request *http.Request) {
+	server.withSample(writer, request, func(result dalybms.PollResult) any {
+		return result.Data.Temperatures
+	})
+}
+
+func (server *Server) handleErrors(writer http.ResponseWriter, request *http.Request) {
+	server.withSample(writer, request, func(result dalybms.PollResult) any {
+		return result.Data.Errors
+	})
+}
+
+func (server *Server) handleInfo(writer http.ResponseWriter, request *http.Request) {
+	var info struct {
+		FirmwareVersion string `json:"firmware_version"`
+		HardwareVersion string `json:"hardware_version"`
+		BatteryCode     string `json:"battery_code"`
+	}
+	var err error
+	ctx := request.Context()
+	if info.FirmwareVersion, err = server.bms.GetFirmwareVersionCtx(ctx); err != nil {
+		writeError(writer, http.StatusBadGateway, err)
+		return
+	}
+	if info.HardwareVersion, err = server.bms.GetHardwareVersionCtx(ctx); err != nil {
+		writeError(writer, http.StatusBadGateway, err)
+		return
+	}
+	if info.BatteryCode, err = server.bms.GetBatteryCodeCtx(ctx); err != nil {
+		writeError(writer, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, info)
+}
+
+func (server *Server) handleMosfet(enable func(ctx context.Context, isOn bool) error) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var body struct {
+			On *bool `json:"on"`
+		}
+		if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.On == nil {
+			writeError(writer, http.StatusBadRequest, fmt.Errorf(`expected {"on": true|false}`))
+			return
+		}
+		if err := enable(request.Context(), *body.On); err != nil {
+			writeError(writer, controlErrorStatus(err), err)
+			return
+		}
+		writeJSON(writer, http.StatusOK, body)
+	}
+}
+
+func (server *Server) handleSOC(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		SOCPercent *float64 `json:"soc_percent"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil || body.SOCPercent == nil {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf(`expected {"soc_percent": 0..100}`))
+		return
+	}
+	if *body.SOCPercent < 0 || *body.SOCPercent > 100 {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("soc_percent out of range: %v", *body.SOCPercent))
+		return
+	}
+	if err := server.bms.SetSOCCtx(request.Context(), *body.SOCPercent); err != nil {
+		writeError(writer, controlErrorStatus(err), err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, body)
+}
+
+// controlErrorStatus returns 429 for rate limited controls, 502 for BMS errors
+func controlErrorStatus(err error) int {
+	if errors.Is(err, dalybms.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadGateway
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
+
+func writeError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, map[string]string{"error": err.Error()})
+}
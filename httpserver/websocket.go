@@ -0,0 +1,230 @@
+package httpserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Minimal RFC 6455 server side: text messages out, incoming frames only handled for ping/close.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opcodeText  = 0x1
+	opcodeClose = 0x8
+	opcodePing  = 0x9
+	opcodePong  = 0xa
+)
+
+// handleWebSocket streams every result given to Update as a JSON message, see PollResult.
+// With ?changes=1 the messages hold only the changed fields, see changeMessage.
+func (server *Server) handleWebSocket(writer http.ResponseWriter, request *http.Request) {
+	if !strings.EqualFold(request.Header.Get("Upgrade"), "websocket") || request.Header.Get("Sec-WebSocket-Key") == "" {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("websocket upgrade expected"))
+		return
+	}
+	var changes *dalybms.ChangeDetector
+	if request.URL.Query().Get("changes") != "" {
+		changes = dalybms.NewChangeDetector(server.Deadbands)
+	}
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		writeError(writer, http.StatusInternalServerError, fmt.Errorf("connection does not support upgrades"))
+		return
+	}
+	connection, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer connection.Close()
+
+	accept := sha1.Sum([]byte(request.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+	if err := buffered.Flush(); err != nil {
+		return
+	}
+
+	results, unsubscribe := server.subscribe()
+	defer unsubscribe()
+
+	// the reader answers pings and ends the stream when the client leaves
+	controlFrames := make(chan websocketFrame)
+	done := make(chan struct{})
+	defer close(done)
+	go readWebSocketFrames(buffered.Reader, controlFrames, done)
+
+	for {
+		select {
+		case <-request.Context().Done():
+			// server shutting down, see Run
+			writeWebSocketFrame(connection, opcodeClose, nil)
+			return
+		case result := <-results:
+			message, err := encodeResult(result, changes)
+			if err != nil || message == nil {
+				continue
+			}
+			if err := writeWebSocketFrame(connection, opcodeText, message); err != nil {
+				return
+			}
+		case frame, open := <-controlFrames:
+			if !open {
+				return
+			}
+			switch frame.opcode {
+			case opcodePing:
+				if err := writeWebSocketFrame(connection, opcodePong, frame.payload); err != nil {
+					return
+				}
+			case opcodeClose:
+				writeWebSocketFrame(connection, opcodeClose, nil)
+				return
+			}
+		}
+	}
+}
+
+// subscribe returns a channel receiving the results. Slow clients miss results.
+func (server *Server) subscribe() (<-chan dalybms.PollResult, func()) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	channel := make(chan dalybms.PollResult, 4)
+	server.subscribers[channel] = struct{}{}
+	return channel, func() {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		delete(server.subscribers, channel)
+	}
+}
+
+// broadcast sends a result to websocket clients. Must be called with mutex held.
+func (server *Server) broadcast(result dalybms.PollResult) {
+	for channel := range server.subscribers {
+		select {
+		case channel <- result:
+		default:
+		}
+	}
+}
+
+// Message of /ws?changes=1, the first one holds every field
+type changeMessage struct {
+	Time    time.Time      `json:"time"`
+	Changes map[string]any `json:"changes,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// encodeResult encodes a result in full, or its changes when changes is set.
+// Returns nil when nothing changed.
+func encodeResult(result dalybms.PollResult, changes *dalybms.ChangeDetector) ([]byte, error) {
+	if changes == nil {
+		return json.Marshal(result)
+	}
+	if result.Err != nil {
+		return json.Marshal(changeMessage{Time: result.Time, Error: result.Err.Error()})
+	}
+	if result.Data == nil {
+		return nil, nil
+	}
+	fields := changes.Changes(result.Data)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changeMessage{Time: result.Time, Changes: fields})
+}
+
+type websocketFrame struct {
+	opcode  byte
+	payload []byte
+}
+
+// readWebSocketFrames forwards control frames until the connection fails or done is closed, then closes frames
+func readWebSocketFrames(reader *bufio.Reader, frames chan<- websocketFrame, done <-chan struct{}) {
+	defer close(frames)
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		opcode := header[0] & 0x0f
+		masked := header[1]&0x80 != 0
+		length := uint64(header[1] & 0x7f)
+
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		if length > 1<<16 {
+			return // clients only send small control frames
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(reader, mask[:]); err != nil {
+				return
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return
+		}
+		for index := range payload {
+			payload[index] ^= mask[index%4]
+		}
+
+		if opcode == opcodePing || opcode == opcodeClose {
+			select {
+			case frames <- websocketFrame{opcode, payload}:
+			case <-done:
+				return
+			}
+		}
+		if opcode == opcodeClose {
+			return
+		}
+	}
+}
+
+// writeWebSocketFrame writes a single unmasked frame
+func writeWebSocketFrame(connection net.Conn, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	connection.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := connection.Write(append(header, payload...))
+	return err
+}
@@ -0,0 +1,153 @@
+package influx
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Encode converts a sample to line protocol. Pack values go to the measurement itself,
+// cells to <measurement>_cell (tag cell) and sensors to <measurement>_temperature (tag sensor).
+// tags are added to every line, eg {"pack": "garage"}.
+func Encode(data *dalybms.AllStatusData, measurement string, tags map[string]string, timestamp time.Time) []byte {
+	var builder strings.Builder
+	line := func(name string, lineTags map[string]string, fields []field) {
+		if len(fields) == 0 {
+			return
+		}
+		writeLine(&builder, name, lineTags, fields, timestamp)
+	}
+
+	stats := data.Stats()
+	var packFields []field
+	if data.SOC != nil {
+		packFields = append(packFields,
+			floatField("soc_percent", float32Value(data.SOC.SOCPercent)),
+			floatField("voltage", float32Value(data.SOC.TotalVoltage)),
+			floatField("current", float32Value(data.SOC.Current)),
+			floatField("power", math.Round(stats.PowerW*10)/10),
+		)
+	}
+	if data.MosfetStatus != nil {
+		packFields = append(packFields,
+			floatField("remaining_ah", float32Value(data.MosfetStatus.CapacityAh)),
+			boolField("charging_mosfet", data.MosfetStatus.ChargingMosfet),
+			boolField("discharging_mosfet", data.MosfetStatus.DischargingMosfet),
+			stringField("mode", data.MosfetStatus.Mode),
+		)
+	}
+	if data.Status != nil {
+		packFields = append(packFields, intField("cycles", int64(data.Status.CycleCount)))
+	}
+	if len(data.CellVoltages) > 0 {
+		packFields = append(packFields, floatField("cell_delta", stats.CellVoltageDelta))
+	}
+	if data.Errors != nil {
+		packFields = append(packFields,
+			intField("error_count", int64(len(data.Errors))),
+			stringField("errors", strings.Join(data.Errors.Strings(), "; ")),
+		)
+	}
+	line(measurement, tags, packFields)
+
+	for _, cellIndex := range sortedKeys(data.CellVoltages) {
+		cellTags := withTag(tags, "cell", strconv.Itoa(cellIndex))
+		if label, ok := data.CellLabels[cellIndex]; ok {
+			cellTags["label"] = label
+		}
+		cellFields := []field{floatField("voltage", data.CellVoltages[cellIndex])}
+		if balancing, ok := data.BalancingStatus[cellIndex]; ok {
+			cellFields = append(cellFields, boolField("balancing", balancing))
+		}
+		line(measurement+"_cell", cellTags, cellFields)
+	}
+
+	temperatures := data.TemperaturesCelsius()
+	for _, sensorIndex := range sortedKeys(temperatures) {
+		line(measurement+"_temperature", withTag(tags, "sensor", strconv.Itoa(sensorIndex)),
+			[]field{floatField("celsius", temperatures[sensorIndex])})
+	}
+
+	return []byte(builder.String())
+}
+
+type field struct {
+	key   string
+	value string // already formatted
+}
+
+func floatField(key string, value float64) field {
+	return field{key, strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+func intField(key string, value int64) field {
+	return field{key, strconv.FormatInt(value, 10) + "i"}
+}
+
+func boolField(key string, value bool) field {
+	return field{key, strconv.FormatBool(value)}
+}
+
+func stringField(key string, value string) field {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return field{key, `"` + escaped + `"`}
+}
+
+// writeLine writes "measurement,tag=value field=value timestamp\n", tags sorted by key
+func writeLine(builder *strings.Builder, measurement string, tags map[string]string, fields []field, timestamp time.Time) {
+	builder.WriteString(measurementEscaper.Replace(measurement))
+	tagKeys := make([]string, 0, len(tags))
+	for key := range tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		if tags[key] == "" {
+			continue // empty tag values are invalid
+		}
+		builder.WriteString("," + tagEscaper.Replace(key) + "=" + tagEscaper.Replace(tags[key]))
+	}
+
+	for fieldIndex, field := range fields {
+		if fieldIndex == 0 {
+			builder.WriteString(" ")
+		} else {
+			builder.WriteString(",")
+		}
+		builder.WriteString(tagEscaper.Replace(field.key) + "=" + field.value)
+	}
+	builder.WriteString(" " + strconv.FormatInt(timestamp.UnixNano(), 10) + "\n")
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+func withTag(tags map[string]string, key string, value string) map[string]string {
+	combined := make(map[string]string, len(tags)+1)
+	for tagKey, tagValue := range tags {
+		combined[tagKey] = tagValue
+	}
+	combined[key] = value
+	return combined
+}
+
+func sortedKeys[V any](values map[int]V) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// float32Value widens without float32 rounding noise, eg 52.4 instead of 52.400001525878906
+func float32Value(value float32) float64 {
+	widened, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return widened
+}
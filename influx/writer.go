@@ -0,0 +1,188 @@
+// Package influx writes BMS samples to InfluxDB v2 in line protocol.
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+type Config struct {
+	URL    string // eg "http://localhost:8086"
+	Org    string
+	Bucket string
+	Token  string
+
+	Measurement string            // default "dalybms"
+	Tags        map[string]string // added to every line, eg {"pack": "garage"}
+
+	BatchSize      int           // samples sent together, default 1
+	MaxBuffered    int           // samples kept while InfluxDB is unreachable, oldest dropped first. Default 1000
+	MaxRetries     int           // attempts per batch before keeping it for the next flush, default 3
+	RetryDelay     time.Duration // doubled after every failed attempt, default 1s
+	RequestTimeout time.Duration // default 10s
+
+	HTTPClient *http.Client // default http.DefaultClient
+}
+
+// Writer buffers samples and posts them to the InfluxDB v2 write API.
+// Feed it with poller.OnResult(writer.Update).
+type Writer struct {
+	config Config
+
+	mutex   sync.Mutex
+	pending [][]byte // encoded samples not written yet
+	lastErr error
+}
+
+func NewWriter(config Config) *Writer {
+	if config.Measurement == "" {
+		config.Measurement = "dalybms"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.MaxBuffered <= 0 {
+		config.MaxBuffered = 1000
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 1 * time.Second
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &Writer{config: config}
+}
+
+// Update queues a successful poll result and flushes once a batch is complete, retries
+// included, so a slow InfluxDB delays the caller. Failed polls are skipped.
+// Write errors are available from Err().
+func (writer *Writer) Update(result dalybms.PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+
+	writer.mutex.Lock()
+	writer.pending = append(writer.pending, Encode(result.Data, writer.config.Measurement, writer.config.Tags, result.Time))
+	if overflow := len(writer.pending) - writer.config.MaxBuffered; overflow > 0 {
+		writer.pending = writer.pending[overflow:]
+	}
+	batchComplete := len(writer.pending) >= writer.config.BatchSize
+	writer.mutex.Unlock()
+
+	if batchComplete {
+		err := writer.Flush(context.Background())
+
+		writer.mutex.Lock()
+		writer.lastErr = err
+		writer.mutex.Unlock()
+	}
+}
+
+// Latest flush error, nil if the latest flush succeeded
+func (writer *Writer) Err() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return writer.lastErr
+}
+
+// Flush posts all pending samples, eg before shutting down. Samples are kept when InfluxDB is unreachable.
+func (writer *Writer) Flush(ctx context.Context) error {
+	writer.mutex.Lock()
+	batch := writer.pending
+	writer.pending = nil
+	writer.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	body := bytes.Join(batch, nil)
+	retryable, err := writer.postWithRetries(ctx, body)
+	if err != nil && retryable {
+		// put the batch back in front of the samples queued meanwhile,
+		// rejected batches (4xx) are dropped as they would never succeed
+		writer.mutex.Lock()
+		writer.pending = append(batch, writer.pending...)
+		if overflow := len(writer.pending) - writer.config.MaxBuffered; overflow > 0 {
+			writer.pending = writer.pending[overflow:]
+		}
+		writer.mutex.Unlock()
+	}
+	return err
+}
+
+func (writer *Writer) postWithRetries(ctx context.Context, body []byte) (bool, error) {
+	delay := writer.config.RetryDelay
+	var err error
+	for attempt := 1; attempt <= writer.config.MaxRetries; attempt++ {
+		var retryable bool
+		retryable, err = writer.post(ctx, body)
+		if err == nil || !retryable {
+			return false, err
+		}
+		if attempt == writer.config.MaxRetries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return true, fmt.Errorf("influx write failed after %d tries: %w", writer.config.MaxRetries, err)
+}
+
+// post sends one request. Server errors, throttling and network errors are retryable.
+func (writer *Writer) post(ctx context.Context, body []byte) (bool, error) {
+	requestCtx, cancel := context.WithTimeout(ctx, writer.config.RequestTimeout)
+	defer cancel()
+
+	query := url.Values{}
+	query.Set("org", writer.config.Org)
+	query.Set("bucket", writer.config.Bucket)
+	query.Set("precision", "ns")
+	endpoint := strings.TrimSuffix(writer.config.URL, "/") + "/api/v2/write?" + query.Encode()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if writer.config.Token != "" {
+		request.Header.Set("Authorization", "Token "+writer.config.Token)
+	}
+
+	response, err := writer.config.HTTPClient.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		return false, nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("influx responded %s: %s", response.Status, strings.TrimSpace(string(message)))
+	retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retryable, err
+}
@@ -0,0 +1,234 @@
+package dalybms
+
+import (
+	"sync"
+	"time"
+)
+
+// AlarmMetric extracts the value checked by a rule, false when the sample lacks the data
+type AlarmMetric func(data *AllBMSData) (float64, bool)
+
+// Metrics for AlarmRule.Metric, in the units of the data: temperatures follow WithTemperatureUnit()
+var (
+	MetricSOC AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.SOC == nil {
+			return 0, false
+		}
+		return Widen(data.SOC.SOCPercent), true
+	}
+	MetricPackVoltage AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.SOC == nil {
+			return 0, false
+		}
+		return Widen(data.SOC.TotalVoltage), true
+	}
+	MetricCurrent AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.SOC == nil {
+			return 0, false
+		}
+		return Widen(data.SOC.Current), true
+	}
+	MetricHighestCellVoltage AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.CellVoltageRange == nil {
+			return 0, false
+		}
+		return Widen(data.CellVoltageRange.HighestVoltage), true
+	}
+	MetricLowestCellVoltage AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.CellVoltageRange == nil {
+			return 0, false
+		}
+		return Widen(data.CellVoltageRange.LowestVoltage), true
+	}
+	MetricCellVoltageDelta AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if len(data.CellVoltages) == 0 && data.CellVoltageRange == nil {
+			return 0, false
+		}
+		return data.Stats().CellVoltageDelta, true
+	}
+	MetricHighestTemperature AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.TemperatureRange == nil {
+			return 0, false
+		}
+		return Widen(data.TemperatureRange.HighestTemperature), true
+	}
+	MetricLowestTemperature AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.TemperatureRange == nil {
+			return 0, false
+		}
+		return Widen(data.TemperatureRange.LowestTemperature), true
+	}
+	MetricMOSTemperature AlarmMetric = func(data *AllBMSData) (float64, bool) {
+		if data.MOSTemperature == nil {
+			return 0, false
+		}
+		return *data.MOSTemperature, true
+	}
+)
+
+// Direction in which a rule trips
+type AlarmCondition int
+
+const (
+	AlarmAbove AlarmCondition = iota // trips when the value is above the threshold
+	AlarmBelow                       // trips when the value is below the threshold
+)
+
+// User-defined alarm, eg cell delta above 0.1 V:
+//
+//	AlarmRule{Name: "cell_delta", Metric: MetricCellVoltageDelta, Condition: AlarmAbove, Threshold: 0.1, Hysteresis: 0.02}
+type AlarmRule struct {
+	Name       string
+	Metric     AlarmMetric
+	Condition  AlarmCondition
+	Threshold  float64
+	Hysteresis float64       // the alarm clears only once the value is back past the threshold by this much
+	Debounce   time.Duration // the condition must hold this long before the alarm trips or clears, 0 = immediately
+}
+
+func (rule *AlarmRule) tripped(value float64) bool {
+	if rule.Condition == AlarmBelow {
+		return value < rule.Threshold
+	}
+	return value > rule.Threshold
+}
+
+func (rule *AlarmRule) cleared(value float64) bool {
+	if rule.Condition == AlarmBelow {
+		return value >= rule.Threshold+rule.Hysteresis
+	}
+	return value <= rule.Threshold-rule.Hysteresis
+}
+
+// Sent when a rule trips (Active) or clears
+type AlarmEvent struct {
+	Rule      string    `json:"rule"`
+	Active    bool      `json:"active"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Time      time.Time `json:"time"`
+}
+
+type alarmState struct {
+	rule         AlarmRule
+	active       bool
+	pendingSince time.Time // when the condition to change state was first seen, zero if not pending
+}
+
+// Alarms evaluates rules against every sample. Feed it with poller.OnResult(alarms.Update)
+// and read events from Subscribe().
+type Alarms struct {
+	mutex       sync.Mutex
+	states      []*alarmState
+	subscribers []chan AlarmEvent
+}
+
+func NewAlarms(rules ...AlarmRule) *Alarms {
+	alarms := &Alarms{}
+	for _, rule := range rules {
+		alarms.AddRule(rule)
+	}
+	return alarms
+}
+
+// Add a rule, evaluated from the next sample
+func (alarms *Alarms) AddRule(rule AlarmRule) {
+	alarms.mutex.Lock()
+	defer alarms.mutex.Unlock()
+	alarms.states = append(alarms.states, &alarmState{rule: rule})
+}
+
+// Subscribe returns a channel receiving every event. Events are dropped when the
+// channel buffer is full. The channel is closed by Close().
+func (alarms *Alarms) Subscribe(bufferSize int) <-chan AlarmEvent {
+	alarms.mutex.Lock()
+	defer alarms.mutex.Unlock()
+
+	channel := make(chan AlarmEvent, bufferSize)
+	alarms.subscribers = append(alarms.subscribers, channel)
+	return channel
+}
+
+// Close closes subscriber channels
+func (alarms *Alarms) Close() {
+	alarms.mutex.Lock()
+	defer alarms.mutex.Unlock()
+	for _, channel := range alarms.subscribers {
+		close(channel)
+	}
+	alarms.subscribers = nil
+}
+
+// Names of the rules currently tripped
+func (alarms *Alarms) Active() []string {
+	alarms.mutex.Lock()
+	defer alarms.mutex.Unlock()
+
+	var active []string
+	for _, state := range alarms.states {
+		if state.active {
+			active = append(active, state.rule.Name)
+		}
+	}
+	return active
+}
+
+// Update evaluates a poll result. Failed polls and rules whose data is missing keep their state.
+func (alarms *Alarms) Update(result PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	sampleTime := result.Time
+	if sampleTime.IsZero() {
+		sampleTime = time.Now()
+	}
+
+	alarms.mutex.Lock()
+	defer alarms.mutex.Unlock()
+
+	for _, state := range alarms.states {
+		if state.rule.Metric == nil {
+			continue
+		}
+		value, ok := state.rule.Metric(result.Data)
+		if !ok {
+			state.pendingSince = time.Time{}
+			continue
+		}
+
+		changing := state.rule.tripped(value)
+		if state.active {
+			changing = state.rule.cleared(value)
+		}
+		if !changing {
+			state.pendingSince = time.Time{}
+			continue
+		}
+		if state.pendingSince.IsZero() {
+			state.pendingSince = sampleTime
+		}
+		if sampleTime.Sub(state.pendingSince) < state.rule.Debounce {
+			continue
+		}
+
+		state.active = !state.active
+		state.pendingSince = time.Time{}
+		alarms.publish(AlarmEvent{
+			Rule:      state.rule.Name,
+			Active:    state.active,
+			Value:     value,
+			Threshold: state.rule.Threshold,
+			Time:      sampleTime,
+		})
+	}
+}
+
+// publish delivers an event without blocking on slow subscribers. Must be called with mutex held.
+func (alarms *Alarms) publish(event AlarmEvent) {
+	for _, channel := range alarms.subscribers {
+		select {
+		case channel <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// ErrAuthenticationFailed is returned (wrapped) by Authenticate() and the writes it precedes
+// when the board rejects the password
+var ErrAuthenticationFailed = errors.New("password rejected by the BMS")
+
+// Max length of a password, it fills the data bytes of the login frame
+const MaxPasswordLength = protocol.DataLength
+
+// Log in with password before parameter writes and controls, for newer firmware that silently
+// ignores them otherwise. command is the login command code of the board: the published protocol
+// doesn't cover it, take it from the documentation of the board or a capture of the Daly app.
+// The password is sent as ASCII in the data bytes, zero padded, and the response data byte 0 is
+// 1 when the board accepts it.
+func WithPassword(command protocol.Command, password string) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.loginCommand = &command
+		bms.password = password
+	}
+}
+
+// Change the password sent by Authenticate(), eg after changing it in the Daly app. The next
+// write logs in again.
+func (bms *DalyBMSIstance) SetPassword(password string) {
+	bms.stateMutex.Lock()
+	bms.password = password
+	bms.stateMutex.Unlock()
+	bms.forgetAuthentication()
+}
+
+// Log in with the password of WithPassword(). Writes do it on their own before the first write,
+// call it again when the board dropped the session, eg after a power cycle. Returns
+// ErrUnsupported without WithPassword().
+func (bms *DalyBMSIstance) Authenticate() error {
+	return bms.AuthenticateCtx(context.Background())
+}
+
+// Authenticate with cancellation support
+func (bms *DalyBMSIstance) AuthenticateCtx(ctx context.Context) error {
+	if bms.loginCommand == nil {
+		return fmt.Errorf("authentication: %w", ErrUnsupported)
+	}
+
+	bms.stateMutex.Lock()
+	password := bms.password
+	bms.stateMutex.Unlock()
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password longer than %d characters", MaxPasswordLength)
+	}
+	var payload [protocol.DataLength]byte
+	copy(payload[:], password)
+
+	response, err := bms.sendReadRequestCtx(ctx, *bms.loginCommand, payload[:], 1, false)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no response from Authenticate")
+	}
+	responseBytes, ok := response.([]byte)
+	if !ok || len(responseBytes) < 1 {
+		return fmt.Errorf("unexpected response type for authenticate")
+	}
+	if responseBytes[0] != 1 {
+		return fmt.Errorf("authenticate: %w", ErrAuthenticationFailed)
+	}
+
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	if bms.authenticated == nil {
+		bms.authenticated = map[byte]bool{}
+	}
+	bms.authenticated[bms.requestAddress(ctx)] = true
+	return nil
+}
+
+// authorizeWrite logs in to the BMS ctx targets before a write, once per connection. Does
+// nothing without WithPassword().
+func (bms *DalyBMSIstance) authorizeWrite(ctx context.Context) error {
+	if bms.loginCommand == nil {
+		return nil
+	}
+	bms.stateMutex.Lock()
+	authenticated := bms.authenticated[bms.requestAddress(ctx)]
+	bms.stateMutex.Unlock()
+	if authenticated {
+		return nil
+	}
+	return bms.AuthenticateCtx(ctx)
+}
+
+// forgetAuthentication makes the next write log in again, after a new connection or a restart
+func (bms *DalyBMSIstance) forgetAuthentication() {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	bms.authenticated = nil
+}
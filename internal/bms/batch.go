@@ -0,0 +1,53 @@
+package dalybms
+
+import (
+	"context"
+)
+
+// busBatch holds the bus across several requests, see beginBatch()
+type busBatch struct {
+	bms      *DalyBMSIstance
+	dirty    bool     // the read buffer may hold stale data and must be drained before the next request
+	deferred []func() // run once the bus is released
+}
+
+type busBatchKey struct{}
+
+// beginBatch takes the bus for a sequence of requests made with the returned context.
+// The read buffer is drained once instead of before every request, which saves a read
+// timeout per request on serial links. Other goroutines wait until release is called.
+func (bms *DalyBMSIstance) beginBatch(ctx context.Context) (context.Context, func()) {
+	if bms.batchFrom(ctx) != nil {
+		// already batched by the caller
+		return ctx, func() {}
+	}
+
+	bms.busMutex.Lock()
+	batch := &busBatch{bms: bms, dirty: true}
+	release := func() {
+		bms.busMutex.Unlock()
+		for _, deferredFunc := range batch.deferred {
+			deferredFunc()
+		}
+	}
+	return context.WithValue(ctx, busBatchKey{}, batch), release
+}
+
+// batchFrom returns the batch carried by ctx for this BMS, nil if none
+func (bms *DalyBMSIstance) batchFrom(ctx context.Context) *busBatch {
+	batch, _ := ctx.Value(busBatchKey{}).(*busBatch)
+	if batch == nil || batch.bms != bms {
+		return nil
+	}
+	return batch
+}
+
+// afterBatch runs deferredFunc once the bus is released, immediately when ctx is not batched.
+// Used for action hooks, which may call back into the BMS.
+func (bms *DalyBMSIstance) afterBatch(ctx context.Context, deferredFunc func()) {
+	if batch := bms.batchFrom(ctx); batch != nil {
+		batch.deferred = append(batch.deferred, deferredFunc)
+		return
+	}
+	deferredFunc()
+}
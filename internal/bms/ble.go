@@ -0,0 +1,126 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Daly BLE UART service: requests are written to 0xfff2, responses are notified on 0xfff1
+const (
+	BLEServiceUUID     = "0000fff0-0000-1000-8000-00805f9b34fb"
+	BLENotifyCharUUID  = "0000fff1-0000-1000-8000-00805f9b34fb"
+	BLEWriteCharUUID   = "0000fff2-0000-1000-8000-00805f9b34fb"
+	bleAddress         = 8 // host address used by the BMS over Bluetooth
+	bleDefaultWaitTime = 500 * time.Millisecond
+)
+
+// BLEDevice is a connected Daly BMS exposing the UART service characteristics.
+// Implement it on top of the BLE stack of your choice (eg tinygo-org/bluetooth).
+type BLEDevice interface {
+	// Write a request to the write characteristic (0xfff2)
+	Write(data []byte) error
+	// Subscribe to the notify characteristic (0xfff1)
+	EnableNotifications(callback func(data []byte)) error
+	Disconnect() error
+}
+
+// BLEConnector scans for and connects to a BMS by MAC address, eg "AA:BB:CC:DD:EE:FF"
+type BLEConnector func(ctx context.Context, macAddress string) (BLEDevice, error)
+
+// bleTransport adapts the notification based BLEDevice to the stream reads used by the protocol code
+type bleTransport struct {
+	device      BLEDevice
+	readTimeout time.Duration
+	mutex       sync.Mutex
+	buffer      []byte
+	dataReady   chan struct{}
+}
+
+func newBLETransport(device BLEDevice, readTimeout time.Duration) (*bleTransport, error) {
+	transport := &bleTransport{
+		device:      device,
+		readTimeout: readTimeout,
+		dataReady:   make(chan struct{}, 1),
+	}
+	if err := device.EnableNotifications(transport.onNotification); err != nil {
+		return nil, fmt.Errorf("failed to enable BLE notifications: %w", err)
+	}
+	return transport, nil
+}
+
+func (transport *bleTransport) onNotification(data []byte) {
+	transport.mutex.Lock()
+	transport.buffer = append(transport.buffer, data...)
+	transport.mutex.Unlock()
+
+	select {
+	case transport.dataReady <- struct{}{}:
+	default:
+	}
+}
+
+// Read returns the buffered bytes as soon as there are any, up to len(b), like a serial read.
+// It waits up to the read timeout for the first notification, returns 0, nil on timeout.
+func (transport *bleTransport) Read(b []byte) (int, error) {
+	deadline := time.NewTimer(transport.readTimeout)
+	defer deadline.Stop()
+
+	for {
+		transport.mutex.Lock()
+		if len(transport.buffer) > 0 {
+			bytesRead := copy(b, transport.buffer)
+			transport.buffer = transport.buffer[bytesRead:]
+			transport.mutex.Unlock()
+			return bytesRead, nil
+		}
+		transport.mutex.Unlock()
+
+		select {
+		case <-transport.dataReady:
+		case <-deadline.C:
+			return 0, nil
+		}
+	}
+}
+
+func (transport *bleTransport) Write(b []byte) (int, error) {
+	if err := transport.device.Write(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (transport *bleTransport) Close() error {
+	return transport.device.Disconnect()
+}
+
+// ConnectBLE connects to the BMS over Bluetooth through the given connector
+func (bms *DalyBMSIstance) ConnectBLE(macAddress string, connector BLEConnector) error {
+	return bms.ConnectBLECtx(context.Background(), macAddress, connector)
+}
+
+// ConnectBLE with cancellation support. ctx also bounds the initial status fetch.
+func (bms *DalyBMSIstance) ConnectBLECtx(ctx context.Context, macAddress string, connector BLEConnector) error {
+	if connector == nil {
+		return fmt.Errorf("no BLE connector provided")
+	}
+
+	device, err := connector(ctx, macAddress)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", macAddress, err)
+	}
+
+	transport, err := newBLETransport(device, bleDefaultWaitTime)
+	if err != nil {
+		_ = device.Disconnect()
+		return err
+	}
+
+	bms.busMutex.Lock()
+	bms.address = bleAddress
+	bms.busMutex.Unlock()
+
+	return bms.ConnectTransportCtx(ctx, transport)
+}
@@ -0,0 +1,178 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cached is a query result with freshness metadata. When the device fails and an earlier
+// value is still within MaxStale, Value holds that value, Stale is true and Err the failure.
+type Cached[T any] struct {
+	Value T         `json:"value"`
+	Time  time.Time `json:"time"` // when Value was read from the device
+	Stale bool      `json:"stale"`
+	Err   error     `json:"-"`
+}
+
+// Age of the value
+func (cached Cached[T]) Age() time.Duration {
+	return time.Since(cached.Time)
+}
+
+// Cache settings
+type CacheConfig struct {
+	TTL      time.Duration // values younger than TTL are served without a request, 0 = always query
+	MaxStale time.Duration // oldest value served when the device fails, 0 = no limit
+}
+
+// Cache decorates a client and remembers the last good value of each query, so a dashboard
+// gets a reading marked as stale instead of an error when the device momentarily fails.
+type Cache struct {
+	bms    *DalyBMSIstance
+	config CacheConfig
+	mutex  sync.Mutex
+	values map[string]Cached[any]
+}
+
+func NewCache(bms *DalyBMSIstance, config CacheConfig) *Cache {
+	return &Cache{bms: bms, config: config, values: map[string]Cached[any]{}}
+}
+
+// Forget the remembered values
+func (cache *Cache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.values = map[string]Cached[any]{}
+}
+
+// cachedQuery serves key from the cache within TTL, otherwise runs fetch and falls back to the
+// remembered value on failure. An error is returned only when no usable value is remembered.
+func cachedQuery[T any](ctx context.Context, cache *Cache, key string, fetch func(ctx context.Context) (T, error)) (Cached[T], error) {
+	if target, ok := targetFrom(ctx); ok {
+		// values of another BMS, see AtAddress()
+		key += fmt.Sprintf("@%02x/%02x", target.requestAddress, target.responseAddress)
+	}
+	cache.mutex.Lock()
+	previous, found := cache.values[key]
+	cache.mutex.Unlock()
+
+	if found && cache.config.TTL > 0 && previous.Age() < cache.config.TTL {
+		return Cached[T]{Value: previous.Value.(T), Time: previous.Time}, nil
+	}
+
+	value, err := fetch(ctx)
+	if err == nil {
+		result := Cached[T]{Value: value, Time: time.Now()}
+		cache.mutex.Lock()
+		cache.values[key] = Cached[any]{Value: value, Time: result.Time}
+		cache.mutex.Unlock()
+		return result, nil
+	}
+
+	if !found || (cache.config.MaxStale > 0 && previous.Age() > cache.config.MaxStale) {
+		return Cached[T]{Err: err}, err
+	}
+	return Cached[T]{Value: previous.Value.(T), Time: previous.Time, Stale: true, Err: err}, nil
+}
+
+// Cached GetStatus()
+func (cache *Cache) GetStatus() (Cached[*StatusData], error) {
+	return cache.GetStatusCtx(context.Background())
+}
+
+// GetStatus with cancellation support
+func (cache *Cache) GetStatusCtx(ctx context.Context) (Cached[*StatusData], error) {
+	return cachedQuery(ctx, cache, "status", cache.bms.GetStatusCtx)
+}
+
+// Cached GetSOC()
+func (cache *Cache) GetSOC() (Cached[*SOCData], error) {
+	return cache.GetSOCCtx(context.Background())
+}
+
+// GetSOC with cancellation support
+func (cache *Cache) GetSOCCtx(ctx context.Context) (Cached[*SOCData], error) {
+	return cachedQuery(ctx, cache, "soc", cache.bms.GetSOCCtx)
+}
+
+// Cached GetCellVoltageRange()
+func (cache *Cache) GetCellVoltageRange() (Cached[*CellVoltageRangeData], error) {
+	return cache.GetCellVoltageRangeCtx(context.Background())
+}
+
+// GetCellVoltageRange with cancellation support
+func (cache *Cache) GetCellVoltageRangeCtx(ctx context.Context) (Cached[*CellVoltageRangeData], error) {
+	return cachedQuery(ctx, cache, "cell_voltage_range", cache.bms.GetCellVoltageRangeCtx)
+}
+
+// Cached GetTemperatureRange()
+func (cache *Cache) GetTemperatureRange() (Cached[*TemperatureRangeData], error) {
+	return cache.GetTemperatureRangeCtx(context.Background())
+}
+
+// GetTemperatureRange with cancellation support
+func (cache *Cache) GetTemperatureRangeCtx(ctx context.Context) (Cached[*TemperatureRangeData], error) {
+	return cachedQuery(ctx, cache, "temperature_range", cache.bms.GetTemperatureRangeCtx)
+}
+
+// Cached GetMosfetStatus()
+func (cache *Cache) GetMosfetStatus() (Cached[*MosfetStatusData], error) {
+	return cache.GetMosfetStatusCtx(context.Background())
+}
+
+// GetMosfetStatus with cancellation support
+func (cache *Cache) GetMosfetStatusCtx(ctx context.Context) (Cached[*MosfetStatusData], error) {
+	return cachedQuery(ctx, cache, "mosfet_status", cache.bms.GetMosfetStatusCtx)
+}
+
+// Cached GetCellVoltages()
+func (cache *Cache) GetCellVoltages() (Cached[map[int]float64], error) {
+	return cache.GetCellVoltagesCtx(context.Background())
+}
+
+// GetCellVoltages with cancellation support
+func (cache *Cache) GetCellVoltagesCtx(ctx context.Context) (Cached[map[int]float64], error) {
+	return cachedQuery(ctx, cache, "cell_voltages", cache.bms.GetCellVoltagesCtx)
+}
+
+// Cached GetTemperatures()
+func (cache *Cache) GetTemperatures() (Cached[map[int]float64], error) {
+	return cache.GetTemperaturesCtx(context.Background())
+}
+
+// GetTemperatures with cancellation support
+func (cache *Cache) GetTemperaturesCtx(ctx context.Context) (Cached[map[int]float64], error) {
+	return cachedQuery(ctx, cache, "temperatures", cache.bms.GetTemperaturesCtx)
+}
+
+// Cached GetBalancingStatus()
+func (cache *Cache) GetBalancingStatus() (Cached[map[int]bool], error) {
+	return cache.GetBalancingStatusCtx(context.Background())
+}
+
+// GetBalancingStatus with cancellation support
+func (cache *Cache) GetBalancingStatusCtx(ctx context.Context) (Cached[map[int]bool], error) {
+	return cachedQuery(ctx, cache, "balancing_status", cache.bms.GetBalancingStatusCtx)
+}
+
+// Cached GetErrors()
+func (cache *Cache) GetErrors() (Cached[BMSErrors], error) {
+	return cache.GetErrorsCtx(context.Background())
+}
+
+// GetErrors with cancellation support
+func (cache *Cache) GetErrorsCtx(ctx context.Context) (Cached[BMSErrors], error) {
+	return cachedQuery(ctx, cache, "errors", cache.bms.GetErrorsCtx)
+}
+
+// Cached GetAllData()
+func (cache *Cache) GetAllData() (Cached[*AllBMSData], error) {
+	return cache.GetAllDataCtx(context.Background())
+}
+
+// GetAllData with cancellation support
+func (cache *Cache) GetAllDataCtx(ctx context.Context) (Cached[*AllBMSData], error) {
+	return cachedQuery(ctx, cache, "all_data", cache.bms.GetAllDataCtx)
+}
@@ -0,0 +1,97 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Daly CAN addressing: extended ID 0x18<command><target><source>, the host uses 0x40
+const (
+	canIDPrefix        = 0x18
+	canHostAddress     = 0x40
+	CANDefaultBMSIndex = 0x01
+)
+
+// CANBus sends and receives extended CAN frames (SocketCAN, USB-CAN adapters...)
+// ReadFrame must return after a short timeout with ok=false when no frame is available.
+type CANBus interface {
+	ReadFrame() (id uint32, data []byte, ok bool, err error)
+	WriteFrame(id uint32, data []byte) error
+	Close() error
+}
+
+// canTransport translates the 13-byte UART frames used by the protocol code to CAN frames and back
+type canTransport struct {
+	bus        CANBus
+	bmsAddress byte
+	pending    []byte // synthesized UART bytes not read yet
+}
+
+// NewCANTransport wraps a CAN bus so it can be used with ConnectTransport
+func NewCANTransport(bus CANBus, bmsAddress byte) Transport {
+	return &canTransport{
+		bus:        bus,
+		bmsAddress: bmsAddress,
+	}
+}
+
+func (transport *canTransport) Write(frame []byte) (int, error) {
+	request, err := protocol.Decode(frame)
+	if err != nil {
+		return 0, fmt.Errorf("invalid request frame for CAN: %w", err)
+	}
+
+	canID := uint32(canIDPrefix)<<24 | uint32(request.Command)<<16 | uint32(transport.bmsAddress)<<8 | canHostAddress
+	if err := transport.bus.WriteFrame(canID, request.Data[:]); err != nil {
+		return 0, err
+	}
+	return len(frame), nil
+}
+
+// Read returns UART-equivalent frames (a5, address, command, 08, data, CRC) built from CAN responses
+func (transport *canTransport) Read(b []byte) (int, error) {
+	for len(transport.pending) == 0 {
+		canID, data, ok, err := transport.bus.ReadFrame()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			// timeout, no more data
+			return 0, nil
+		}
+
+		if byte(canID>>24)&0x1f != canIDPrefix || byte(canID>>8) != canHostAddress || byte(canID) != transport.bmsAddress {
+			// not a response addressed to us
+			continue
+		}
+
+		// already filtered by CAN address, answer like a UART board
+		response := protocol.Frame{Address: DefaultResponseAddress, Command: protocol.Command(canID >> 16)}
+		copy(response.Data[:], data)
+		transport.pending = response.Encode()
+	}
+
+	bytesRead := copy(b, transport.pending)
+	transport.pending = transport.pending[bytesRead:]
+	return bytesRead, nil
+}
+
+func (transport *canTransport) Close() error {
+	return transport.bus.Close()
+}
+
+// ConnectCAN connects to the BMS through a SocketCAN interface, eg "can0"
+func (bms *DalyBMSIstance) ConnectCAN(interfaceName string) error {
+	return bms.ConnectCANCtx(context.Background(), interfaceName)
+}
+
+// ConnectCAN with cancellation support. ctx also bounds the initial status fetch.
+func (bms *DalyBMSIstance) ConnectCANCtx(ctx context.Context, interfaceName string) error {
+	bus, err := OpenSocketCAN(interfaceName)
+	if err != nil {
+		return err
+	}
+	return bms.ConnectTransportCtx(ctx, NewCANTransport(bus, CANDefaultBMSIndex))
+}
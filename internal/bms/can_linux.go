@@ -0,0 +1,99 @@
+//go:build linux
+
+package dalybms
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	canRawProtocol  = 1          // CAN_RAW
+	canEFFFlag      = 0x80000000 // extended frame format
+	canEFFMask      = 0x1fffffff
+	canFrameSize    = 16
+	canReadTimeout  = 100 * time.Millisecond
+	sockaddrCANSize = 24
+)
+
+// socketCAN is a raw SocketCAN socket bound to one interface
+type socketCAN struct {
+	fd int
+}
+
+// OpenSocketCAN opens a raw SocketCAN socket on the given interface, eg "can0"
+func OpenSocketCAN(interfaceName string) (CANBus, error) {
+	netInterface, err := net.InterfaceByName(interfaceName)
+	if err != nil {
+		return nil, fmt.Errorf("CAN interface %s not found: %w", interfaceName, err)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_CAN, syscall.SOCK_RAW, canRawProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CAN socket: %w", err)
+	}
+
+	// struct sockaddr_can { sa_family_t can_family; int can_ifindex; union can_addr; }
+	var address [sockaddrCANSize]byte
+	binary.NativeEndian.PutUint16(address[0:2], syscall.AF_CAN)
+	binary.NativeEndian.PutUint32(address[4:8], uint32(netInterface.Index))
+	_, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&address[0])), sockaddrCANSize)
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind CAN socket to %s: %w", interfaceName, errno)
+	}
+
+	readTimeout := syscall.NsecToTimeval(canReadTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &readTimeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set CAN read timeout: %w", err)
+	}
+
+	return &socketCAN{fd: fd}, nil
+}
+
+func (bus *socketCAN) ReadFrame() (uint32, []byte, bool, error) {
+	// struct can_frame { u32 can_id; u8 len; u8 pad; u8 res0; u8 len8_dlc; u8 data[8]; }
+	var frame [canFrameSize]byte
+	bytesRead, err := syscall.Read(bus.fd, frame[:])
+	if err != nil {
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+			return 0, nil, false, nil
+		}
+		return 0, nil, false, err
+	}
+	if bytesRead < canFrameSize {
+		return 0, nil, false, nil
+	}
+
+	canID := binary.NativeEndian.Uint32(frame[0:4])
+	if canID&canEFFFlag == 0 {
+		// standard frames are not used by the BMS, report them with a zero ID so they get skipped
+		return 0, nil, true, nil
+	}
+	dataLength := min(int(frame[4]), 8)
+	return canID & canEFFMask, append([]byte(nil), frame[8:8+dataLength]...), true, nil
+}
+
+func (bus *socketCAN) WriteFrame(id uint32, data []byte) error {
+	if len(data) > 8 {
+		return fmt.Errorf("CAN payload too long: %d bytes", len(data))
+	}
+
+	var frame [canFrameSize]byte
+	binary.NativeEndian.PutUint32(frame[0:4], (id&canEFFMask)|canEFFFlag)
+	frame[4] = byte(len(data))
+	copy(frame[8:], data)
+
+	_, err := syscall.Write(bus.fd, frame[:])
+	return err
+}
+
+func (bus *socketCAN) Close() error {
+	return syscall.Close(bus.fd)
+}
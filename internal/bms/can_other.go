@@ -0,0 +1,12 @@
+//go:build !linux
+
+package dalybms
+
+import (
+	"fmt"
+)
+
+// OpenSocketCAN is only available on Linux. Use NewCANTransport with your own CANBus elsewhere.
+func OpenSocketCAN(interfaceName string) (CANBus, error) {
+	return nil, fmt.Errorf("SocketCAN is not supported on this platform")
+}
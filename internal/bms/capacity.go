@@ -0,0 +1,59 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Get the remaining capacity in Ah, without the MOSFET flags of GetMosfetStatus() and
+// without triggering OnMosfetChange
+func (bms *DalyBMSIstance) GetRemainingCapacity() (float64, error) {
+	return bms.GetRemainingCapacityCtx(context.Background())
+}
+
+// GetRemainingCapacity with cancellation support
+func (bms *DalyBMSIstance) GetRemainingCapacityCtx(ctx context.Context) (float64, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return 0, err
+	} else if sinowealth {
+		remaining, err := bms.sinowealthInt32(ctx, sinowealthRegRemainingCap)
+		if err != nil {
+			return 0, err
+		}
+		return float64(remaining) / 1000, nil
+	}
+
+	// bytes 4-7 of the MOSFET status frame, mAh
+	data, err := bms.readParameter(ctx, protocol.CommandMosfetStatus, "get_remaining_capacity")
+	if err != nil {
+		return 0, err
+	}
+	return float64(int32(binary.BigEndian.Uint32(data[4:8]))) / 1000, nil
+}
+
+// Get the full capacity in Ah the firmware learned over the last charge cycle. Only
+// Sinowealth boards report it, Daly boards return ErrUnsupported: use the rated capacity
+// of GetRatedParams() instead.
+func (bms *DalyBMSIstance) GetCycleCapacity() (float64, error) {
+	return bms.GetCycleCapacityCtx(context.Background())
+}
+
+// GetCycleCapacity with cancellation support
+func (bms *DalyBMSIstance) GetCycleCapacityCtx(ctx context.Context) (float64, error) {
+	sinowealth, err := bms.isSinowealth(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !sinowealth {
+		return 0, fmt.Errorf("cycle capacity: %w", ErrUnsupported)
+	}
+
+	full, err := bms.sinowealthInt32(ctx, sinowealthRegFullCapacity)
+	if err != nil {
+		return 0, err
+	}
+	return float64(full) / 1000, nil
+}
@@ -0,0 +1,69 @@
+package dalybms
+
+import (
+	"fmt"
+)
+
+// Physical label for each logical cell index (1-based), eg 11 => "Module B, cell 3"
+type CellMap map[int]string
+
+// NewModuleCellMap builds a map for packs made of equal modules wired in series.
+// moduleNames are used in wiring order, eg []string{"A", "B", "C", "D"} for a 16S pack of 4S modules.
+func NewModuleCellMap(cellsPerModule int, moduleNames []string) CellMap {
+	cellMap := make(CellMap)
+	if cellsPerModule <= 0 {
+		return cellMap
+	}
+	for moduleIndex, moduleName := range moduleNames {
+		for position := 1; position <= cellsPerModule; position++ {
+			cellIndex := moduleIndex*cellsPerModule + position
+			cellMap[cellIndex] = fmt.Sprintf("Module %s, cell %d", moduleName, position)
+		}
+	}
+	return cellMap
+}
+
+// Label for a cell index, falls back to "cell N" for unmapped cells
+func (cellMap CellMap) Label(cellIndex int) string {
+	if label, ok := cellMap[cellIndex]; ok {
+		return label
+	}
+	return fmt.Sprintf("cell %d", cellIndex)
+}
+
+// Set the physical cell mapping used in outputs
+func (bms *DalyBMSIstance) SetCellMap(cellMap CellMap) {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	bms.cellMap = cellMap
+}
+
+// Label for a cell index using the configured mapping
+func (bms *DalyBMSIstance) CellLabel(cellIndex int) string {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	return bms.cellMap.Label(cellIndex)
+}
+
+// cellLabels returns the label of every cell in the pack, nil without a mapping
+func (bms *DalyBMSIstance) cellLabels(numberOfCells int) map[int]string {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+
+	if len(bms.cellMap) == 0 {
+		return nil
+	}
+	labels := make(map[int]string, numberOfCells)
+	for cellIndex := 1; cellIndex <= numberOfCells; cellIndex++ {
+		labels[cellIndex] = bms.cellMap.Label(cellIndex)
+	}
+	return labels
+}
+
+// cellName returns the label of a cell when labels has one, "cell N" otherwise
+func cellName(labels map[int]string, cellIndex int) string {
+	if label := labels[cellIndex]; label != "" {
+		return label
+	}
+	return fmt.Sprintf("cell %d", cellIndex)
+}
@@ -0,0 +1,151 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// ForEachCellVoltage calls fn with each cell voltage as soon as its frame is read, without
+// buffering the whole response. Useful for large packs (32S, 48S) and streaming output.
+// Cells are 1-based and arrive in frame order. fn runs while the bus is held and must not call the BMS.
+// Cells of lost frames are skipped and reported by a *MissingFramesError once the others are passed.
+func (bms *DalyBMSIstance) ForEachCellVoltage(fn func(cellIndex int, voltage float64)) error {
+	return bms.ForEachCellVoltageCtx(context.Background(), fn)
+}
+
+// ForEachCellVoltage with cancellation support
+func (bms *DalyBMSIstance) ForEachCellVoltageCtx(ctx context.Context, fn func(cellIndex int, voltage float64)) error {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return err
+	} else if sinowealth {
+		voltages, err := bms.sinowealthCellVoltages(ctx)
+		if err != nil {
+			return err
+		}
+		for _, cellIndex := range slices.Sorted(maps.Keys(voltages)) {
+			fn(cellIndex, voltages[cellIndex])
+		}
+		return nil
+	}
+
+	maxResp, err := bms.calculateNumberOfResponses(ctx, "cells", 3)
+	if err != nil {
+		return err
+	}
+	status, err := bms.statusCtx(ctx)
+	if err != nil {
+		return err
+	}
+	numberOfCells := status.NumberOfCells
+
+	seenFrames := make(map[byte]bool) // frames are passed again after a retry
+	onFrame := func(data []byte) {
+		frameNumber := data[0]
+		if frameNumber == 0 || seenFrames[frameNumber] {
+			return
+		}
+		seenFrames[frameNumber] = true
+
+		// frame N holds cells 3N-2..3N, in mV
+		for offset := 0; offset < 3; offset++ {
+			cellIndex := (int(frameNumber)-1)*3 + offset + 1
+			if cellIndex > numberOfCells {
+				return
+			}
+			millivolts := binary.BigEndian.Uint16(data[1+offset*2 : 3+offset*2])
+			fn(cellIndex, float64(millivolts)/1000.0)
+		}
+	}
+
+	response, err := bms.streamReadRequestCtx(ctx, protocol.CommandCellVoltages, nil, maxResp, true, onFrame)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no data for get_cell_voltages")
+	}
+
+	var missingFrames []int
+	for frameNumber := 1; (frameNumber-1)*3 < numberOfCells; frameNumber++ {
+		if !seenFrames[byte(frameNumber)] {
+			missingFrames = append(missingFrames, frameNumber)
+		}
+	}
+	if len(missingFrames) > 0 {
+		return &MissingFramesError{Command: protocol.CommandCellVoltages, Frames: missingFrames}
+	}
+	return nil
+}
+
+// Get cell voltages ordered by cell index, cell 1 first
+func (bms *DalyBMSIstance) GetCellVoltageSlice() ([]float64, error) {
+	return bms.GetCellVoltageSliceCtx(context.Background())
+}
+
+// GetCellVoltageSlice with cancellation support. Cells missing from the response are 0, lost
+// frames are reported by a *MissingFramesError.
+func (bms *DalyBMSIstance) GetCellVoltageSliceCtx(ctx context.Context) ([]float64, error) {
+	status, err := bms.statusCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	voltages := make([]float64, status.NumberOfCells)
+
+	err = bms.ForEachCellVoltageCtx(ctx, func(cellIndex int, voltage float64) {
+		if cellIndex <= len(voltages) {
+			voltages[cellIndex-1] = voltage
+		}
+	})
+	var missingError *MissingFramesError
+	if err != nil && !errors.As(err, &missingError) {
+		return nil, err
+	}
+	return voltages, err
+}
+
+// Get temperatures ordered by sensor index, sensor 1 first
+func (bms *DalyBMSIstance) GetTemperatureSlice() ([]float64, error) {
+	return bms.GetTemperatureSliceCtx(context.Background())
+}
+
+// GetTemperatureSlice with cancellation support
+func (bms *DalyBMSIstance) GetTemperatureSliceCtx(ctx context.Context) ([]float64, error) {
+	temperatures, err := bms.GetTemperaturesCtx(ctx)
+	if temperatures == nil {
+		return nil, err
+	}
+	return OrderedValues(temperatures), err
+}
+
+// Cell voltages ordered by cell index, cell 1 first
+func (data *AllBMSData) CellVoltagesSlice() []float64 {
+	return OrderedValues(data.CellVoltages)
+}
+
+// Temperatures ordered by sensor index, sensor 1 first
+func (data *AllBMSData) TemperaturesSlice() []float64 {
+	return OrderedValues(data.Temperatures)
+}
+
+// OrderedValues converts a 1-based index map, eg from GetCellVoltages(), to a slice.
+// The slice is as long as the highest index, missing indexes are 0.
+func OrderedValues(values map[int]float64) []float64 {
+	length := 0
+	for index := range values {
+		length = max(length, index)
+	}
+
+	ordered := make([]float64, length)
+	for index, value := range values {
+		if index >= 1 {
+			ordered[index-1] = value
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,155 @@
+package dalybms
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// Minimum change of a value before a ChangeDetector reports it again, 0 reports any change
+type Deadbands struct {
+	CellVoltage float64 // V, cell voltages, ranges and delta
+	PackVoltage float64 // V
+	Current     float64 // A
+	SOC         float64 // %
+	Temperature float64 // °C, or the unit of WithTemperatureUnit()
+	Capacity    float64 // Ah
+	Power       float64 // W
+	Energy      float64 // Wh
+}
+
+// 5 mV, 0.1 V, 0.1 A, 0.5 %, 0.5 °C, 0.1 Ah, 5 W, 10 Wh
+func DefaultDeadbands() Deadbands {
+	return Deadbands{
+		CellVoltage: 0.005,
+		PackVoltage: 0.1,
+		Current:     0.1,
+		SOC:         0.5,
+		Temperature: 0.5,
+		Capacity:    0.1,
+		Power:       5,
+		Energy:      10,
+	}
+}
+
+// ChangeDetector reports the fields of successive samples that moved beyond their deadband, so
+// telemetry outputs publish only changes. Fields are keyed by path, eg "soc/soc_percent",
+// "cell_voltages/3" or "stats/power_w", see AllBMSData and Stats for the names.
+type ChangeDetector struct {
+	deadbands Deadbands
+	mutex     sync.Mutex
+	reported  map[string]any
+}
+
+func NewChangeDetector(deadbands Deadbands) *ChangeDetector {
+	return &ChangeDetector{deadbands: deadbands, reported: map[string]any{}}
+}
+
+// Changes returns the fields of data that differ from their last reported value by at least
+// their deadband, every field on the first call after creation or Reset. Only reported values
+// become the reference, so a slow drift is reported once it adds up to a deadband.
+func (detector *ChangeDetector) Changes(data *AllBMSData) map[string]any {
+	detector.mutex.Lock()
+	defer detector.mutex.Unlock()
+
+	changes := map[string]any{}
+	for path, field := range detector.fields(data) {
+		previous, found := detector.reported[path]
+		if found && !field.changed(previous) {
+			continue
+		}
+		detector.reported[path] = field.value
+		changes[path] = field.value
+	}
+	return changes
+}
+
+// Forget the reported values, eg after a reconnection
+func (detector *ChangeDetector) Reset() {
+	detector.mutex.Lock()
+	defer detector.mutex.Unlock()
+	detector.reported = map[string]any{}
+}
+
+type sampleField struct {
+	value    any
+	deadband float64 // for float64 values
+}
+
+func (field sampleField) changed(previous any) bool {
+	if value, ok := field.value.(float64); ok {
+		if previousValue, ok := previous.(float64); ok {
+			difference := math.Abs(value - previousValue)
+			// tolerate float32 rounding, eg 3.3 - 3.295 is slightly below 0.005
+			return difference > 0 && difference >= field.deadband-1e-9
+		}
+	}
+	return !reflect.DeepEqual(field.value, previous)
+}
+
+// fields flattens a sample and its stats. Sections missing from the sample are skipped.
+func (detector *ChangeDetector) fields(data *AllBMSData) map[string]sampleField {
+	deadbands := detector.deadbands
+	fields := map[string]sampleField{}
+	measure := func(path string, value float64, deadband float64) {
+		fields[path] = sampleField{value: value, deadband: deadband}
+	}
+	state := func(path string, value any) {
+		fields[path] = sampleField{value: value}
+	}
+
+	if soc := data.SOC; soc != nil {
+		measure("soc/total_voltage", Widen(soc.TotalVoltage), deadbands.PackVoltage)
+		measure("soc/current", Widen(soc.Current), deadbands.Current)
+		measure("soc/soc_percent", Widen(soc.SOCPercent), deadbands.SOC)
+	}
+	if cellRange := data.CellVoltageRange; cellRange != nil {
+		measure("cell_voltage_range/highest_voltage", Widen(cellRange.HighestVoltage), deadbands.CellVoltage)
+		state("cell_voltage_range/highest_cell", cellRange.HighestCell)
+		measure("cell_voltage_range/lowest_voltage", Widen(cellRange.LowestVoltage), deadbands.CellVoltage)
+		state("cell_voltage_range/lowest_cell", cellRange.LowestCell)
+	}
+	if temperatureRange := data.TemperatureRange; temperatureRange != nil {
+		measure("temperature_range/highest_temperature", Widen(temperatureRange.HighestTemperature), deadbands.Temperature)
+		state("temperature_range/highest_sensor", temperatureRange.HighestSensor)
+		measure("temperature_range/lowest_temperature", Widen(temperatureRange.LowestTemperature), deadbands.Temperature)
+		state("temperature_range/lowest_sensor", temperatureRange.LowestSensor)
+	}
+	if mosfet := data.MosfetStatus; mosfet != nil {
+		state("mosfet_status/mode", mosfet.Mode)
+		state("mosfet_status/charging_mosfet", mosfet.ChargingMosfet)
+		state("mosfet_status/discharging_mosfet", mosfet.DischargingMosfet)
+		measure("mosfet_status/capacity_ah", Widen(mosfet.CapacityAh), deadbands.Capacity)
+	}
+	if status := data.Status; status != nil {
+		state("status/number_of_cells", status.NumberOfCells)
+		state("status/number_of_temperature_sensors", status.NumberOfTemperatureSensors)
+		state("status/is_charger_running", status.IsChargerRunning)
+		state("status/is_load_running", status.IsLoadRunning)
+		state("status/cycle_count", status.CycleCount)
+		for name, active := range status.States {
+			state("status/states/"+name, active)
+		}
+	}
+	for cell, voltage := range data.CellVoltages {
+		measure("cell_voltages/"+strconv.Itoa(cell), voltage, deadbands.CellVoltage)
+	}
+	for sensor, temperature := range data.Temperatures {
+		measure("temperatures/"+strconv.Itoa(sensor), temperature, deadbands.Temperature)
+	}
+	for cell, balancing := range data.BalancingStatus {
+		state("balancing_status/"+strconv.Itoa(cell), balancing)
+	}
+	if data.Errors != nil {
+		state("errors", data.Errors.Strings())
+	}
+
+	stats := data.Stats()
+	measure("stats/average_cell_voltage", stats.AverageCellVoltage, deadbands.CellVoltage)
+	measure("stats/cell_voltage_delta", stats.CellVoltageDelta, deadbands.CellVoltage)
+	measure("stats/average_temperature", stats.AverageTemperature, deadbands.Temperature)
+	measure("stats/power_w", stats.PowerW, deadbands.Power)
+	measure("stats/remaining_energy_wh", stats.RemainingEnergyWh, deadbands.Energy)
+	return fields
+}
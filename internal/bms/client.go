@@ -0,0 +1,136 @@
+package dalybms
+
+import "context"
+
+// BMSClient is the API of a connected BMS: readings, settings and controls. *DalyBMSIstance
+// implements it. Depend on it to swap the real client for a mock or a simulated one, eg in tests.
+// Connection management (Connect*, Disconnect, hooks, observers) is not part of it.
+type BMSClient interface {
+	// Readings
+	GetSOC() (*SOCData, error)
+	GetSOCCtx(ctx context.Context) (*SOCData, error)
+	GetCellVoltageRange() (*CellVoltageRangeData, error)
+	GetCellVoltageRangeCtx(ctx context.Context) (*CellVoltageRangeData, error)
+	GetTemperatureRange() (*TemperatureRangeData, error)
+	GetTemperatureRangeCtx(ctx context.Context) (*TemperatureRangeData, error)
+	GetMosfetStatus() (*MosfetStatusData, error)
+	GetMosfetStatusCtx(ctx context.Context) (*MosfetStatusData, error)
+	GetStatus() (*StatusData, error)
+	GetStatusCtx(ctx context.Context) (*StatusData, error)
+	GetCellVoltages() (map[int]float64, error)
+	GetCellVoltagesCtx(ctx context.Context) (map[int]float64, error)
+	GetCellVoltageSlice() ([]float64, error)
+	GetCellVoltageSliceCtx(ctx context.Context) ([]float64, error)
+	ForEachCellVoltage(fn func(cellIndex int, voltage float64)) error
+	ForEachCellVoltageCtx(ctx context.Context, fn func(cellIndex int, voltage float64)) error
+	GetTemperatures() (map[int]float64, error)
+	GetTemperaturesCtx(ctx context.Context) (map[int]float64, error)
+	GetTemperatureSlice() ([]float64, error)
+	GetTemperatureSliceCtx(ctx context.Context) ([]float64, error)
+	GetMOSTemperature() (float64, error)
+	GetMOSTemperatureCtx(ctx context.Context) (float64, error)
+	GetBalancingStatus() (map[int]bool, error)
+	GetBalancingStatusCtx(ctx context.Context) (map[int]bool, error)
+	GetOpenWireStatus() (*OpenWireStatus, error)
+	GetOpenWireStatusCtx(ctx context.Context) (*OpenWireStatus, error)
+	GetHeaterStatus() (*HeaterStatus, error)
+	GetHeaterStatusCtx(ctx context.Context) (*HeaterStatus, error)
+	GetErrors() (BMSErrors, error)
+	GetErrorsCtx(ctx context.Context) (BMSErrors, error)
+	GetRemainingCapacity() (float64, error)
+	GetRemainingCapacityCtx(ctx context.Context) (float64, error)
+	GetCycleCapacity() (float64, error)
+	GetCycleCapacityCtx(ctx context.Context) (float64, error)
+	GetAllData() (*AllBMSData, error)
+	GetAllDataCtx(ctx context.Context) (*AllBMSData, error)
+	GetAllDataPartial() (*AllBMSData, map[string]error)
+	GetAllDataPartialCtx(ctx context.Context) (*AllBMSData, map[string]error)
+	GetBoardData(board int) (*AllBMSData, error)
+	GetBoardDataCtx(ctx context.Context, board int) (*AllBMSData, error)
+	GetParallelData(boards int) (map[int]*AllBMSData, map[int]error)
+	GetParallelDataCtx(ctx context.Context, boards int) (map[int]*AllBMSData, map[int]error)
+	GetPackSummary() (*PackSummary, error)
+	GetPackSummaryCtx(ctx context.Context) (*PackSummary, error)
+	GetChargeLimits(config ChargeLimitConfig) (*ChargeLimits, error)
+	GetChargeLimitsCtx(ctx context.Context, config ChargeLimitConfig) (*ChargeLimits, error)
+	GetErrorHistory() []ErrorHistoryEntry
+	GetLinkStats() LinkStats
+	CellLabel(cellIndex int) string
+
+	// Device information
+	GetFirmwareVersion() (string, error)
+	GetFirmwareVersionCtx(ctx context.Context) (string, error)
+	GetHardwareVersion() (string, error)
+	GetHardwareVersionCtx(ctx context.Context) (string, error)
+	GetBatteryCode() (string, error)
+	GetBatteryCodeCtx(ctx context.Context) (string, error)
+
+	// Parameters
+	GetRatedParams() (*RatedParams, error)
+	GetRatedParamsCtx(ctx context.Context) (*RatedParams, error)
+	SetRatedCapacity(capacityAh float32) error
+	SetRatedCapacityCtx(ctx context.Context, capacityAh float32) error
+	GetBatteryConfig() (*BatteryConfig, error)
+	GetBatteryConfigCtx(ctx context.Context) (*BatteryConfig, error)
+	SetBatteryConfig(config BatteryConfig) error
+	SetBatteryConfigCtx(ctx context.Context, config BatteryConfig) error
+	SetNumberOfCells(cells int, sensors int) error
+	SetNumberOfCellsCtx(ctx context.Context, cells int, sensors int) error
+	GetVoltageThresholds() (*VoltageThresholds, error)
+	GetVoltageThresholdsCtx(ctx context.Context) (*VoltageThresholds, error)
+	SetVoltageThresholds(thresholds VoltageThresholds) error
+	SetVoltageThresholdsCtx(ctx context.Context, thresholds VoltageThresholds) error
+	GetPackVoltageThresholds() (*PackVoltageThresholds, error)
+	GetPackVoltageThresholdsCtx(ctx context.Context) (*PackVoltageThresholds, error)
+	SetPackVoltageThresholds(thresholds PackVoltageThresholds) error
+	SetPackVoltageThresholdsCtx(ctx context.Context, thresholds PackVoltageThresholds) error
+	GetCurrentThresholds() (*CurrentThresholds, error)
+	GetCurrentThresholdsCtx(ctx context.Context) (*CurrentThresholds, error)
+	SetCurrentThresholds(thresholds CurrentThresholds) error
+	SetCurrentThresholdsCtx(ctx context.Context, thresholds CurrentThresholds) error
+	GetChargeTemperatureThresholds() (*TemperatureThresholds, error)
+	GetChargeTemperatureThresholdsCtx(ctx context.Context) (*TemperatureThresholds, error)
+	SetChargeTemperatureThresholds(thresholds TemperatureThresholds) error
+	SetChargeTemperatureThresholdsCtx(ctx context.Context, thresholds TemperatureThresholds) error
+	GetDischargeTemperatureThresholds() (*TemperatureThresholds, error)
+	GetDischargeTemperatureThresholdsCtx(ctx context.Context) (*TemperatureThresholds, error)
+	SetDischargeTemperatureThresholds(thresholds TemperatureThresholds) error
+	SetDischargeTemperatureThresholdsCtx(ctx context.Context, thresholds TemperatureThresholds) error
+	GetDifferenceThresholds() (*DifferenceThresholds, error)
+	GetDifferenceThresholdsCtx(ctx context.Context) (*DifferenceThresholds, error)
+	SetDifferenceThresholds(thresholds DifferenceThresholds) error
+	SetDifferenceThresholdsCtx(ctx context.Context, thresholds DifferenceThresholds) error
+	GetBalanceSettings() (*BalanceSettings, error)
+	GetBalanceSettingsCtx(ctx context.Context) (*BalanceSettings, error)
+	SetBalanceSettings(settings BalanceSettings) error
+	SetBalanceSettingsCtx(ctx context.Context, settings BalanceSettings) error
+
+	// Controls
+	SetPassword(password string)
+	Authenticate() error
+	AuthenticateCtx(ctx context.Context) error
+	EnableChargeMosfet(isOn bool) error
+	EnableChargeMosfetCtx(ctx context.Context, isOn bool) error
+	EnableDischargeMosfet(isOn bool) error
+	EnableDischargeMosfetCtx(ctx context.Context, isOn bool) error
+	SetMosfetState(charge bool, discharge bool) (*MosfetStatusData, error)
+	SetMosfetStateCtx(ctx context.Context, charge bool, discharge bool) (*MosfetStatusData, error)
+	EnableHeater(isOn bool) error
+	EnableHeaterCtx(ctx context.Context, isOn bool) error
+	SetSOC(socPercent float64) error
+	SetSOCCtx(ctx context.Context, socPercent float64) error
+	Restart() error
+	RestartCtx(ctx context.Context) error
+	Sleep() error
+	SleepCtx(ctx context.Context) error
+	Wake() error
+	WakeCtx(ctx context.Context) error
+	FactoryReset() error
+	FactoryResetCtx(ctx context.Context) error
+	SaveParameters() error
+	SaveParametersCtx(ctx context.Context) error
+	SendRaw(command byte, payload []byte) ([]Frame, error)
+	SendRawCtx(ctx context.Context, command byte, payload []byte) ([]Frame, error)
+}
+
+var _ BMSClient = (*DalyBMSIstance)(nil)
@@ -1,56 +1,142 @@
 package dalybms
 
 import (
-	"fmt"
+	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/tarm/serial"
+	"github.com/jonamat/go-daly-bms/protocol"
 )
 
-// BMS serial connection
+// BMS connection. Safe for concurrent use: each request/response exchange holds the
+// bus for its whole duration, so calls from several goroutines never interleave frames.
 type DalyBMSIstance struct {
-	serialPort     *serial.Port
-	requestRetries int
-	latestStatus   *StatusData // cached from GetStatus()
-	address        int
+	busMutex        sync.Mutex // serializes transactions on the transport
+	transport       Transport  // serial port by default, see ConnectTransport()
+	retryPolicy     RetryPolicy
+	address         int
+	responseAddress byte                // expected source address of responses, 0 = any
+	foreignFrames   ForeignFrameHandler // receives responses from other addresses, may be nil
+	strictFrames    bool                // CRC and header mismatches fail the request, see WithStrictFrames()
+	keepEcho        bool                // frames identical to the request are responses, see WithKeepEcho()
+	commandDelay    time.Duration       // minimum gap between writes, see WithInterCommandDelay()
+	lastWrite       time.Time           // guarded by busMutex
+	temperatureUnit TemperatureUnit     // see WithTemperatureUnit()
+	logger          Logger
+	responseTimeout time.Duration // overall deadline per command, 0 = none
+	linkLatency     time.Duration // max silence tolerated while waiting for a response, see WithLatency()
+	rounding        bool          // round readings to the protocol resolution, see WithRounding()
+	currentSign     CurrentSign   // see WithCurrentSignConvention()
+	frameObserver   FrameObserver // guarded by busMutex and stateMutex
+	protocol        Protocol      // guarded by stateMutex, see WithProtocol()
+
+	stateMutex   sync.Mutex  // guards the cached state below
+	latestStatus *StatusData // cached from GetStatus()
+
+	actionHooks        []ActionHook
+	latestMosfetStatus *MosfetStatusData // cached from GetMosfetStatus(), used to detect changes
+	alarmActive        bool
+	errorHistory       errorHistory // see GetErrorHistory()
+	linkStats          LinkStats    // see GetLinkStats()
+	created            time.Time
+
+	cellMap CellMap // physical cell labels, see SetCellMap()
+
+	errorMessages  map[int][]string // errorLanguage with errorOverrides, merged by NewClient()
+	errorLanguage  map[int][]string // translated error messages, see WithLanguage()
+	errorOverrides map[int][]string // see WithErrorMessages()
+	languageError  error            // unknown language of WithLanguage(), logged by NewClient()
+
+	unsolicitedFrames chan<- protocol.Frame // receives frames answering no request, see WithUnsolicitedFrames()
+
+	backgroundReads  bool              // read frames in a goroutine, see WithBackgroundReader()
+	background       *backgroundReader // running reader, guarded by busMutex
+	awaitingResponse atomic.Bool       // a request is collecting frames from the background reader
+
+	targetStatus map[requestTarget]*StatusData // cached from GetStatus() with AtAddress(), guarded by stateMutex
+
+	heaterCommands *HeaterCommands // see WithHeaterCommands(), nil without heater
+	mosSensor      int             // temperature sensor of the MOSFETs, see WithMOSTemperatureSensor()
+
+	dangerousCommands bool              // see WithDangerousCommands()
+	saveCommand       *protocol.Command // see WithSaveCommand(), nil without one
+
+	loginCommand  *protocol.Command // see WithPassword(), nil without password
+	password      string            // guarded by stateMutex
+	authenticated map[byte]bool     // request addresses logged in to, guarded by stateMutex
+
+	verifyTries int           // reads of a written value, 0 = no verification, see WithWriteVerification()
+	verifyDelay time.Duration // between the reads
+
+	controlInterval time.Duration            // see WithControlRateLimit(), 0 = no limit
+	lastControl     map[controlKey]time.Time // guarded by stateMutex
+
+	serialOpener SerialOpener // see WithSerialOpener(), nil = OpenSerialPort()
+
+	sleepCommand *protocol.Command // see WithSleepCommand(), nil without one
+	wakeIdle     time.Duration     // see WithWakeOnIdle(), 0 = never wake
+	wakeDelay    time.Duration     // between the wake frame and the request
+	asleep       bool              // wake before the next request, guarded by busMutex
 }
 
+// Deprecated: use NewClient, which accepts options.
 func DalyBMS() *DalyBMSIstance {
-	return &DalyBMSIstance{
-		requestRetries: 3, // default
-		address:        4, // default for RS485
-	}
+	return NewClient()
 }
 
-// Connect opens the serial port. Eg "/dev/ttyUSB0"
+// Connect opens the serial port. Eg "/dev/ttyUSB0", or "tcp://192.168.1.50:2000" for a ser2net bridge
 func (bms *DalyBMSIstance) Connect(serialDevicePath string) error {
-	portConfig := &serial.Config{
-		Name:        serialDevicePath,
-		Baud:        9600,
-		ReadTimeout: 100 * time.Millisecond, // e.g. 100ms
-		Size:        8,
-		Parity:      serial.ParityNone,
-		StopBits:    serial.Stop1,
-	}
-
-	openedPort, err := serial.OpenPort(portConfig)
-	if err != nil {
-		return fmt.Errorf("failed to open serial port: %w", err)
-	}
-
-	bms.serialPort = openedPort
+	return bms.ConnectCtx(context.Background(), serialDevicePath)
+}
 
-	// Optionally fetch initial status once connected
-	_, _ = bms.GetStatus()
-	return nil
+// Connect with cancellation support. ctx also bounds the initial status fetch.
+func (bms *DalyBMSIstance) ConnectCtx(ctx context.Context, serialDevicePath string) error {
+	return bms.ConnectWithConfigCtx(ctx, serialDevicePath, DefaultSerialConfig())
 }
 
-// Close serial port
+// Close the transport
 func (bms *DalyBMSIstance) Disconnect() error {
-	if bms.serialPort != nil {
-		err := bms.serialPort.Close()
-		bms.serialPort = nil
+	bms.busMutex.Lock()
+	defer bms.busMutex.Unlock()
+
+	bms.stopBackgroundReader()
+	bms.forgetAuthentication()
+	if bms.transport != nil {
+		err := bms.transport.Close()
+		bms.transport = nil
 		return err
 	}
 	return nil
 }
+
+// isConnected reports whether a transport is in use
+func (bms *DalyBMSIstance) isConnected() bool {
+	bms.busMutex.Lock()
+	defer bms.busMutex.Unlock()
+	return bms.transport != nil
+}
+
+// cachedStatus returns the status cached by the latest GetStatus() of the BMS ctx targets, nil if none
+func (bms *DalyBMSIstance) cachedStatus(ctx context.Context) *StatusData {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	if target, ok := targetFrom(ctx); ok {
+		return bms.targetStatus[target]
+	}
+	return bms.latestStatus
+}
+
+// cacheStatus caches the status of the BMS ctx targets, nil forgets it
+func (bms *DalyBMSIstance) cacheStatus(ctx context.Context, status *StatusData) {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	if target, ok := targetFrom(ctx); ok {
+		if bms.targetStatus == nil {
+			bms.targetStatus = map[requestTarget]*StatusData{}
+		}
+		bms.targetStatus[target] = status
+		return
+	}
+	bms.latestStatus = status
+}
@@ -0,0 +1,64 @@
+package dalybms
+
+// Pure decoders of multi-frame and bit field responses. They get the data sections of the
+// frames as received, so they must cope with any length and content.
+
+// decodeFrameValues unpacks multi-frame responses (cell voltages, temperatures): each frame
+// holds its number then itemsPerFrame signed big endian values of itemSize bytes. Values are
+// keyed by position, frame N holding values (N-1)*itemsPerFrame+1 onwards, up to needed, so a
+// lost or reordered frame doesn't shift the others. The numbers of the frames needed but not
+// received are returned in ascending order. Repeated frames and values cut by a short frame
+// are skipped.
+func decodeFrameValues(frames [][]byte, needed int, itemsPerFrame int, itemSize int) (map[int]float64, []int) {
+	values := make(map[int]float64)
+	if (itemSize != 1 && itemSize != 2) || itemsPerFrame < 1 {
+		return values, nil
+	}
+
+	seenFrames := make(map[int]bool)
+	for _, frame := range frames {
+		if len(frame) == 0 || frame[0] == 0 || seenFrames[int(frame[0])] {
+			continue
+		}
+		frameNumber := int(frame[0])
+		seenFrames[frameNumber] = true
+
+		for itemIndex := 0; itemIndex < itemsPerFrame; itemIndex++ {
+			index := (frameNumber-1)*itemsPerFrame + itemIndex + 1
+			offset := 1 + itemIndex*itemSize
+			if index > needed || offset+itemSize > len(frame) {
+				break
+			}
+			if itemSize == 2 {
+				values[index] = float64(int16(uint16(frame[offset])<<8 | uint16(frame[offset+1])))
+			} else {
+				values[index] = float64(int8(frame[offset]))
+			}
+		}
+	}
+
+	var missingFrames []int
+	for frameNumber := 1; (frameNumber-1)*itemsPerFrame < needed; frameNumber++ {
+		if !seenFrames[frameNumber] {
+			missingFrames = append(missingFrames, frameNumber)
+		}
+	}
+	return values, missingFrames
+}
+
+// Highest cell count the balancing frame can report, 6 bytes of flags
+const maxBalancingCells = 48
+
+// decodeBalancing reads one bit per cell, cell 1 in the least significant bit of the last byte,
+// cell 9 in the least significant bit of the one before and so on, up to maxBalancingCells
+func decodeBalancing(data []byte, numberOfCells int) map[int]bool {
+	balancingMap := make(map[int]bool)
+	for cellIndex := 1; cellIndex <= numberOfCells && cellIndex <= maxBalancingCells; cellIndex++ {
+		byteIndex := len(data) - 1 - (cellIndex-1)/8
+		if byteIndex < 0 {
+			break
+		}
+		balancingMap[cellIndex] = data[byteIndex]&(1<<((cellIndex-1)%8)) != 0
+	}
+	return balancingMap
+}
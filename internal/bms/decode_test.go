@@ -0,0 +1,103 @@
+package dalybms
+
+import (
+	"slices"
+	"testing"
+)
+
+// Data sections of responses recorded against the simulator
+var (
+	seedCellVoltageFrames = [][]byte{
+		{0x01, 0x0c, 0xe7, 0x0c, 0xe6, 0x0c, 0xe1, 0x00},
+		{0x02, 0x0c, 0xe8, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}
+	seedTemperatureFrame = []byte{0x01, 0x3c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	seedBalancingData    = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+)
+
+func FuzzDecodeFrameValues(f *testing.F) {
+	f.Add(slices.Concat(seedCellVoltageFrames...), 4, 3, 2)
+	f.Add(seedTemperatureFrame, 1, 7, 1)
+	f.Add(seedCellVoltageFrames[1], 48, 3, 2)
+	f.Add([]byte{}, 0, 0, 0)
+
+	f.Fuzz(func(t *testing.T, data []byte, needed int, itemsPerFrame int, itemSize int) {
+		// the getters ask for at most a few hundred values
+		if needed < -1 || needed > 512 || itemsPerFrame < -1 || itemsPerFrame > 16 {
+			t.Skip()
+		}
+		// split like the frames of a response, the last one may be short
+		var frames [][]byte
+		for len(data) > 0 {
+			frameLength := min(len(data), 8)
+			frames = append(frames, data[:frameLength])
+			data = data[frameLength:]
+		}
+
+		values, missingFrames := decodeFrameValues(frames, needed, itemsPerFrame, itemSize)
+		for index := range values {
+			if index < 1 || index > needed {
+				t.Errorf("value %d outside 1..%d", index, needed)
+			}
+		}
+		if !slices.IsSorted(missingFrames) {
+			t.Errorf("missing frames %v not in ascending order", missingFrames)
+		}
+	})
+}
+
+func FuzzDecodeBalancing(f *testing.F) {
+	f.Add(seedBalancingData, 4)
+	f.Add([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0x05}, 16)
+	f.Add([]byte{0xff}, 48)
+	f.Add([]byte{}, 1)
+
+	f.Fuzz(func(t *testing.T, data []byte, numberOfCells int) {
+		balancing := decodeBalancing(data, numberOfCells)
+		for cellIndex := range balancing {
+			if cellIndex < 1 || cellIndex > numberOfCells || cellIndex > maxBalancingCells {
+				t.Errorf("cell %d outside 1..min(%d, %d)", cellIndex, numberOfCells, maxBalancingCells)
+			}
+		}
+		if len(balancing) > len(data)*8 {
+			t.Errorf("%d cells decoded from %d bytes", len(balancing), len(data))
+		}
+	})
+}
+
+func TestDecodeBalancing(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		numberOfCells int
+		balancing     []int // cells expected to balance, the others must be false
+	}{
+		{"none", []byte{0, 0, 0, 0, 0, 0, 0, 0}, 16, nil},
+		{"cell 1 is the low bit of the last byte", []byte{0, 0, 0, 0, 0, 0, 0, 0x01}, 16, []int{1}},
+		{"cell 8 is the high bit of the last byte", []byte{0, 0, 0, 0, 0, 0, 0, 0x80}, 16, []int{8}},
+		{"cell 9 is the low bit of the byte before", []byte{0, 0, 0, 0, 0, 0, 0x01, 0}, 16, []int{9}},
+		{"bits in both bytes", []byte{0, 0, 0, 0, 0, 0, 0x80, 0x05}, 16, []int{1, 3, 16}},
+		{"partial last byte", []byte{0, 0, 0, 0, 0, 0, 0xff, 0xff}, 13, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}},
+		{"partial byte ignores higher bits", []byte{0, 0, 0, 0, 0, 0, 0xe0, 0x00}, 13, nil},
+		{"48 cells", []byte{0xff, 0xff, 0x80, 0, 0, 0, 0, 0x01}, 48, []int{1, 48}},
+		{"cells above 48 are not decoded", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 64,
+			[]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24,
+				25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48}},
+		{"short data", []byte{0x01}, 16, []int{1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			balancing := decodeBalancing(test.data, test.numberOfCells)
+
+			wantCells := min(test.numberOfCells, maxBalancingCells, len(test.data)*8)
+			if len(balancing) != wantCells {
+				t.Errorf("decoded %d cells, want %d", len(balancing), wantCells)
+			}
+			for cellIndex := 1; cellIndex <= wantCells; cellIndex++ {
+				if want := slices.Contains(test.balancing, cellIndex); balancing[cellIndex] != want {
+					t.Errorf("cell %d balancing = %v, want %v", cellIndex, balancing[cellIndex], want)
+				}
+			}
+		})
+	}
+}
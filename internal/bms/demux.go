@@ -0,0 +1,51 @@
+package dalybms
+
+import (
+	"context"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Deliver frames that answer no pending request to frames instead of dropping them, eg alarm
+// broadcasts pushed by some firmwares or late answers to an earlier command. Frames found in the
+// receive buffer before a request are delivered too. Sends never block: while the channel is
+// full frames are logged and dropped.
+func WithUnsolicitedFrames(frames chan<- protocol.Frame) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.unsolicitedFrames = frames
+	}
+}
+
+// routeUnsolicited delivers a frame that answers no pending request, false when nobody listens
+func (bms *DalyBMSIstance) routeUnsolicited(frame []byte) bool {
+	if bms.unsolicitedFrames == nil {
+		return false
+	}
+	decoded, err := protocol.Decode(frame)
+	if err != nil {
+		return false
+	}
+	select {
+	case bms.unsolicitedFrames <- decoded:
+	default:
+		bms.logf("Unsolicited frames channel full, dropping %x", frame)
+	}
+	return true
+}
+
+// drainUnsolicited reads the frames left in the receive buffer and delivers them, see
+// WithUnsolicitedFrames(). Must be called with busMutex held.
+func (bms *DalyBMSIstance) drainUnsolicited() {
+	reader := &frameReader{bms: bms, transport: bms.transport}
+	for {
+		frame, err := reader.next(context.Background())
+		if err != nil {
+			// strict mode, the reader already skipped the bad frame
+			continue
+		}
+		if frame == nil {
+			return
+		}
+		bms.routeUnsolicited(frame)
+	}
+}
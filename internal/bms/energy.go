@@ -0,0 +1,254 @@
+package dalybms
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Energy moved in and out of the pack
+type EnergyCounters struct {
+	ChargedAh    float64 `json:"charged_ah"`
+	DischargedAh float64 `json:"discharged_ah"`
+	ChargedWh    float64 `json:"charged_wh"`
+	DischargedWh float64 `json:"discharged_wh"`
+}
+
+func (counters *EnergyCounters) add(other EnergyCounters) {
+	counters.ChargedAh += other.ChargedAh
+	counters.DischargedAh += other.DischargedAh
+	counters.ChargedWh += other.ChargedWh
+	counters.DischargedWh += other.DischargedWh
+}
+
+// Persistent state of an EnergyMeter. Days are keyed by local date, eg "2024-05-31".
+type EnergySnapshot struct {
+	Total EnergyCounters            `json:"total"`
+	Days  map[string]EnergyCounters `json:"days"`
+}
+
+// EnergyStore persists the counters across restarts. Load returns nil, nil when nothing was saved yet.
+type EnergyStore interface {
+	Load() (*EnergySnapshot, error)
+	Save(snapshot *EnergySnapshot) error
+}
+
+// JSON file store
+type FileEnergyStore struct {
+	Path string
+}
+
+func (store *FileEnergyStore) Load() (*EnergySnapshot, error) {
+	content, err := os.ReadFile(store.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &EnergySnapshot{}
+	if err := json.Unmarshal(content, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Save writes to a temporary file first, so a crash never leaves a truncated file
+func (store *FileEnergyStore) Save(snapshot *EnergySnapshot) error {
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	temporaryPath := store.Path + ".tmp"
+	if err := os.WriteFile(temporaryPath, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporaryPath, store.Path)
+}
+
+// EnergyMeter integrates current and voltage readings (coulomb counting) into
+// charged/discharged Ah and Wh counters. Feed it with Poller.OnResult(meter.Update).
+type EnergyMeter struct {
+	MaxGap       time.Duration // samples further apart are not integrated, eg across reconnects
+	SaveInterval time.Duration // how often the store is written, 0 = on every sample
+	HistoryDays  int           // daily counters kept, 0 = all
+
+	mutex      sync.Mutex
+	store      EnergyStore
+	snapshot   EnergySnapshot
+	lastSample *energySample
+	lastSave   time.Time
+}
+
+type energySample struct {
+	time    time.Time
+	voltage float64
+	current float64
+}
+
+// NewEnergyMeter creates a meter resuming from the store, which may be nil
+func NewEnergyMeter(store EnergyStore) (*EnergyMeter, error) {
+	meter := &EnergyMeter{
+		MaxGap:       1 * time.Minute, // default
+		SaveInterval: 5 * time.Minute, // default
+		HistoryDays:  31,              // default
+		store:        store,
+		snapshot:     EnergySnapshot{Days: make(map[string]EnergyCounters)},
+	}
+
+	if store != nil {
+		saved, err := store.Load()
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil {
+			meter.snapshot.Total = saved.Total
+			for day, counters := range saved.Days {
+				meter.snapshot.Days[day] = counters
+			}
+		}
+	}
+	return meter, nil
+}
+
+// Update integrates a poll result. Failed polls break the integration until the next good sample.
+func (meter *EnergyMeter) Update(result PollResult) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+
+	if result.Err != nil || result.Data == nil || result.Data.SOC == nil {
+		meter.lastSample = nil
+		return
+	}
+
+	sampleTime := result.Time
+	if sampleTime.IsZero() {
+		sampleTime = time.Now()
+	}
+	sample := &energySample{
+		time:    sampleTime,
+		voltage: Widen(result.Data.SOC.TotalVoltage),
+		current: result.Data.SOC.ChargeCurrent(),
+	}
+
+	previous := meter.lastSample
+	meter.lastSample = sample
+	if previous == nil {
+		return
+	}
+	elapsed := sample.time.Sub(previous.time)
+	if elapsed <= 0 || (meter.MaxGap > 0 && elapsed > meter.MaxGap) {
+		return
+	}
+
+	meter.integrate(previous, sample, elapsed)
+	meter.saveIfDue(sample.time)
+}
+
+// integrate adds the energy moved between two samples (trapezoidal rule)
+func (meter *EnergyMeter) integrate(previous, sample *energySample, elapsed time.Duration) {
+	hours := elapsed.Hours()
+	ampereHours := (previous.current + sample.current) / 2 * hours
+	wattHours := (previous.current*previous.voltage + sample.current*sample.voltage) / 2 * hours
+
+	var delta EnergyCounters
+	if ampereHours >= 0 {
+		delta.ChargedAh = ampereHours
+	} else {
+		delta.DischargedAh = -ampereHours
+	}
+	if wattHours >= 0 {
+		delta.ChargedWh = wattHours
+	} else {
+		delta.DischargedWh = -wattHours
+	}
+
+	meter.snapshot.Total.add(delta)
+	day := dayKey(sample.time)
+	dayCounters := meter.snapshot.Days[day]
+	dayCounters.add(delta)
+	meter.snapshot.Days[day] = dayCounters
+	meter.pruneHistory()
+}
+
+// pruneHistory drops the oldest daily counters beyond HistoryDays
+func (meter *EnergyMeter) pruneHistory() {
+	if meter.HistoryDays <= 0 {
+		return
+	}
+	for len(meter.snapshot.Days) > meter.HistoryDays {
+		oldest := ""
+		for day := range meter.snapshot.Days {
+			if oldest == "" || day < oldest {
+				oldest = day
+			}
+		}
+		delete(meter.snapshot.Days, oldest)
+	}
+}
+
+// saveIfDue writes the snapshot when SaveInterval elapsed. Must be called with mutex held.
+func (meter *EnergyMeter) saveIfDue(now time.Time) {
+	if meter.store == nil || now.Sub(meter.lastSave) < meter.SaveInterval {
+		return
+	}
+	if err := meter.store.Save(meter.copySnapshot()); err != nil {
+		return // retried on the next sample, Save() reports errors
+	}
+	meter.lastSave = now
+}
+
+// Save writes the counters to the store now, eg before shutting down
+func (meter *EnergyMeter) Save() error {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+
+	if meter.store == nil {
+		return nil
+	}
+	if err := meter.store.Save(meter.copySnapshot()); err != nil {
+		return err
+	}
+	meter.lastSave = time.Now()
+	return nil
+}
+
+// Counters since the meter was first started
+func (meter *EnergyMeter) Total() EnergyCounters {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.snapshot.Total
+}
+
+// Counters of the local day containing the given time, eg time.Now()
+func (meter *EnergyMeter) Day(day time.Time) EnergyCounters {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.snapshot.Days[dayKey(day)]
+}
+
+// Copy of all counters
+func (meter *EnergyMeter) Snapshot() *EnergySnapshot {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.copySnapshot()
+}
+
+func (meter *EnergyMeter) copySnapshot() *EnergySnapshot {
+	snapshot := &EnergySnapshot{
+		Total: meter.snapshot.Total,
+		Days:  make(map[string]EnergyCounters, len(meter.snapshot.Days)),
+	}
+	for day, counters := range meter.snapshot.Days {
+		snapshot.Days[day] = counters
+	}
+	return snapshot
+}
+
+func dayKey(moment time.Time) string {
+	return moment.Local().Format("2006-01-02")
+}
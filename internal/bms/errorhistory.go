@@ -0,0 +1,132 @@
+package dalybms
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+)
+
+// Change of an error flag seen by GetErrors(), see GetErrorHistory()
+type ErrorHistoryEntry struct {
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error"`
+	Raised bool      `json:"raised"` // false when the error cleared
+}
+
+// errorHistory latches error flag changes, which are often too short to be seen between polls
+type errorHistory struct {
+	size    int    // entries kept, 0 disables the history
+	path    string // JSON file the entries are saved to, "" = memory only
+	loaded  bool
+	entries []ErrorHistoryEntry
+	active  []string // errors seen in the latest read
+}
+
+// Keep the last size error flag changes, 0 disables the history. Default 100.
+func WithErrorHistory(size int) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.errorHistory.size = max(size, 0)
+	}
+}
+
+// Persist the error history to a JSON file, loaded on first use
+func WithErrorHistoryFile(path string) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.errorHistory.path = path
+	}
+}
+
+// Get the latest error flag changes, oldest first
+func (bms *DalyBMSIstance) GetErrorHistory() []ErrorHistoryEntry {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+
+	bms.loadErrorHistory()
+	return slices.Clone(bms.errorHistory.entries)
+}
+
+// recordErrors adds the differences between the previous and current errors to the history
+func (bms *DalyBMSIstance) recordErrors(current []string) {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+
+	history := &bms.errorHistory
+	if history.size == 0 {
+		return
+	}
+	bms.loadErrorHistory()
+
+	now := time.Now()
+	changed := false
+	for _, errorText := range current {
+		if !slices.Contains(history.active, errorText) {
+			history.entries = append(history.entries, ErrorHistoryEntry{Time: now, Error: errorText, Raised: true})
+			changed = true
+		}
+	}
+	for _, errorText := range history.active {
+		if !slices.Contains(current, errorText) {
+			history.entries = append(history.entries, ErrorHistoryEntry{Time: now, Error: errorText, Raised: false})
+			changed = true
+		}
+	}
+	history.active = slices.Clone(current)
+	if !changed {
+		return
+	}
+
+	if overflow := len(history.entries) - history.size; overflow > 0 {
+		history.entries = slices.Delete(history.entries, 0, overflow)
+	}
+	if history.path != "" {
+		if err := saveErrorHistory(history.path, history.entries); err != nil {
+			bms.logf("Failed to save error history: %v", err)
+		}
+	}
+}
+
+// loadErrorHistory reads the history file once. Must be called with stateMutex held.
+func (bms *DalyBMSIstance) loadErrorHistory() {
+	history := &bms.errorHistory
+	if history.loaded || history.path == "" {
+		return
+	}
+	history.loaded = true
+
+	content, err := os.ReadFile(history.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &history.entries)
+	}
+	if err != nil {
+		bms.logf("Failed to load error history: %v", err)
+		return
+	}
+
+	// errors raised and not cleared before the restart are still active
+	for _, entry := range history.entries {
+		if entry.Raised && !slices.Contains(history.active, entry.Error) {
+			history.active = append(history.active, entry.Error)
+		} else if !entry.Raised {
+			history.active = slices.DeleteFunc(history.active, func(errorText string) bool { return errorText == entry.Error })
+		}
+	}
+}
+
+// saveErrorHistory writes to a temporary file first, so a crash never leaves a truncated file
+func saveErrorHistory(path string, entries []ErrorHistoryEntry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	temporaryPath := path + ".tmp"
+	if err := os.WriteFile(temporaryPath, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporaryPath, path)
+}
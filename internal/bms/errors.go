@@ -1,70 +1,161 @@
 package dalybms
 
-var DalyErrorCodes = map[int][]string{
-	0: {
-		"one stage warning of unit over voltage",
-		"one stage warning of unit over voltage",
-		"one stage warning of unit over voltage",
-		"two stage warning of unit over voltage",
-		"Total voltage is too high One alarm",
-		"Total voltage is too high Level two alarm",
-		"Total voltage is too low One alarm",
-		"Total voltage is too low Level two alarm",
-	},
-	1: {
-		"Charging temperature too high. One alarm",
-		"Charging temperature too high. Level two alarm",
-		"Charging temperature too low. One alarm",
-		"Charging temperature too low. Level two alarm",
-		"Discharge temperature is too high. One alarm",
-		"Discharge temperature is too high. Level two alarm",
-		"Discharge temperature is too low. One alarm",
-		"Discharge temperature is too low. Level two alarm",
-	},
-	2: {
-		"Charge over current. Level one alarm",
-		"Charge over current, level two alarm",
-		"Discharge over current. Level one alarm",
-		"Discharge overcurrent, level two alarm",
-		"SOC is too high an alarm",
-		"SOC is too high. Alarm Two",
-		"SOC is too low. level one alarm",
-		"SOC is too low. level two alarm",
-	},
-	3: {
-		"Excessive differential pressure level one alarm",
-		"Excessive differential pressure level two alarm",
-		"Excessive temperature difference level one alarm",
-		"Excessive temperature difference level two alarm",
-	},
-	4: {
-		"charging  MOS overtemperature warning",
-		"discharge MOS overtemperature warning",
-		"charging MOS temperature detection sensor failure",
-		"discharge MOS temperature detection sensor failure",
-		"charging MOS adhesion failure",
-		"discharge MOS adhesion failure",
-		"charging MOS breaker failure",
-		"discharge MOS breaker failure",
-	},
-	5: {
-		"AFE acquisition chip malfunction",
-		"monomer collect drop off",
-		"Single Temperature Sensor Fault",
-		"EEPROM storage failures",
-		"RTC clock malfunction",
-		"Precharge Failure",
-		"vehicle communications malfunction",
-		"intranet communication module malfunction",
-	},
-	6: {
-		"Current Module Failure",
-		"main pressure detection module",
-		"Short circuit protection failure",
-		"Low Voltage No Charging",
-		"RESERVED",
-		"RESERVED",
-		"RESERVED",
-		"RESERVED",
-	},
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// ErrTimeout is returned (wrapped) when the BMS does not answer in time
+var ErrTimeout = errors.New("response timeout")
+
+// Frame errors reported in strict mode, see WithStrictFrames
+var (
+	ErrCRCMismatch    = errors.New("CRC mismatch")
+	ErrHeaderMismatch = errors.New("header mismatch")
+)
+
+// FrameError carries the frame that failed validation in strict mode. Err is
+// ErrCRCMismatch or ErrHeaderMismatch.
+type FrameError struct {
+	Err    error
+	Frame  []byte
+	Reason string
+}
+
+func (frameError *FrameError) Error() string {
+	return fmt.Sprintf("%v: %s, frame %x", frameError.Err, frameError.Reason, frameError.Frame)
+}
+
+func (frameError *FrameError) Unwrap() error {
+	return frameError.Err
+}
+
+// ErrMissingFrames is returned (wrapped in a *MissingFramesError) when frames of a multi-frame
+// response never arrived
+var ErrMissingFrames = errors.New("missing frames")
+
+// MissingFramesError lists the frames a multi-frame response lacked, numbered from 1. The
+// values of the frames received are returned along with it, keyed by their own index.
+type MissingFramesError struct {
+	Command protocol.Command
+	Frames  []int
+}
+
+func (missingError *MissingFramesError) Error() string {
+	return fmt.Sprintf("command %s: %v %v", missingError.Command.Hex(), ErrMissingFrames, missingError.Frames)
+}
+
+func (missingError *MissingFramesError) Unwrap() error {
+	return ErrMissingFrames
+}
+
+// Messages of the error flags, indexed by byte and bit of the 0x98 response
+var DalyErrorCodes = protocol.ErrorCodes
+
+// Severity of an error flag
+type ErrorSeverity string
+
+const (
+	SeverityWarning ErrorSeverity = "warning" // level one, reserved or unknown, the BMS keeps the pack running
+	SeverityAlarm   ErrorSeverity = "alarm"   // level two or a hardware failure, see protocol.ErrorFlags
+)
+
+// Quantity or component an error flag is about
+type ErrorCategory string
+
+const (
+	CategoryVoltage     ErrorCategory = "voltage"
+	CategoryTemperature ErrorCategory = "temperature"
+	CategoryCurrent     ErrorCategory = "current"
+	CategorySOC         ErrorCategory = "soc"
+	CategoryMOS         ErrorCategory = "mos"
+	CategoryHardware    ErrorCategory = "hardware"
+)
+
+// Active error flag reported by GetErrors()
+type BMSError struct {
+	Code     int           `json:"code"` // Byte*8 + Bit
+	Byte     int           `json:"byte"`
+	Bit      int           `json:"bit"`
+	Severity ErrorSeverity `json:"severity"`
+	Category ErrorCategory `json:"category"`
+	Message  string        `json:"message"` // text of DalyErrorCodes, or its translation, see WithLanguage()
+}
+
+func (bmsError BMSError) String() string {
+	return bmsError.Message
+}
+
+// Error flags returned by GetErrors()
+type BMSErrors []BMSError
+
+// Strings returns the messages, the value GetErrors() returned before errors were structured
+func (bmsErrors BMSErrors) Strings() []string {
+	if bmsErrors == nil {
+		return nil
+	}
+	messages := make([]string, 0, len(bmsErrors))
+	for _, bmsError := range bmsErrors {
+		messages = append(messages, bmsError.Message)
+	}
+	return messages
+}
+
+// Has reports whether the flag at bit of byteIndex in the 0x98 response is active
+func (bmsErrors BMSErrors) Has(byteIndex int, bit int) bool {
+	for _, bmsError := range bmsErrors {
+		if bmsError.Byte == byteIndex && bmsError.Bit == bit {
+			return true
+		}
+	}
+	return false
+}
+
+// newBMSError describes the flag at bit of byteIndex in the 0x98 response
+func newBMSError(byteIndex int, bit int) BMSError {
+	message := fmt.Sprintf("Unknown error code at byte=%d bit=%d", byteIndex, bit)
+	if messages, ok := DalyErrorCodes[byteIndex]; ok && bit < len(messages) {
+		message = messages[bit]
+	}
+
+	bmsError := BMSError{
+		Code:     byteIndex*8 + bit,
+		Byte:     byteIndex,
+		Bit:      bit,
+		Severity: SeverityWarning,
+		Category: errorCategory(byteIndex, bit),
+		Message:  message,
+	}
+	// by position, Message may be translated or overridden
+	if protocol.IsAlarm(byteIndex, bit) {
+		bmsError.Severity = SeverityAlarm
+	}
+	return bmsError
+}
+
+// errorCategory follows the layout of DalyErrorCodes
+func errorCategory(byteIndex int, bit int) ErrorCategory {
+	switch {
+	case byteIndex == 0:
+		return CategoryVoltage
+	case byteIndex == 1:
+		return CategoryTemperature
+	case byteIndex == 2 && bit < 4:
+		return CategoryCurrent
+	case byteIndex == 2:
+		return CategorySOC
+	case byteIndex == 3 && bit < 2:
+		// cell voltage difference
+		return CategoryVoltage
+	case byteIndex == 3:
+		return CategoryTemperature
+	case byteIndex == 4:
+		return CategoryMOS
+	case byteIndex == 6 && bit == 3:
+		// low voltage, no charging
+		return CategoryVoltage
+	}
+	return CategoryHardware
 }
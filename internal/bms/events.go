@@ -0,0 +1,239 @@
+package dalybms
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Kind of event published by an EventBus
+type EventKind int
+
+const (
+	ChargeStarted EventKind = iota
+	ChargeStopped
+	DischargeStarted
+	DischargeStopped
+	MosfetEnabled
+	MosfetDisabled
+	ErrorRaised
+	ErrorCleared
+	CellOvervoltage
+	CellUndervoltage
+)
+
+var eventKindNames = []string{
+	"charge_started",
+	"charge_stopped",
+	"discharge_started",
+	"discharge_stopped",
+	"mosfet_enabled",
+	"mosfet_disabled",
+	"error_raised",
+	"error_cleared",
+	"cell_overvoltage",
+	"cell_undervoltage",
+}
+
+func (kind EventKind) String() string {
+	if kind < 0 || int(kind) >= len(eventKindNames) {
+		return "unknown"
+	}
+	return eventKindNames[kind]
+}
+
+// Kinds are serialized by name, eg "charge_started"
+func (kind EventKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+func (kind *EventKind) UnmarshalText(text []byte) error {
+	for index, name := range eventKindNames {
+		if name == string(text) {
+			*kind = EventKind(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event kind: %s", text)
+}
+
+// State transition between two samples. Only the fields relevant to the kind are set.
+type Event struct {
+	Kind    EventKind `json:"kind"`
+	Time    time.Time `json:"time"`
+	Current float64   `json:"current,omitempty"` // A, positive when charging, charge/discharge events
+	Mosfet  string    `json:"mosfet,omitempty"`  // "charge" or "discharge", mosfet events
+	Error   string    `json:"error,omitempty"`   // error events
+	Cell    int       `json:"cell,omitempty"`    // cell events
+	Label   string    `json:"label,omitempty"`   // physical label of the cell, cell events with SetCellMap()
+	Voltage float64   `json:"voltage,omitempty"` // V, cell events
+}
+
+// EventBus derives events from consecutive samples. Feed it with poller.OnResult(bus.Update).
+// The first sample only sets the initial state, except for cells already past a limit.
+type EventBus struct {
+	IdleCurrent      float64 // A, currents within ±IdleCurrent are neither charge nor discharge
+	CellOvervoltage  float64 // V, per-cell limit for CellOvervoltage events, 0 disables
+	CellUndervoltage float64 // V, per-cell limit for CellUndervoltage events, 0 disables
+	CellHysteresis   float64 // V, a cell must come back this far inside the limit before firing again
+
+	mutex        sync.Mutex
+	subscribers  []eventSubscriber
+	previous     *AllBMSData
+	charging     bool
+	discharging  bool
+	overvoltage  map[int]bool
+	undervoltage map[int]bool
+}
+
+type eventSubscriber struct {
+	channel chan Event
+	kinds   map[EventKind]bool // nil = all kinds
+}
+
+// NewEventBus creates a bus with LiFePO4 cell limits
+func NewEventBus() *EventBus {
+	return &EventBus{
+		IdleCurrent:      0.5,  // default
+		CellOvervoltage:  3.65, // default
+		CellUndervoltage: 2.5,  // default
+		CellHysteresis:   0.05, // default
+		overvoltage:      make(map[int]bool),
+		undervoltage:     make(map[int]bool),
+	}
+}
+
+// Subscribe returns a channel receiving events of the given kinds, all kinds if none.
+// Events are dropped when the channel buffer is full. The channel is closed by Close().
+func (bus *EventBus) Subscribe(bufferSize int, kinds ...EventKind) <-chan Event {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	subscriber := eventSubscriber{channel: make(chan Event, bufferSize)}
+	if len(kinds) > 0 {
+		subscriber.kinds = make(map[EventKind]bool, len(kinds))
+		for _, kind := range kinds {
+			subscriber.kinds[kind] = true
+		}
+	}
+	bus.subscribers = append(bus.subscribers, subscriber)
+	return subscriber.channel
+}
+
+// Close closes subscriber channels
+func (bus *EventBus) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for _, subscriber := range bus.subscribers {
+		close(subscriber.channel)
+	}
+	bus.subscribers = nil
+}
+
+// Update compares a poll result with the previous one. Failed polls are skipped.
+func (bus *EventBus) Update(result PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	sampleTime := result.Time
+	if sampleTime.IsZero() {
+		sampleTime = time.Now()
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	for _, event := range bus.detect(result.Data, sampleTime) {
+		bus.publish(event)
+	}
+	bus.previous = result.Data
+}
+
+// detect returns the events between the previous sample and data. Must be called with mutex held.
+func (bus *EventBus) detect(data *AllBMSData, sampleTime time.Time) []Event {
+	var events []Event
+	previous := bus.previous
+
+	if data.SOC != nil {
+		current := data.SOC.ChargeCurrent()
+		charging, discharging := current > bus.IdleCurrent, current < -bus.IdleCurrent
+		if previous != nil && previous.SOC != nil {
+			if charging != bus.charging {
+				events = append(events, Event{Kind: pick(charging, ChargeStarted, ChargeStopped), Time: sampleTime, Current: current})
+			}
+			if discharging != bus.discharging {
+				events = append(events, Event{Kind: pick(discharging, DischargeStarted, DischargeStopped), Time: sampleTime, Current: current})
+			}
+		}
+		bus.charging, bus.discharging = charging, discharging
+	}
+
+	if data.MosfetStatus != nil && previous != nil && previous.MosfetStatus != nil {
+		if data.MosfetStatus.ChargingMosfet != previous.MosfetStatus.ChargingMosfet {
+			kind := pick(data.MosfetStatus.ChargingMosfet, MosfetEnabled, MosfetDisabled)
+			events = append(events, Event{Kind: kind, Time: sampleTime, Mosfet: "charge"})
+		}
+		if data.MosfetStatus.DischargingMosfet != previous.MosfetStatus.DischargingMosfet {
+			kind := pick(data.MosfetStatus.DischargingMosfet, MosfetEnabled, MosfetDisabled)
+			events = append(events, Event{Kind: kind, Time: sampleTime, Mosfet: "discharge"})
+		}
+	}
+
+	if data.Errors != nil && previous != nil && previous.Errors != nil {
+		current, last := data.Errors.Strings(), previous.Errors.Strings()
+		for _, errorText := range current {
+			if !slices.Contains(last, errorText) {
+				events = append(events, Event{Kind: ErrorRaised, Time: sampleTime, Error: errorText})
+			}
+		}
+		for _, errorText := range last {
+			if !slices.Contains(current, errorText) {
+				events = append(events, Event{Kind: ErrorCleared, Time: sampleTime, Error: errorText})
+			}
+		}
+	}
+
+	for _, cellIndex := range slices.Sorted(maps.Keys(data.CellVoltages)) {
+		voltage := data.CellVoltages[cellIndex]
+		if bus.CellOvervoltage > 0 {
+			if !bus.overvoltage[cellIndex] && voltage > bus.CellOvervoltage {
+				bus.overvoltage[cellIndex] = true
+				events = append(events, Event{Kind: CellOvervoltage, Time: sampleTime, Cell: cellIndex, Label: data.CellLabels[cellIndex], Voltage: voltage})
+			} else if voltage <= bus.CellOvervoltage-bus.CellHysteresis {
+				bus.overvoltage[cellIndex] = false
+			}
+		}
+		if bus.CellUndervoltage > 0 {
+			if !bus.undervoltage[cellIndex] && voltage < bus.CellUndervoltage {
+				bus.undervoltage[cellIndex] = true
+				events = append(events, Event{Kind: CellUndervoltage, Time: sampleTime, Cell: cellIndex, Label: data.CellLabels[cellIndex], Voltage: voltage})
+			} else if voltage >= bus.CellUndervoltage+bus.CellHysteresis {
+				bus.undervoltage[cellIndex] = false
+			}
+		}
+	}
+
+	return events
+}
+
+// publish delivers an event without blocking on slow subscribers. Must be called with mutex held.
+func (bus *EventBus) publish(event Event) {
+	for _, subscriber := range bus.subscribers {
+		if subscriber.kinds != nil && !subscriber.kinds[event.Kind] {
+			continue
+		}
+		select {
+		case subscriber.channel <- event:
+		default:
+		}
+	}
+}
+
+func pick(condition bool, ifTrue EventKind, ifFalse EventKind) EventKind {
+	if condition {
+		return ifTrue
+	}
+	return ifFalse
+}
@@ -0,0 +1,87 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// ErrDangerousCommand is returned (wrapped) by FactoryReset() and SaveParameters() unless the
+// client was created with WithDangerousCommands()
+var ErrDangerousCommand = errors.New("dangerous command not enabled, see WithDangerousCommands()")
+
+// Allow FactoryReset() and SaveParameters(), which overwrite the stored configuration of the board.
+// Leave it off in monitoring code so a stray call can't wipe the protection parameters.
+func WithDangerousCommands() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.dangerousCommands = true
+	}
+}
+
+// Command code that makes the board store the written parameters, for firmware that keeps
+// writes in RAM until told otherwise. The published protocol has none: boards that store each
+// write as it comes leave it unset and SaveParameters() returns ErrUnsupported.
+func WithSaveCommand(command protocol.Command) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.saveCommand = &command
+	}
+}
+
+// Restore the factory parameters (command 0xd8): cell count, capacity and every protection
+// threshold. Requires WithDangerousCommands(). Cached state is dropped, the next reads fetch it again.
+func (bms *DalyBMSIstance) FactoryReset() error {
+	return bms.FactoryResetCtx(context.Background())
+}
+
+// FactoryReset with cancellation support
+func (bms *DalyBMSIstance) FactoryResetCtx(ctx context.Context) error {
+	if !bms.dangerousCommands {
+		return fmt.Errorf("factory reset: %w", ErrDangerousCommand)
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandFactoryReset, nil, 1, false)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no response from FactoryReset")
+	}
+	bms.logf("FactoryReset response: %x\n", response)
+
+	// the cached status holds the old cell count
+	bms.cacheStatus(ctx, nil)
+	// and the factory password may differ
+	bms.forgetAuthentication()
+	return nil
+}
+
+// Store the written parameters, see WithSaveCommand(). Requires WithDangerousCommands().
+func (bms *DalyBMSIstance) SaveParameters() error {
+	return bms.SaveParametersCtx(context.Background())
+}
+
+// SaveParameters with cancellation support
+func (bms *DalyBMSIstance) SaveParametersCtx(ctx context.Context) error {
+	if !bms.dangerousCommands {
+		return fmt.Errorf("save parameters: %w", ErrDangerousCommand)
+	}
+	if bms.saveCommand == nil {
+		return fmt.Errorf("save parameters: %w", ErrUnsupported)
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, *bms.saveCommand, nil, 1, false)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no response from SaveParameters")
+	}
+	bms.logf("SaveParameters response: %x\n", response)
+	return nil
+}
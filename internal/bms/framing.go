@@ -0,0 +1,91 @@
+package dalybms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+const (
+	frameStartByte = protocol.StartByte
+	frameLength    = protocol.FrameLength // 4 for header, 8 for data, 1 for CRC
+)
+
+// frameReader accumulates bytes from the transport and extracts aligned frames, so partial
+// reads and leading garbage don't abort a request. After a CRC failure it resynchronizes on
+// the next start byte.
+type frameReader struct {
+	bms          *DalyBMSIstance
+	transport    Transport
+	buffer       []byte
+	lastActivity time.Time // request write or latest read with data, see WithLatency()
+}
+
+// next returns the next frame with a valid CRC, or nil when the transport has no more data
+// (within the latency of the link) or ctx is done. In strict mode a CRC mismatch is returned as a *FrameError.
+// Must be called with busMutex held, unless the reader runs in the background reader.
+func (reader *frameReader) next(ctx context.Context) ([]byte, error) {
+	readBuffer := make([]byte, 64)
+
+	for ctx.Err() == nil {
+		// drop anything before the start byte
+		startIndex := bytes.IndexByte(reader.buffer, frameStartByte)
+		if startIndex < 0 {
+			if len(reader.buffer) > 0 {
+				reader.bms.logf("Discarding %d bytes of garbage: %x", len(reader.buffer), reader.buffer)
+			}
+			reader.buffer = reader.buffer[:0]
+		} else if startIndex > 0 {
+			reader.bms.logf("Discarding %d bytes of garbage: %x", startIndex, reader.buffer[:startIndex])
+			reader.buffer = reader.buffer[startIndex:]
+		}
+
+		if len(reader.buffer) >= frameLength {
+			frame := append([]byte(nil), reader.buffer[:frameLength]...)
+			if _, err := protocol.Decode(frame); err != nil {
+				reader.bms.observeFrame(DirectionRX, frame)
+				// the start byte was not a real frame start, look for the next one
+				reader.buffer = reader.buffer[1:]
+				// the reason is the detail after the protocol error, eg "computed 3c != 3d"
+				_, reason, _ := strings.Cut(err.Error(), ": ")
+				frameErr := &FrameError{Err: ErrHeaderMismatch, Frame: frame, Reason: reason}
+				if errors.Is(err, protocol.ErrChecksum) {
+					frameErr.Err = ErrCRCMismatch
+					reader.bms.countLink(func(stats *LinkStats) { stats.CRCErrors++ })
+				} else {
+					reader.bms.countLink(func(stats *LinkStats) { stats.HeaderErrors++ })
+				}
+				if reader.bms.strictFrames {
+					return nil, frameErr
+				}
+				reader.bms.logf("%v, resynchronizing", err)
+				continue
+			}
+
+			reader.buffer = reader.buffer[frameLength:]
+			reader.bms.observeFrame(DirectionRX, frame)
+			reader.bms.countLink(func(stats *LinkStats) { stats.FramesReceived++ })
+			return frame, nil
+		}
+
+		bytesRead, readErr := reader.transport.Read(readBuffer)
+		if readErr == nil && bytesRead == 0 && reader.bms.awaitingData(reader.lastActivity) {
+			// a slow link may still deliver the rest
+			continue
+		}
+		if readErr != nil || bytesRead == 0 {
+			// Probably a timeout or no more data
+			if len(reader.buffer) > 0 {
+				reader.bms.logf("Partial frame discarded: got %d bytes (expected %d)", len(reader.buffer), frameLength)
+			}
+			return nil, nil
+		}
+		reader.buffer = append(reader.buffer, readBuffer[:bytesRead]...)
+		reader.lastActivity = time.Now()
+	}
+	return nil, nil
+}
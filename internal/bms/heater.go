@@ -0,0 +1,79 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Commands of the heating output of Daly boards that have one. The published protocol doesn't
+// cover them and their codes vary between firmware versions, so there is no default: take them
+// from the documentation of the board or a capture of the Daly app.
+type HeaterCommands struct {
+	Status protocol.Command // response data byte 0: 1 = heating
+	Set    protocol.Command // request data byte 0: 1 = on, 0 = off
+}
+
+// Enable GetHeaterStatus() and EnableHeater() with the command codes of the board
+func WithHeaterCommands(commands HeaterCommands) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.heaterCommands = &commands
+	}
+}
+
+// State of the heating output, see GetHeaterStatus()
+type HeaterStatus struct {
+	On   bool      `json:"on"`
+	Time time.Time `json:"time"` // when the response was read
+}
+
+// Get the state of the heating output. Returns ErrUnsupported without WithHeaterCommands().
+func (bms *DalyBMSIstance) GetHeaterStatus() (*HeaterStatus, error) {
+	return bms.GetHeaterStatusCtx(context.Background())
+}
+
+// GetHeaterStatus with cancellation support
+func (bms *DalyBMSIstance) GetHeaterStatusCtx(ctx context.Context) (*HeaterStatus, error) {
+	if bms.heaterCommands == nil {
+		return nil, fmt.Errorf("heater: %w", ErrUnsupported)
+	}
+	data, err := bms.readParameter(ctx, bms.heaterCommands.Status, "get_heater_status")
+	if err != nil {
+		return nil, err
+	}
+	return &HeaterStatus{On: data[0] == 1, Time: time.Now()}, nil
+}
+
+// Switch the heating output on or off. Returns ErrUnsupported without WithHeaterCommands().
+func (bms *DalyBMSIstance) EnableHeater(isOn bool) error {
+	return bms.EnableHeaterCtx(context.Background(), isOn)
+}
+
+// EnableHeater with cancellation support
+func (bms *DalyBMSIstance) EnableHeaterCtx(ctx context.Context, isOn bool) error {
+	if bms.heaterCommands == nil {
+		return fmt.Errorf("heater: %w", ErrUnsupported)
+	}
+	var state byte
+	if isOn {
+		state = 1
+	}
+
+	if err := bms.limitControl(ctx, bms.heaterCommands.Set, []byte{state}); err != nil {
+		return err
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, bms.heaterCommands.Set, []byte{state}, 1, false)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no response from EnableHeater")
+	}
+	bms.logf("EnableHeater response: %x\n", response)
+	return nil
+}
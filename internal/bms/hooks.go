@@ -0,0 +1,186 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kind of event delivered to action hooks
+type ActionEventKind int
+
+const (
+	ChargeMosfetDisabled ActionEventKind = iota
+	ChargeMosfetEnabled
+	DischargeMosfetDisabled
+	DischargeMosfetEnabled
+	AlarmRaised
+	AlarmCleared
+)
+
+func (kind ActionEventKind) String() string {
+	switch kind {
+	case ChargeMosfetDisabled:
+		return "charge_mosfet_disabled"
+	case ChargeMosfetEnabled:
+		return "charge_mosfet_enabled"
+	case DischargeMosfetDisabled:
+		return "discharge_mosfet_disabled"
+	case DischargeMosfetEnabled:
+		return "discharge_mosfet_enabled"
+	case AlarmRaised:
+		return "alarm_raised"
+	case AlarmCleared:
+		return "alarm_cleared"
+	}
+	return "unknown"
+}
+
+var actionEventKindNames = map[string]ActionEventKind{
+	"charge_mosfet_disabled":    ChargeMosfetDisabled,
+	"charge_mosfet_enabled":     ChargeMosfetEnabled,
+	"discharge_mosfet_disabled": DischargeMosfetDisabled,
+	"discharge_mosfet_enabled":  DischargeMosfetEnabled,
+	"alarm_raised":              AlarmRaised,
+	"alarm_cleared":             AlarmCleared,
+}
+
+// Kinds are serialized by name, eg "alarm_raised"
+func (kind ActionEventKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+func (kind *ActionEventKind) UnmarshalText(text []byte) error {
+	parsedKind, ok := actionEventKindNames[string(text)]
+	if !ok {
+		return fmt.Errorf("unknown action event kind: %s", text)
+	}
+	*kind = parsedKind
+	return nil
+}
+
+// Event passed to action hooks. Errors holds the active alarms for alarm events.
+type ActionEvent struct {
+	Kind         ActionEventKind   `json:"kind"`
+	MosfetStatus *MosfetStatusData `json:"mosfet_status,omitempty"`
+	Errors       []string          `json:"errors,omitempty"`
+}
+
+// ActionHook drives local actions (GPIO lines, relays, buzzers...) on alarm/mosfet events
+type ActionHook interface {
+	HandleAction(event ActionEvent) error
+}
+
+// ActionHookFunc adapts a plain function to the ActionHook interface
+type ActionHookFunc func(event ActionEvent) error
+
+func (hookFunc ActionHookFunc) HandleAction(event ActionEvent) error {
+	return hookFunc(event)
+}
+
+// RelayHook switches an output line on the given event kinds.
+// SetLevel is user supplied, eg for periph.io: func(on bool) error { return pin.Out(gpio.Level(on)) }
+type RelayHook struct {
+	SetLevel func(on bool) error
+	OnKinds  map[ActionEventKind]bool // kind => level to set
+}
+
+func (relay *RelayHook) HandleAction(event ActionEvent) error {
+	level, ok := relay.OnKinds[event.Kind]
+	if !ok || relay.SetLevel == nil {
+		return nil
+	}
+	return relay.SetLevel(level)
+}
+
+// Register a hook called on alarm and mosfet events
+func (bms *DalyBMSIstance) AddActionHook(hook ActionHook) {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	bms.actionHooks = append(bms.actionHooks, hook)
+}
+
+// fireActions delivers events to all registered hooks. Hook errors are logged, not returned.
+// Must be called without stateMutex held, hooks may call back into the BMS. In a batch,
+// hooks run once the bus is released.
+func (bms *DalyBMSIstance) fireActions(ctx context.Context, events []ActionEvent) {
+	if len(events) == 0 {
+		return
+	}
+	bms.afterBatch(ctx, func() {
+		bms.deliverActions(events)
+	})
+}
+
+func (bms *DalyBMSIstance) deliverActions(events []ActionEvent) {
+
+	bms.stateMutex.Lock()
+	hooks := append([]ActionHook(nil), bms.actionHooks...)
+	bms.stateMutex.Unlock()
+
+	for _, event := range events {
+		for _, hook := range hooks {
+			if err := hook.HandleAction(event); err != nil {
+				bms.logf("Action hook failed for %s: %v", event.Kind, err)
+			}
+		}
+	}
+}
+
+// detectMosfetActions compares a new mosfet status with the cached one and fires hooks on changes
+func (bms *DalyBMSIstance) detectMosfetActions(ctx context.Context, current *MosfetStatusData) {
+	if isTargeted(ctx) {
+		return // another BMS, see AtAddress()
+	}
+	bms.stateMutex.Lock()
+	previous := bms.latestMosfetStatus
+	bms.latestMosfetStatus = current
+	bms.stateMutex.Unlock()
+	if previous == nil {
+		return
+	}
+
+	var events []ActionEvent
+	if previous.ChargingMosfet != current.ChargingMosfet {
+		kind := ChargeMosfetDisabled
+		if current.ChargingMosfet {
+			kind = ChargeMosfetEnabled
+		}
+		events = append(events, ActionEvent{Kind: kind, MosfetStatus: current})
+	}
+	if previous.DischargingMosfet != current.DischargingMosfet {
+		kind := DischargeMosfetDisabled
+		if current.DischargingMosfet {
+			kind = DischargeMosfetEnabled
+		}
+		events = append(events, ActionEvent{Kind: kind, MosfetStatus: current})
+	}
+	bms.fireActions(ctx, events)
+}
+
+// trackErrors records the active errors in the history and fires the alarm hooks, unless ctx
+// targets another BMS, see AtAddress()
+func (bms *DalyBMSIstance) trackErrors(ctx context.Context, current []string) {
+	if isTargeted(ctx) {
+		return
+	}
+	bms.recordErrors(current)
+	bms.detectAlarmActions(ctx, current)
+}
+
+// detectAlarmActions fires hooks when the BMS starts or stops reporting errors
+func (bms *DalyBMSIstance) detectAlarmActions(ctx context.Context, current []string) {
+	bms.stateMutex.Lock()
+	wasAlarmed := bms.alarmActive
+	bms.alarmActive = len(current) > 0
+	isAlarmed := bms.alarmActive
+	bms.stateMutex.Unlock()
+	if wasAlarmed == isAlarmed {
+		return
+	}
+
+	if isAlarmed {
+		bms.fireActions(ctx, []ActionEvent{{Kind: AlarmRaised, Errors: current}})
+	} else {
+		bms.fireActions(ctx, []ActionEvent{{Kind: AlarmCleared}})
+	}
+}
@@ -0,0 +1,219 @@
+package dalybms
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Cell voltages at a point in time, input of AnalyzeImbalance()
+type CellSample struct {
+	Time     time.Time       `json:"time"`
+	Voltages map[int]float64 `json:"voltages"`         // by cell index
+	Labels   map[int]string  `json:"labels,omitempty"` // physical label of each cell, see SetCellMap()
+}
+
+// Imbalance analysis of a time series, see AnalyzeImbalance()
+type ImbalanceReport struct {
+	Samples           int             `json:"samples"`
+	Span              time.Duration   `json:"span"`
+	CurrentDelta      float64         `json:"current_delta"`        // V, highest - lowest cell in the latest sample
+	AverageDelta      float64         `json:"average_delta"`        // V
+	TrendVoltsPerHour float64         `json:"trend_volts_per_hour"` // change of the delta, negative when converging
+	CellDeviations    map[int]float64 `json:"cell_deviations"`      // V, average deviation of each cell from the pack mean
+	ChronicHighCells  []int           `json:"chronic_high_cells"`   // highest cell in at least half of the samples
+	ChronicLowCells   []int           `json:"chronic_low_cells"`    // lowest cell in at least half of the samples
+	Converging        bool            `json:"converging"`
+	TimeToBalance     time.Duration   `json:"time_to_balance"` // until the delta reaches BalancedDelta at the current trend, 0 if not converging
+	Recommendations   []string        `json:"recommendations"`
+}
+
+// Delta below which a pack counts as balanced
+const BalancedDelta = 0.01 // V
+
+// Share of samples in which a cell must be the highest/lowest to count as chronic
+const chronicShare = 0.5
+
+// AnalyzeImbalance reports chronic high/low cells, the imbalance trend and the estimated time to balance.
+// Samples must be in time order. The trend needs samples spanning some time, eg an hour of polling.
+func AnalyzeImbalance(samples []CellSample) *ImbalanceReport {
+	report := &ImbalanceReport{CellDeviations: make(map[int]float64)}
+
+	var deltas, hours []float64
+	highestCounts := make(map[int]int)
+	lowestCounts := make(map[int]int)
+	deviationSums := make(map[int]float64)
+	deviationCounts := make(map[int]int)
+	var firstTime time.Time
+	var labels map[int]string
+	for _, sample := range samples {
+		if len(sample.Voltages) < 2 {
+			continue
+		}
+		if report.Samples == 0 {
+			firstTime = sample.Time
+		}
+		report.Span = sample.Time.Sub(firstTime)
+		report.Samples++
+		if sample.Labels != nil {
+			labels = sample.Labels
+		}
+
+		lowest, highest := valueRange(sample.Voltages)
+		mean := average(sample.Voltages)
+		for _, cellIndex := range slices.Sorted(maps.Keys(sample.Voltages)) {
+			voltage := sample.Voltages[cellIndex]
+			deviationSums[cellIndex] += voltage - mean
+			deviationCounts[cellIndex]++
+			// ties count for the lowest index only, so a balanced pack has no chronic cells
+			if voltage == highest && highest > lowest {
+				highestCounts[cellIndex]++
+				highest = math.Inf(1)
+			}
+			if voltage == lowest && highest != lowest {
+				lowestCounts[cellIndex]++
+				lowest = math.Inf(-1)
+			}
+		}
+
+		deltas = append(deltas, sampleDelta(sample.Voltages))
+		hours = append(hours, report.Span.Hours())
+	}
+	if report.Samples == 0 {
+		return report
+	}
+
+	report.CurrentDelta = deltas[len(deltas)-1]
+	report.AverageDelta = math.Round(averageSlice(deltas)*10000) / 10000
+	for cellIndex, sum := range deviationSums {
+		report.CellDeviations[cellIndex] = math.Round(sum/float64(deviationCounts[cellIndex])*10000) / 10000
+	}
+	for _, cellIndex := range slices.Sorted(maps.Keys(highestCounts)) {
+		if float64(highestCounts[cellIndex]) >= chronicShare*float64(report.Samples) {
+			report.ChronicHighCells = append(report.ChronicHighCells, cellIndex)
+		}
+	}
+	for _, cellIndex := range slices.Sorted(maps.Keys(lowestCounts)) {
+		if float64(lowestCounts[cellIndex]) >= chronicShare*float64(report.Samples) {
+			report.ChronicLowCells = append(report.ChronicLowCells, cellIndex)
+		}
+	}
+
+	if report.Span > 0 {
+		report.TrendVoltsPerHour = math.Round(slope(hours, deltas)*10000) / 10000
+	}
+	if report.TrendVoltsPerHour < 0 && report.CurrentDelta > BalancedDelta {
+		report.Converging = true
+		hoursLeft := (report.CurrentDelta - BalancedDelta) / -report.TrendVoltsPerHour
+		report.TimeToBalance = time.Duration(hoursLeft * float64(time.Hour)).Round(time.Minute)
+	}
+
+	report.Recommendations = imbalanceRecommendations(report, labels)
+	return report
+}
+
+// imbalanceRecommendations names cells by their label in labels, the latest mapping of the samples
+func imbalanceRecommendations(report *ImbalanceReport, labels map[int]string) []string {
+	var recommendations []string
+	for _, cellIndex := range report.ChronicHighCells {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"%s is usually the highest (%+.0f mV): it may have less capacity, check it when balancing does not help",
+			cellName(labels, cellIndex), report.CellDeviations[cellIndex]*1000))
+	}
+	for _, cellIndex := range report.ChronicLowCells {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"%s is usually the lowest (%+.0f mV): check its connections, self-discharge or capacity",
+			cellName(labels, cellIndex), report.CellDeviations[cellIndex]*1000))
+	}
+	switch {
+	case report.TrendVoltsPerHour > 0 && report.Span >= time.Hour:
+		recommendations = append(recommendations, fmt.Sprintf(
+			"imbalance is growing by %.1f mV/h: check the balance settings and that the pack reaches the balance start voltage",
+			report.TrendVoltsPerHour*1000))
+	case report.Converging && report.TimeToBalance > 7*24*time.Hour:
+		recommendations = append(recommendations, "balancing is too slow to fix the imbalance: consider a manual top balance or an active balancer")
+	}
+	if report.CurrentDelta > 0.1 {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"cell delta is %.0f mV: consider a manual top balance", report.CurrentDelta*1000))
+	}
+	return recommendations
+}
+
+// sampleDelta returns the highest - lowest cell voltage, rounded to the mV resolution of the readings
+func sampleDelta(voltages map[int]float64) float64 {
+	lowest, highest := valueRange(voltages)
+	return math.Round((highest-lowest)*1000) / 1000
+}
+
+func averageSlice(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// slope of the least squares line through the points
+func slope(x []float64, y []float64) float64 {
+	meanX, meanY := averageSlice(x), averageSlice(y)
+	var numerator, denominator float64
+	for index := range x {
+		numerator += (x[index] - meanX) * (y[index] - meanY)
+		denominator += (x[index] - meanX) * (x[index] - meanX)
+	}
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// ImbalanceTracker keeps the cell voltages of the latest samples for AnalyzeImbalance().
+// Feed it with poller.OnResult(tracker.Update).
+type ImbalanceTracker struct {
+	MaxSamples int           // oldest samples are dropped beyond this, default 1440 (a day at one sample per minute)
+	MinSpacing time.Duration // samples closer to the previous one are skipped, default 1 minute
+
+	mutex   sync.Mutex
+	samples []CellSample
+}
+
+func NewImbalanceTracker() *ImbalanceTracker {
+	return &ImbalanceTracker{
+		MaxSamples: 1440,        // default
+		MinSpacing: time.Minute, // default
+	}
+}
+
+// Update records the cell voltages of a poll result. Failed polls are skipped.
+func (tracker *ImbalanceTracker) Update(result PollResult) {
+	if result.Err != nil || result.Data == nil || len(result.Data.CellVoltages) == 0 {
+		return
+	}
+	sampleTime := result.Time
+	if sampleTime.IsZero() {
+		sampleTime = time.Now()
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if count := len(tracker.samples); count > 0 && sampleTime.Sub(tracker.samples[count-1].Time) < tracker.MinSpacing {
+		return
+	}
+	tracker.samples = append(tracker.samples, CellSample{Time: sampleTime, Voltages: maps.Clone(result.Data.CellVoltages), Labels: result.Data.CellLabels})
+	if overflow := len(tracker.samples) - tracker.MaxSamples; tracker.MaxSamples > 0 && overflow > 0 {
+		tracker.samples = slices.Delete(tracker.samples, 0, overflow)
+	}
+}
+
+// Report analyzes the recorded samples
+func (tracker *ImbalanceTracker) Report() *ImbalanceReport {
+	tracker.mutex.Lock()
+	samples := slices.Clone(tracker.samples)
+	tracker.mutex.Unlock()
+	return AnalyzeImbalance(samples)
+}
@@ -0,0 +1,70 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Get the BMS software (firmware) version, eg "20210222-1.01T"
+func (bms *DalyBMSIstance) GetFirmwareVersion() (string, error) {
+	return bms.GetFirmwareVersionCtx(context.Background())
+}
+
+// GetFirmwareVersion with cancellation support
+func (bms *DalyBMSIstance) GetFirmwareVersionCtx(ctx context.Context) (string, error) {
+	return bms.readTextFrames(ctx, protocol.CommandFirmwareVersion, 2, "get_firmware_version")
+}
+
+// Get the BMS hardware version
+func (bms *DalyBMSIstance) GetHardwareVersion() (string, error) {
+	return bms.GetHardwareVersionCtx(context.Background())
+}
+
+// GetHardwareVersion with cancellation support
+func (bms *DalyBMSIstance) GetHardwareVersionCtx(ctx context.Context) (string, error) {
+	return bms.readTextFrames(ctx, protocol.CommandHardwareVersion, 2, "get_hardware_version")
+}
+
+// Get the battery code (serial number configured in the BMS), used to identify units
+func (bms *DalyBMSIstance) GetBatteryCode() (string, error) {
+	return bms.GetBatteryCodeCtx(context.Background())
+}
+
+// GetBatteryCode with cancellation support
+func (bms *DalyBMSIstance) GetBatteryCodeCtx(ctx context.Context) (string, error) {
+	return bms.readTextFrames(ctx, protocol.CommandBatteryCode, 5, "get_battery_code")
+}
+
+// readTextFrames reads an ASCII value split over several frames: 1 byte frame number + 7 chars each
+func (bms *DalyBMSIstance) readTextFrames(ctx context.Context, command protocol.Command, maxFrames int, operation string) (string, error) {
+	response, err := bms.sendReadRequestCtx(ctx, command, nil, maxFrames, true)
+	if err != nil {
+		return "", err
+	}
+	if response == nil {
+		return "", fmt.Errorf("no data for %s", operation)
+	}
+
+	dataFrames, ok := response.([][]byte)
+	if !ok {
+		return "", fmt.Errorf("unexpected response type for %s", operation)
+	}
+
+	// frames may arrive out of order, sort them by frame number
+	sort.SliceStable(dataFrames, func(i, j int) bool {
+		return dataFrames[i][0] < dataFrames[j][0]
+	})
+
+	var text strings.Builder
+	for _, frame := range dataFrames {
+		if len(frame) < 2 {
+			continue
+		}
+		text.Write(frame[1:])
+	}
+	return strings.TrimRight(text.String(), "\x00 "), nil
+}
@@ -0,0 +1,37 @@
+package dalybms
+
+import (
+	"context"
+	"time"
+)
+
+// RunKeepAlive sends a SOC request whenever the bus has been idle for interval, so WiFi
+// bridges and NAT gateways don't close the connection between sparse polls. Failures are
+// logged. Blocks until ctx is done and returns ctx.Err().
+func (bms *DalyBMSIstance) RunKeepAlive(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		bms.busMutex.Lock()
+		idle := time.Since(bms.lastWrite)
+		connected := bms.transport != nil
+		bms.busMutex.Unlock()
+
+		if connected && idle >= interval {
+			if _, err := bms.GetSOCCtx(ctx); err != nil && ctx.Err() == nil {
+				bms.logf("Keepalive request failed: %v", err)
+			}
+		}
+	}
+}
+
+// awaitingData is true while an empty read is within the link latency since lastActivity
+func (bms *DalyBMSIstance) awaitingData(lastActivity time.Time) bool {
+	return bms.linkLatency > 0 && time.Since(lastActivity) < bms.linkLatency
+}
@@ -0,0 +1,178 @@
+package dalybms
+
+import (
+	"context"
+)
+
+// How a LimitCurve maps a reading to a current factor
+type LimitMode int
+
+const (
+	LimitModeLinear LimitMode = iota // interpolate between points
+	LimitModeStep                    // factor of the last point at or below the reading
+)
+
+// Point of a LimitCurve: at Value (V or °C) the current is Factor (0-1) times the maximum
+type LimitPoint struct {
+	Value  float64
+	Factor float64
+}
+
+// Curve mapping a reading to a current factor, points sorted by Value.
+// Readings outside the curve get the factor of the nearest end. An empty curve is factor 1.
+type LimitCurve []LimitPoint
+
+func (curve LimitCurve) factor(value float64, mode LimitMode) float64 {
+	if len(curve) == 0 {
+		return 1
+	}
+	if value <= curve[0].Value {
+		return curve[0].Factor
+	}
+	last := curve[len(curve)-1]
+	if value >= last.Value {
+		return last.Factor
+	}
+
+	for pointIndex := 1; pointIndex < len(curve); pointIndex++ {
+		previous, point := curve[pointIndex-1], curve[pointIndex]
+		if value >= point.Value {
+			continue
+		}
+		if mode == LimitModeStep || point.Value == previous.Value {
+			return previous.Factor
+		}
+		ratio := (value - previous.Value) / (point.Value - previous.Value)
+		return previous.Factor + (point.Factor-previous.Factor)*ratio
+	}
+	return last.Factor
+}
+
+// Configuration of the charge/discharge current limit computation, see DefaultChargeLimitConfig()
+type ChargeLimitConfig struct {
+	Mode                LimitMode
+	MaxChargeCurrent    float64 // A
+	MaxDischargeCurrent float64 // A
+
+	ChargeCellVoltage    LimitCurve // on the highest cell voltage
+	DischargeCellVoltage LimitCurve // on the lowest cell voltage
+	ChargeTemperature    LimitCurve // on both the lowest and highest temperature
+	DischargeTemperature LimitCurve // on both the lowest and highest temperature
+
+	StopOnAlarm bool // level two errors and hardware failures set both limits to 0
+}
+
+// DefaultChargeLimitConfig returns curves for LiFePO4 cells: charge current tapers from 3.40 V
+// to zero at 3.55 V, discharge current from 3.10 V to zero at 2.80 V, and no charging below 0 °C.
+func DefaultChargeLimitConfig(maxChargeCurrent float64, maxDischargeCurrent float64) ChargeLimitConfig {
+	return ChargeLimitConfig{
+		Mode:                 LimitModeLinear,
+		MaxChargeCurrent:     maxChargeCurrent,
+		MaxDischargeCurrent:  maxDischargeCurrent,
+		ChargeCellVoltage:    LimitCurve{{3.40, 1}, {3.50, 0.1}, {3.55, 0}},
+		DischargeCellVoltage: LimitCurve{{2.80, 0}, {2.90, 0.1}, {3.10, 1}},
+		ChargeTemperature:    LimitCurve{{0, 0}, {5, 0.2}, {10, 1}, {45, 1}, {55, 0}},
+		DischargeTemperature: LimitCurve{{-20, 0}, {-10, 1}, {50, 1}, {60, 0}},
+		StopOnAlarm:          true,
+	}
+}
+
+// Recommended current limits, eg for an inverter or charger
+type ChargeLimits struct {
+	ChargeCurrentLimit    float64 `json:"charge_current_limit"`    // A
+	DischargeCurrentLimit float64 `json:"discharge_current_limit"` // A
+	ChargeLimitedBy       string  `json:"charge_limited_by"`       // the most restrictive input, "" if not limited
+	DischargeLimitedBy    string  `json:"discharge_limited_by"`
+}
+
+// Names of the inputs reported in ChargeLimits.ChargeLimitedBy and DischargeLimitedBy
+const (
+	LimitedByCellVoltage = "cell_voltage"
+	LimitedByTemperature = "temperature"
+	LimitedByAlarm       = "alarm"
+	LimitedByMosfet      = "mosfet"
+)
+
+// ChargeLimits computes the current limits of a sample. Inputs whose source data is missing are ignored.
+func (data *AllBMSData) ChargeLimits(config ChargeLimitConfig) *ChargeLimits {
+	chargeFactor, chargeLimitedBy := 1.0, ""
+	dischargeFactor, dischargeLimitedBy := 1.0, ""
+	limitCharge := func(factor float64, limitedBy string) {
+		if factor < chargeFactor {
+			chargeFactor, chargeLimitedBy = factor, limitedBy
+		}
+	}
+	limitDischarge := func(factor float64, limitedBy string) {
+		if factor < dischargeFactor {
+			dischargeFactor, dischargeLimitedBy = factor, limitedBy
+		}
+	}
+
+	if data.CellVoltageRange != nil {
+		limitCharge(config.ChargeCellVoltage.factor(Widen(data.CellVoltageRange.HighestVoltage), config.Mode), LimitedByCellVoltage)
+		limitDischarge(config.DischargeCellVoltage.factor(Widen(data.CellVoltageRange.LowestVoltage), config.Mode), LimitedByCellVoltage)
+	}
+	if data.TemperatureRange != nil {
+		for _, temperature := range []float64{data.TemperatureRange.LowestCelsius(), data.TemperatureRange.HighestCelsius()} {
+			limitCharge(config.ChargeTemperature.factor(temperature, config.Mode), LimitedByTemperature)
+			limitDischarge(config.DischargeTemperature.factor(temperature, config.Mode), LimitedByTemperature)
+		}
+	}
+	if config.StopOnAlarm {
+		for _, bmsError := range data.Errors {
+			if bmsError.Severity == SeverityAlarm {
+				limitCharge(0, LimitedByAlarm)
+				limitDischarge(0, LimitedByAlarm)
+				break
+			}
+		}
+	}
+	// never ask for current the BMS won't allow
+	if data.MosfetStatus != nil {
+		if !data.MosfetStatus.ChargingMosfet {
+			limitCharge(0, LimitedByMosfet)
+		}
+		if !data.MosfetStatus.DischargingMosfet {
+			limitDischarge(0, LimitedByMosfet)
+		}
+	}
+
+	return &ChargeLimits{
+		ChargeCurrentLimit:    roundCurrent(config.MaxChargeCurrent * max(chargeFactor, 0)),
+		DischargeCurrentLimit: roundCurrent(config.MaxDischargeCurrent * max(dischargeFactor, 0)),
+		ChargeLimitedBy:       chargeLimitedBy,
+		DischargeLimitedBy:    dischargeLimitedBy,
+	}
+}
+
+// roundCurrent rounds to the 0.1A resolution of the BMS
+func roundCurrent(current float64) float64 {
+	return float64(CurrentFromAmps(current)) / 10
+}
+
+// Get recommended charge/discharge current limits from cell voltages, temperatures, errors and MOSFET states
+func (bms *DalyBMSIstance) GetChargeLimits(config ChargeLimitConfig) (*ChargeLimits, error) {
+	return bms.GetChargeLimitsCtx(context.Background(), config)
+}
+
+// GetChargeLimits with cancellation support
+func (bms *DalyBMSIstance) GetChargeLimitsCtx(ctx context.Context, config ChargeLimitConfig) (*ChargeLimits, error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	var data AllBMSData
+	var err error
+	if data.CellVoltageRange, err = bms.GetCellVoltageRangeCtx(ctx); err != nil {
+		return nil, err
+	}
+	if data.TemperatureRange, err = bms.GetTemperatureRangeCtx(ctx); err != nil {
+		return nil, err
+	}
+	if data.MosfetStatus, err = bms.GetMosfetStatusCtx(ctx); err != nil {
+		return nil, err
+	}
+	if data.Errors, err = bms.GetErrorsCtx(ctx); err != nil {
+		return nil, err
+	}
+	return data.ChargeLimits(config), nil
+}
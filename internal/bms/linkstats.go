@@ -0,0 +1,42 @@
+package dalybms
+
+import (
+	"time"
+)
+
+// Link quality counters since Since, see GetLinkStats()
+type LinkStats struct {
+	Since          time.Time `json:"since"`
+	FramesSent     uint64    `json:"frames_sent"`
+	FramesReceived uint64    `json:"frames_received"` // with a valid CRC
+	CRCErrors      uint64    `json:"crc_errors"`
+	HeaderErrors   uint64    `json:"header_errors"` // unexpected command or address
+	Echoes         uint64    `json:"echoes"`        // requests looped back by the adapter
+	Timeouts       uint64    `json:"timeouts"`      // attempts without a response
+	Retries        uint64    `json:"retries"`
+}
+
+// Get the link quality counters, to diagnose marginal wiring
+func (bms *DalyBMSIstance) GetLinkStats() LinkStats {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	stats := bms.linkStats
+	if stats.Since.IsZero() {
+		stats.Since = bms.created
+	}
+	return stats
+}
+
+// Reset the link quality counters
+func (bms *DalyBMSIstance) ResetLinkStats() {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	bms.linkStats = LinkStats{Since: time.Now()}
+}
+
+// countLink updates the link counters
+func (bms *DalyBMSIstance) countLink(update func(stats *LinkStats)) {
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	update(&bms.linkStats)
+}
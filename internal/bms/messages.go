@@ -0,0 +1,94 @@
+package dalybms
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Translations of DalyErrorCodes, one JSON file per language with the same byte/bit layout
+//
+//go:embed translations/*.json
+var translations embed.FS
+
+// ErrorLanguages lists the languages WithLanguage() accepts besides "en"
+func ErrorLanguages() []string {
+	entries, _ := translations.ReadDir("translations")
+	languages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		languages = append(languages, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	return languages
+}
+
+// Report error messages in a language of ErrorLanguages(), eg "it". Unknown languages keep
+// the English messages of DalyErrorCodes.
+func WithLanguage(language string) Option {
+	return func(bms *DalyBMSIstance) {
+		language = strings.ToLower(language)
+		bms.languageError = nil
+		bms.errorLanguage = nil
+		if language == "en" || language == "" {
+			return
+		}
+		catalog, err := loadTranslation(language)
+		if err != nil {
+			// WithLogger() may come later in the options, NewClient() logs it
+			bms.languageError = fmt.Errorf("no error messages for language %q, available: %v", language, ErrorLanguages())
+			return
+		}
+		bms.errorLanguage = catalog
+	}
+}
+
+// Override error messages, keyed like DalyErrorCodes. Empty strings and missing bytes keep the
+// message of WithLanguage() or the English one, whatever the order of the options. Severity and
+// category don't depend on the text.
+func WithErrorMessages(messages map[int][]string) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.errorOverrides = mergeErrorMessages(bms.errorOverrides, messages)
+	}
+}
+
+// mergeErrorMessages returns a copy of catalog with the non-empty messages of overrides
+func mergeErrorMessages(catalog map[int][]string, overrides map[int][]string) map[int][]string {
+	merged := make(map[int][]string, len(catalog))
+	for byteIndex, messages := range catalog {
+		merged[byteIndex] = slices.Clone(messages)
+	}
+	for byteIndex, messages := range overrides {
+		for bit, message := range messages {
+			if message == "" {
+				continue
+			}
+			for len(merged[byteIndex]) <= bit {
+				merged[byteIndex] = append(merged[byteIndex], "")
+			}
+			merged[byteIndex][bit] = message
+		}
+	}
+	return merged
+}
+
+func loadTranslation(language string) (map[int][]string, error) {
+	content, err := translations.ReadFile("translations/" + language + ".json")
+	if err != nil {
+		return nil, err
+	}
+	var catalog map[int][]string
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// errorMessage returns the message of a flag in the configured language, "" when it has none
+func (bms *DalyBMSIstance) errorMessage(byteIndex int, bit int) string {
+	if messages, ok := bms.errorMessages[byteIndex]; ok && bit < len(messages) {
+		return messages[bit]
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package dalybms
+
+import "testing"
+
+// WithErrorMessages() overrides WithLanguage() whatever the order of the options
+func TestErrorMessagesOptionOrder(t *testing.T) {
+	catalog, err := loadTranslation("it")
+	if err != nil {
+		t.Fatalf("loadTranslation: %v", err)
+	}
+	overrides := map[int][]string{0: {"", "Cell overvoltage, custom"}}
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{"language first", []Option{WithLanguage("it"), WithErrorMessages(overrides)}},
+		{"overrides first", []Option{WithErrorMessages(overrides), WithLanguage("it")}},
+	}
+	for _, test := range tests {
+		bms := NewClient(test.options...)
+		if got, want := bms.errorMessage(0, 1), "Cell overvoltage, custom"; got != want {
+			t.Errorf("%s: byte 0 bit 1 = %q, want %q", test.name, got, want)
+		}
+		if got, want := bms.errorMessage(0, 0), catalog[0][0]; got != want {
+			t.Errorf("%s: byte 0 bit 0 = %q, want %q", test.name, got, want)
+		}
+		if got, want := bms.errorMessage(1, 0), catalog[1][0]; got != want {
+			t.Errorf("%s: byte 1 bit 0 = %q, want %q", test.name, got, want)
+		}
+	}
+}
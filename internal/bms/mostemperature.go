@@ -0,0 +1,49 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+)
+
+// Use sensor sensorIndex of GetTemperatures() as the MOSFET (board) temperature, for boards that
+// report it after the cell sensors. 0, the default, means the board has none.
+func WithMOSTemperatureSensor(sensorIndex int) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.mosSensor = sensorIndex
+	}
+}
+
+// Get the MOSFET temperature, in the unit of WithTemperatureUnit(). Returns ErrUnsupported
+// without WithMOSTemperatureSensor().
+func (bms *DalyBMSIstance) GetMOSTemperature() (float64, error) {
+	return bms.GetMOSTemperatureCtx(context.Background())
+}
+
+// GetMOSTemperature with cancellation support
+func (bms *DalyBMSIstance) GetMOSTemperatureCtx(ctx context.Context) (float64, error) {
+	if bms.mosSensor <= 0 {
+		return 0, fmt.Errorf("MOS temperature: %w", ErrUnsupported)
+	}
+
+	// with missing frames the sensor may still have been read
+	temperatures, err := bms.GetTemperaturesCtx(ctx)
+	if temperature := bms.mosTemperature(temperatures); temperature != nil {
+		return *temperature, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("MOS temperature: no sensor %d, the BMS reports %d", bms.mosSensor, len(temperatures))
+}
+
+// mosTemperature returns the MOSFET sensor of temperatures, nil when not configured or missing
+func (bms *DalyBMSIstance) mosTemperature(temperatures map[int]float64) *float64 {
+	if bms.mosSensor <= 0 {
+		return nil
+	}
+	temperature, ok := temperatures[bms.mosSensor]
+	if !ok {
+		return nil
+	}
+	return &temperature
+}
@@ -0,0 +1,67 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// A cell reading below this voltage has an open sense lead: a connected cell of any chemistry
+// stays well above it, even when fully discharged
+const openWireVoltage = 0.5 // V
+
+// Disconnected cell sense (balance) leads, see GetOpenWireStatus()
+type OpenWireStatus struct {
+	Flagged bool      `json:"flagged"` // the BMS raises its open wire flag, protocol.OpenWireErrorByte/Bit
+	Cells   []int     `json:"cells"`   // cells reading as disconnected, ascending
+	Time    time.Time `json:"time"`    // when the readings were taken
+}
+
+// Open reports whether a lead is open, flagged by the BMS or seen in the cell voltages
+func (status *OpenWireStatus) Open() bool {
+	return status.Flagged || len(status.Cells) > 0
+}
+
+// OpenWires checks the errors and cell voltages of a read for open sense leads. A broken lead
+// reads 0 V, or close to it, on the cells it measures.
+func (data *AllBMSData) OpenWires() *OpenWireStatus {
+	status := &OpenWireStatus{
+		Flagged: data.Errors.Has(protocol.OpenWireErrorByte, protocol.OpenWireErrorBit),
+		Cells:   []int{},
+		Time:    data.Time,
+	}
+	for cellIndex, voltage := range data.CellVoltages {
+		if voltage < openWireVoltage {
+			status.Cells = append(status.Cells, cellIndex)
+		}
+	}
+	slices.Sort(status.Cells)
+	return status
+}
+
+// Get the cells whose sense lead is disconnected, from the error flags and the cell voltages.
+// Daly boards only flag an open lead, the voltages tell which cells it affects.
+func (bms *DalyBMSIstance) GetOpenWireStatus() (*OpenWireStatus, error) {
+	return bms.GetOpenWireStatusCtx(context.Background())
+}
+
+// GetOpenWireStatus with cancellation support
+func (bms *DalyBMSIstance) GetOpenWireStatusCtx(ctx context.Context) (*OpenWireStatus, error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	data := AllBMSData{Time: time.Now()}
+	var err error
+	// Sinowealth boards have no error flags, their cell voltages are still checked
+	if data.Errors, err = bms.GetErrorsCtx(ctx); err != nil && !errors.Is(err, ErrUnsupported) {
+		return nil, err
+	}
+	// cells of lost frames are unknown, not open
+	if data.CellVoltages, err = bms.GetCellVoltagesCtx(ctx); data.CellVoltages == nil {
+		return nil, err
+	}
+	return data.OpenWires(), err
+}
@@ -1,25 +1,39 @@
 package dalybms
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
-	"log"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
 )
 
 // BMS status query
 type StatusData struct {
-	NumberOfCells              int
-	NumberOfTemperatureSensors int
-	IsChargerRunning           bool
-	IsLoadRunning              bool
-	States                     map[string]bool
-	CycleCount                 int16
+	NumberOfCells              int             `json:"number_of_cells"`
+	NumberOfTemperatureSensors int             `json:"number_of_temperature_sensors"`
+	IsChargerRunning           bool            `json:"is_charger_running"`
+	IsLoadRunning              bool            `json:"is_load_running"`
+	States                     map[string]bool `json:"states"`
+	CycleCount                 int16           `json:"cycle_count"`
+	Time                       time.Time       `json:"time"` // when the response was read
 }
 
 // Get BMS status
 func (bms *DalyBMSIstance) GetStatus() (*StatusData, error) {
-	response, err := bms.sendReadRequest("94", "", 1, false)
+	return bms.GetStatusCtx(context.Background())
+}
+
+// GetStatus with cancellation support
+func (bms *DalyBMSIstance) GetStatusCtx(ctx context.Context) (*StatusData, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthStatus(ctx)
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandStatus, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -36,17 +50,20 @@ func (bms *DalyBMSIstance) GetStatus() (*StatusData, error) {
 	}
 
 	// Equivalent to Python struct.unpack('>b b ? ? b h x')
-	var raw struct {
+	raw := struct {
 		Cells              int8
 		TemperatureSensors int8
 		ChargerRunning     bool
 		LoadRunning        bool
 		StateBits          int8
 		CycleCount         int16
-		Skip               byte
-	}
-	if err := binary.Read(bytes.NewReader(responseBytes), binary.BigEndian, &raw); err != nil {
-		return nil, err
+	}{
+		Cells:              int8(responseBytes[0]),
+		TemperatureSensors: int8(responseBytes[1]),
+		ChargerRunning:     responseBytes[2] != 0,
+		LoadRunning:        responseBytes[3] != 0,
+		StateBits:          int8(responseBytes[4]),
+		CycleCount:         int16(binary.BigEndian.Uint16(responseBytes[5:7])),
 	}
 
 	// Interpret the individual bits in raw.StateBits
@@ -57,26 +74,42 @@ func (bms *DalyBMSIstance) GetStatus() (*StatusData, error) {
 		statesMap[stateNames[bitIndex]] = (bitValue == 1)
 	}
 
-	bms.latestStatus = &StatusData{
+	statusData := &StatusData{
 		NumberOfCells:              int(raw.Cells),
 		NumberOfTemperatureSensors: int(raw.TemperatureSensors),
 		IsChargerRunning:           raw.ChargerRunning,
 		IsLoadRunning:              raw.LoadRunning,
 		States:                     statesMap,
 		CycleCount:                 raw.CycleCount,
+		Time:                       time.Now(),
 	}
-	return bms.latestStatus, nil
+
+	bms.cacheStatus(ctx, statusData)
+	return statusData, nil
 }
 
 type SOCData struct {
-	TotalVoltage float32
-	Current      float32
-	SOCPercent   float32
+	TotalVoltage      float32     `json:"total_voltage"`
+	Current           float32     `json:"current"`
+	CurrentConvention CurrentSign `json:"current_convention"` // sign of Current, see WithCurrentSignConvention()
+	SOCPercent        float32     `json:"soc_percent"`
+	Time              time.Time   `json:"time"` // when the response was read
 }
 
 // Get State of Charge
 func (bms *DalyBMSIstance) GetSOC() (*SOCData, error) {
-	response, err := bms.sendReadRequest("90", "", 1, false)
+	return bms.GetSOCCtx(context.Background())
+}
+
+// GetSOC with cancellation support
+func (bms *DalyBMSIstance) GetSOCCtx(ctx context.Context) (*SOCData, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthSOC(ctx)
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandSOC, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -94,29 +127,44 @@ func (bms *DalyBMSIstance) GetSOC() (*SOCData, error) {
 
 	// struct.unpack('>h h h h') => 4 big-endian int16
 	var raw [4]int16
-	if err := binary.Read(bytes.NewReader(responseBytes), binary.BigEndian, &raw); err != nil {
-		return nil, err
+	for index := range raw {
+		raw[index] = int16(binary.BigEndian.Uint16(responseBytes[index*2 : index*2+2]))
 	}
 
 	socData := &SOCData{
 		TotalVoltage: float32(raw[0]) / 10.0,
 		Current:      float32(raw[2]-30000) / 10.0,
 		SOCPercent:   float32(raw[3]) / 10.0,
+		Time:         time.Now(),
 	}
 
-	return socData, nil
+	return bms.roundSOC(bms.applyCurrentSign(socData), dalyResolution), nil
 }
 
 type CellVoltageRangeData struct {
-	HighestVoltage float32
-	HighestCell    int8
-	LowestVoltage  float32
-	LowestCell     int8
+	HighestVoltage   float32   `json:"highest_voltage"`
+	HighestCell      int8      `json:"highest_cell"`
+	HighestCellLabel string    `json:"highest_cell_label,omitempty"`
+	LowestVoltage    float32   `json:"lowest_voltage"`
+	LowestCell       int8      `json:"lowest_cell"`
+	LowestCellLabel  string    `json:"lowest_cell_label,omitempty"`
+	Time             time.Time `json:"time"` // when the response was read
 }
 
 // Get highest/lowest cell voltages
 func (bms *DalyBMSIstance) GetCellVoltageRange() (*CellVoltageRangeData, error) {
-	response, err := bms.sendReadRequest("91", "", 1, false)
+	return bms.GetCellVoltageRangeCtx(context.Background())
+}
+
+// GetCellVoltageRange with cancellation support
+func (bms *DalyBMSIstance) GetCellVoltageRangeCtx(ctx context.Context) (*CellVoltageRangeData, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthCellVoltageRange(ctx)
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandCellVoltageRange, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -133,37 +181,55 @@ func (bms *DalyBMSIstance) GetCellVoltageRange() (*CellVoltageRangeData, error)
 	}
 
 	// struct.unpack('>h b h b 2x')
-	var raw struct {
+	raw := struct {
 		HighestVoltageRaw int16
 		HighestCellID     int8
 		LowestVoltageRaw  int16
 		LowestCellID      int8
-		Skipped           [2]byte
-	}
-	if err := binary.Read(bytes.NewReader(responseBytes), binary.BigEndian, &raw); err != nil {
-		return nil, err
+	}{
+		HighestVoltageRaw: int16(binary.BigEndian.Uint16(responseBytes[0:2])),
+		HighestCellID:     int8(responseBytes[2]),
+		LowestVoltageRaw:  int16(binary.BigEndian.Uint16(responseBytes[3:5])),
+		LowestCellID:      int8(responseBytes[5]),
 	}
 
 	cellVoltageRangeData := &CellVoltageRangeData{
-		HighestVoltage: float32(raw.HighestVoltageRaw) / 1000.0,
-		HighestCell:    raw.HighestCellID,
-		LowestVoltage:  float32(raw.LowestVoltageRaw) / 1000.0,
-		LowestCell:     raw.LowestCellID,
+		HighestVoltage:   float32(raw.HighestVoltageRaw) / 1000.0,
+		HighestCell:      raw.HighestCellID,
+		HighestCellLabel: bms.CellLabel(int(raw.HighestCellID)),
+		LowestVoltage:    float32(raw.LowestVoltageRaw) / 1000.0,
+		LowestCell:       raw.LowestCellID,
+		LowestCellLabel:  bms.CellLabel(int(raw.LowestCellID)),
+		Time:             time.Now(),
 	}
 
-	return cellVoltageRangeData, nil
+	return bms.roundCellVoltageRange(cellVoltageRangeData, dalyResolution), nil
 }
 
 type TemperatureRangeData struct {
-	HighestTemperature float32
-	HighestSensor      int8
-	LowestTemperature  float32
-	LowestSensor       int8
+	HighestTemperature float32   `json:"highest_temperature"`
+	HighestSensor      int8      `json:"highest_sensor"`
+	LowestTemperature  float32   `json:"lowest_temperature"`
+	LowestSensor       int8      `json:"lowest_sensor"`
+	Time               time.Time `json:"time"` // when the response was read
+
+	Unit TemperatureUnit `json:"unit"` // see WithTemperatureUnit()
 }
 
 // Get overall highest/lowest temperature info
 func (bms *DalyBMSIstance) GetTemperatureRange() (*TemperatureRangeData, error) {
-	response, err := bms.sendReadRequest("92", "", 1, false)
+	return bms.GetTemperatureRangeCtx(context.Background())
+}
+
+// GetTemperatureRange with cancellation support
+func (bms *DalyBMSIstance) GetTemperatureRangeCtx(ctx context.Context) (*TemperatureRangeData, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthTemperatureRange(ctx)
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandTemperatureRange, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -180,15 +246,16 @@ func (bms *DalyBMSIstance) GetTemperatureRange() (*TemperatureRangeData, error)
 	}
 
 	// struct.unpack('>b b b b 4x')
-	var raw struct {
+	raw := struct {
 		HighestTemperatureRaw int8
 		HighestSensor         int8
 		LowestTemperatureRaw  int8
 		LowestSensor          int8
-		Skipped               [4]byte
-	}
-	if err := binary.Read(bytes.NewReader(responseBytes), binary.BigEndian, &raw); err != nil {
-		return nil, err
+	}{
+		HighestTemperatureRaw: int8(responseBytes[0]),
+		HighestSensor:         int8(responseBytes[1]),
+		LowestTemperatureRaw:  int8(responseBytes[2]),
+		LowestSensor:          int8(responseBytes[3]),
 	}
 
 	temperatureRangeData := &TemperatureRangeData{
@@ -196,21 +263,34 @@ func (bms *DalyBMSIstance) GetTemperatureRange() (*TemperatureRangeData, error)
 		HighestSensor:      raw.HighestSensor,
 		LowestTemperature:  float32(raw.LowestTemperatureRaw) - 40.0,
 		LowestSensor:       raw.LowestSensor,
+		Time:               time.Now(),
 	}
 
-	return temperatureRangeData, nil
+	return bms.convertTemperatureRange(bms.roundTemperatureRange(temperatureRangeData, dalyResolution)), nil
 }
 
 type MosfetStatusData struct {
-	Mode              string
-	ChargingMosfet    bool
-	DischargingMosfet bool
-	CapacityAh        float32
+	Mode              string    `json:"mode"`
+	ChargingMosfet    bool      `json:"charging_mosfet"`
+	DischargingMosfet bool      `json:"discharging_mosfet"`
+	CapacityAh        float32   `json:"capacity_ah"`
+	Time              time.Time `json:"time"` // when the response was read
 }
 
 // Get MOSFET charging/discharging status
 func (bms *DalyBMSIstance) GetMosfetStatus() (*MosfetStatusData, error) {
-	response, err := bms.sendReadRequest("93", "", 1, false)
+	return bms.GetMosfetStatusCtx(context.Background())
+}
+
+// GetMosfetStatus with cancellation support
+func (bms *DalyBMSIstance) GetMosfetStatusCtx(ctx context.Context) (*MosfetStatusData, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthMosfetStatus(ctx)
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandMosfetStatus, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -227,15 +307,16 @@ func (bms *DalyBMSIstance) GetMosfetStatus() (*MosfetStatusData, error) {
 	}
 
 	// struct.unpack('>b ? ? B l') => int8, bool, bool, uint8, int32
-	var raw struct {
+	raw := struct {
 		ModeRaw           int8
 		ChargingMosfet    bool
 		DischargingMosfet bool
-		UnusedByte        uint8
 		CapacityRaw       int32
-	}
-	if err := binary.Read(bytes.NewReader(responseBytes), binary.BigEndian, &raw); err != nil {
-		return nil, err
+	}{
+		ModeRaw:           int8(responseBytes[0]),
+		ChargingMosfet:    responseBytes[1] != 0,
+		DischargingMosfet: responseBytes[2] != 0,
+		CapacityRaw:       int32(binary.BigEndian.Uint32(responseBytes[4:8])),
 	}
 
 	modeText := "discharging"
@@ -250,19 +331,33 @@ func (bms *DalyBMSIstance) GetMosfetStatus() (*MosfetStatusData, error) {
 		ChargingMosfet:    raw.ChargingMosfet,
 		DischargingMosfet: raw.DischargingMosfet,
 		CapacityAh:        float32(raw.CapacityRaw) / 1000.0,
+		Time:              time.Now(),
 	}
 
-	return mosfetStatusData, nil
+	bms.detectMosfetActions(ctx, mosfetStatusData)
+	return bms.roundMosfetStatus(mosfetStatusData, dalyResolution), nil
 }
 
-// Get individual cell voltages in a map[cellIndex] = voltage
+// Get individual cell voltages in a map[cellIndex] = voltage. When frames are lost the cells
+// received are returned with a *MissingFramesError.
 func (bms *DalyBMSIstance) GetCellVoltages() (map[int]float64, error) {
-	maxResp, err := bms.calculateNumberOfResponses("cells", 3)
+	return bms.GetCellVoltagesCtx(context.Background())
+}
+
+// GetCellVoltages with cancellation support
+func (bms *DalyBMSIstance) GetCellVoltagesCtx(ctx context.Context) (map[int]float64, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthCellVoltages(ctx)
+	}
+
+	maxResp, err := bms.calculateNumberOfResponses(ctx, "cells", 3)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := bms.sendReadRequest("95", "", maxResp, true)
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandCellVoltages, nil, maxResp, true)
 	if err != nil {
 		return nil, err
 	}
@@ -281,8 +376,9 @@ func (bms *DalyBMSIstance) GetCellVoltages() (map[int]float64, error) {
 		}
 	}
 
-	parsedValues, err := bms.splitFramesForData(dataFrames, "cells", 3)
-	if err != nil {
+	// with missing frames the other values are kept, along with the error
+	parsedValues, err := bms.splitFramesForData(ctx, dataFrames, "cells", 3)
+	if parsedValues == nil {
 		return nil, err
 	}
 
@@ -290,17 +386,30 @@ func (bms *DalyBMSIstance) GetCellVoltages() (map[int]float64, error) {
 	for index, millivolts := range parsedValues {
 		parsedValues[index] = millivolts / 1000.0
 	}
-	return parsedValues, nil
+	return bms.roundCellVoltages(parsedValues, dalyResolution), err
 }
 
-// Get temperature sensor values in a map[sensorIndex] = temperature
+// Get temperature sensor values in a map[sensorIndex] = temperature. When frames are lost the
+// sensors received are returned with a *MissingFramesError.
 func (bms *DalyBMSIstance) GetTemperatures() (map[int]float64, error) {
-	maxResp, err := bms.calculateNumberOfResponses("temperature_sensors", 7)
+	return bms.GetTemperaturesCtx(context.Background())
+}
+
+// GetTemperatures with cancellation support
+func (bms *DalyBMSIstance) GetTemperaturesCtx(ctx context.Context) (map[int]float64, error) {
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		temperatures, err := bms.sinowealthTemperatures(ctx)
+		return bms.convertTemperatures(temperatures), err
+	}
+
+	maxResp, err := bms.calculateNumberOfResponses(ctx, "temperature_sensors", 7)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := bms.sendReadRequest("96", "", maxResp, true)
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandTemperatures, nil, maxResp, true)
 	if err != nil {
 		return nil, err
 	}
@@ -318,8 +427,9 @@ func (bms *DalyBMSIstance) GetTemperatures() (map[int]float64, error) {
 		}
 	}
 
-	parsedValues, err := bms.splitFramesForData(dataFrames, "temperature_sensors", 7)
-	if err != nil {
+	// with missing frames the other values are kept, along with the error
+	parsedValues, err := bms.splitFramesForData(ctx, dataFrames, "temperature_sensors", 7)
+	if parsedValues == nil {
 		return nil, err
 	}
 
@@ -327,12 +437,17 @@ func (bms *DalyBMSIstance) GetTemperatures() (map[int]float64, error) {
 	for index, rawValue := range parsedValues {
 		parsedValues[index] = rawValue - 40.0
 	}
-	return parsedValues, nil
+	return bms.convertTemperatures(bms.roundTemperatures(parsedValues, dalyResolution)), err
 }
 
 // Get cell balancing (on/off) for each cell in a map[cellIndex] = isBalancing
 func (bms *DalyBMSIstance) GetBalancingStatus() (map[int]bool, error) {
-	response, err := bms.sendReadRequest("97", "", 1, false)
+	return bms.GetBalancingStatusCtx(context.Background())
+}
+
+// GetBalancingStatus with cancellation support
+func (bms *DalyBMSIstance) GetBalancingStatusCtx(ctx context.Context) (map[int]bool, error) {
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandBalancingStatus, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -346,36 +461,20 @@ func (bms *DalyBMSIstance) GetBalancingStatus() (map[int]bool, error) {
 	}
 
 	numberOfCells := 0
-	if bms.latestStatus != nil {
-		numberOfCells = bms.latestStatus.NumberOfCells
-	}
-	balancingMap := make(map[int]bool)
-
-	// convert entire response to a single big-endian integer, then interpret bits from the right side.
-	bigIntValue := bigEndianToUint64(responseBytes)
-	binaryString := fmt.Sprintf("%b", bigIntValue)
-	// pad to at least 48 bits (like the Python code did zfill(48))
-	for len(binaryString) < 48 {
-		binaryString = "0" + binaryString
-	}
-
-	// for each cell from 1..n, check the bit from the right.
-	// python code uses bits[-cellIndex].
-	for cellIndex := 1; cellIndex <= numberOfCells; cellIndex++ {
-		bitPosition := len(binaryString) - cellIndex
-		if bitPosition < 0 {
-			// no more bits to read
-			break
-		}
-		balancingMap[cellIndex] = (binaryString[bitPosition] == '1')
+	if status := bms.cachedStatus(ctx); status != nil {
+		numberOfCells = status.NumberOfCells
 	}
+	return decodeBalancing(responseBytes, numberOfCells), nil
+}
 
-	return balancingMap, nil
+// Get the active error flags from the BMS, Strings() lists their messages
+func (bms *DalyBMSIstance) GetErrors() (BMSErrors, error) {
+	return bms.GetErrorsCtx(context.Background())
 }
 
-// Get errors from the BMS
-func (bms *DalyBMSIstance) GetErrors() ([]string, error) {
-	response, err := bms.sendReadRequest("98", "", 1, false)
+// GetErrors with cancellation support
+func (bms *DalyBMSIstance) GetErrorsCtx(ctx context.Context) (BMSErrors, error) {
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandErrors, nil, 1, false)
 	if err != nil {
 		return nil, err
 	}
@@ -397,96 +496,136 @@ func (bms *DalyBMSIstance) GetErrors() ([]string, error) {
 		}
 	}
 	if isAllZero {
-		return []string{}, nil
+		bms.trackErrors(ctx, nil)
+		return BMSErrors{}, nil
 	}
 
-	var foundErrors []string
+	foundErrors := BMSErrors{}
 	for byteIndex, singleByte := range responseBytes {
-		if singleByte == 0 {
-			continue
-		}
-		// Check each bit in this byte
 		for bitPos := 0; bitPos < 8; bitPos++ {
-			bitMask := byte(1 << bitPos)
-			if (singleByte & bitMask) != 0 {
-				// The Python code looks up dalyErrorCodes[byteIndex][bitPos]
-				if errorList, ok := DalyErrorCodes[byteIndex]; ok {
-					if bitPos < len(errorList) {
-						foundErrors = append(foundErrors, errorList[bitPos])
-					} else {
-						foundErrors = append(foundErrors,
-							fmt.Sprintf("Unknown error code at byte=%d bit=%d", byteIndex, bitPos))
-					}
-				} else {
-					foundErrors = append(foundErrors,
-						fmt.Sprintf("Unknown error code at byte=%d bit=%d", byteIndex, bitPos))
+			if singleByte&(1<<bitPos) != 0 {
+				bmsError := newBMSError(byteIndex, bitPos)
+				if message := bms.errorMessage(byteIndex, bitPos); message != "" {
+					bmsError.Message = message
 				}
+				foundErrors = append(foundErrors, bmsError)
 			}
 		}
 	}
+	bms.trackErrors(ctx, foundErrors.Strings())
 	return foundErrors, nil
 }
 
+// SchemaVersion of the JSON form of AllBMSData, see the README. It changes when a field is renamed,
+// removed or changes unit or sign, not when one is added. 2: soc.current follows soc.current_convention.
+const SchemaVersion = 2
+
 type AllBMSData struct {
-	SOC              *SOCData
-	CellVoltageRange *CellVoltageRangeData
-	TemperatureRange *TemperatureRangeData
-	MosfetStatus     *MosfetStatusData
-	Status           *StatusData
-	CellVoltages     map[int]float64
-	Temperatures     map[int]float64
-	BalancingStatus  map[int]bool
-	Errors           []string
-}
-
-// Get all data in one call
+	SchemaVersion    int                   `json:"schema_version"`
+	Time             time.Time             `json:"time"`                 // when the read started
+	ReadTimes        map[string]time.Time  `json:"read_times,omitempty"` // when each field was read, by JSON name
+	SOC              *SOCData              `json:"soc"`
+	CellVoltageRange *CellVoltageRangeData `json:"cell_voltage_range"`
+	TemperatureRange *TemperatureRangeData `json:"temperature_range"`
+	MosfetStatus     *MosfetStatusData     `json:"mosfet_status"`
+	Status           *StatusData           `json:"status"`
+	CellVoltages     map[int]float64       `json:"cell_voltages"`
+	Temperatures     map[int]float64       `json:"temperatures"`
+	TemperatureUnit  TemperatureUnit       `json:"temperature_unit"`          // of the temperature fields, see WithTemperatureUnit()
+	MOSTemperature   *float64              `json:"mos_temperature,omitempty"` // see WithMOSTemperatureSensor()
+	BalancingStatus  map[int]bool          `json:"balancing_status"`
+	Errors           BMSErrors             `json:"errors"`
+	CellLabels       map[int]string        `json:"cell_labels,omitempty"` // physical label of each cell, see SetCellMap()
+	Board            int                   `json:"board,omitempty"`       // board number, see GetBoardData()
+	Rounded          bool                  `json:"rounded,omitempty"`     // read with WithRounding(), Stats() are rounded too
+}
+
+// markRead records the read time of a field, by JSON name
+func (data *AllBMSData) markRead(field string) {
+	if data.ReadTimes == nil {
+		data.ReadTimes = make(map[string]time.Time)
+	}
+	data.ReadTimes[field] = time.Now()
+}
+
+// Get all data in one call. The bus is held for the whole sequence, so the samples are
+// consistent and the read buffer is drained only once.
 func (bms *DalyBMSIstance) GetAllData() (*AllBMSData, error) {
-	socData, socErr := bms.GetSOC()
+	return bms.GetAllDataCtx(context.Background())
+}
+
+// GetAllData with cancellation support
+func (bms *DalyBMSIstance) GetAllDataCtx(ctx context.Context) (*AllBMSData, error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return bms.sinowealthAllData(ctx)
+	}
+
+	startTime := time.Now()
+	readTimes := make(map[string]time.Time)
+
+	socData, socErr := bms.GetSOCCtx(ctx)
 	if socErr != nil {
 		return nil, socErr
 	}
+	readTimes["soc"] = time.Now()
 
-	voltageRangeData, voltageRangeErr := bms.GetCellVoltageRange()
+	voltageRangeData, voltageRangeErr := bms.GetCellVoltageRangeCtx(ctx)
 	if voltageRangeErr != nil {
 		return nil, voltageRangeErr
 	}
+	readTimes["cell_voltage_range"] = time.Now()
 
-	temperatureRangeData, temperatureRangeErr := bms.GetTemperatureRange()
+	temperatureRangeData, temperatureRangeErr := bms.GetTemperatureRangeCtx(ctx)
 	if temperatureRangeErr != nil {
 		return nil, temperatureRangeErr
 	}
+	readTimes["temperature_range"] = time.Now()
 
-	mosfetStatusData, mosfetStatusErr := bms.GetMosfetStatus()
+	mosfetStatusData, mosfetStatusErr := bms.GetMosfetStatusCtx(ctx)
 	if mosfetStatusErr != nil {
 		return nil, mosfetStatusErr
 	}
+	readTimes["mosfet_status"] = time.Now()
 
-	statusData, statusErr := bms.GetStatus()
+	statusData, statusErr := bms.GetStatusCtx(ctx)
 	if statusErr != nil {
 		return nil, statusErr
 	}
+	readTimes["status"] = time.Now()
 
-	individualCellVoltages, cellVoltErr := bms.GetCellVoltages()
+	individualCellVoltages, cellVoltErr := bms.GetCellVoltagesCtx(ctx)
 	if cellVoltErr != nil {
 		return nil, cellVoltErr
 	}
+	readTimes["cell_voltages"] = time.Now()
 
-	temperatureSensors, tempErr := bms.GetTemperatures()
+	temperatureSensors, tempErr := bms.GetTemperaturesCtx(ctx)
 	if tempErr != nil {
 		return nil, tempErr
 	}
+	readTimes["temperatures"] = time.Now()
 
-	balancingInfo, balErr := bms.GetBalancingStatus()
+	balancingInfo, balErr := bms.GetBalancingStatusCtx(ctx)
 	if balErr != nil {
 		return nil, balErr
 	}
+	readTimes["balancing_status"] = time.Now()
 
-	errorsList, errorsErr := bms.GetErrors()
+	errorsList, errorsErr := bms.GetErrorsCtx(ctx)
 	if errorsErr != nil {
 		return nil, errorsErr
 	}
+	readTimes["errors"] = time.Now()
 
 	allBmsData := &AllBMSData{
+		SchemaVersion:    SchemaVersion,
+		Time:             startTime,
+		ReadTimes:        readTimes,
 		SOC:              socData,
 		CellVoltageRange: voltageRangeData,
 		TemperatureRange: temperatureRangeData,
@@ -494,51 +633,178 @@ func (bms *DalyBMSIstance) GetAllData() (*AllBMSData, error) {
 		Status:           statusData,
 		CellVoltages:     individualCellVoltages,
 		Temperatures:     temperatureSensors,
+		MOSTemperature:   bms.mosTemperature(temperatureSensors),
 		BalancingStatus:  balancingInfo,
 		Errors:           errorsList,
+		CellLabels:       bms.cellLabels(statusData.NumberOfCells),
+		TemperatureUnit:  bms.temperatureUnit,
+		Rounded:          bms.rounding,
 	}
 
 	return allBmsData, nil
 }
 
+// Get all data, keeping whatever succeeded. Failed fields are nil and their errors are
+// returned by JSON field name, eg "cell_voltages". The map is empty when everything succeeded.
+func (bms *DalyBMSIstance) GetAllDataPartial() (*AllBMSData, map[string]error) {
+	return bms.GetAllDataPartialCtx(context.Background())
+}
+
+// GetAllDataPartial with cancellation support. Remaining fields fail once ctx is done.
+func (bms *DalyBMSIstance) GetAllDataPartialCtx(ctx context.Context) (*AllBMSData, map[string]error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	allBmsData := &AllBMSData{SchemaVersion: SchemaVersion, Time: time.Now(), TemperatureUnit: bms.temperatureUnit, Rounded: bms.rounding}
+	fieldErrors := make(map[string]error)
+	record := func(field string, err error) {
+		if err != nil {
+			fieldErrors[field] = err
+			return
+		}
+		allBmsData.markRead(field)
+	}
+
+	var err error
+	allBmsData.SOC, err = bms.GetSOCCtx(ctx)
+	record("soc", err)
+
+	allBmsData.CellVoltageRange, err = bms.GetCellVoltageRangeCtx(ctx)
+	record("cell_voltage_range", err)
+
+	allBmsData.TemperatureRange, err = bms.GetTemperatureRangeCtx(ctx)
+	record("temperature_range", err)
+
+	allBmsData.MosfetStatus, err = bms.GetMosfetStatusCtx(ctx)
+	record("mosfet_status", err)
+
+	// cells and temperatures fall back to a previously cached status when this one fails
+	allBmsData.Status, err = bms.GetStatusCtx(ctx)
+	record("status", err)
+
+	allBmsData.CellVoltages, err = bms.GetCellVoltagesCtx(ctx)
+	record("cell_voltages", err)
+
+	allBmsData.Temperatures, err = bms.GetTemperaturesCtx(ctx)
+	record("temperatures", err)
+	allBmsData.MOSTemperature = bms.mosTemperature(allBmsData.Temperatures)
+
+	allBmsData.BalancingStatus, err = bms.GetBalancingStatusCtx(ctx)
+	record("balancing_status", err)
+
+	allBmsData.Errors, err = bms.GetErrorsCtx(ctx)
+	record("errors", err)
+
+	if allBmsData.Status != nil {
+		allBmsData.CellLabels = bms.cellLabels(allBmsData.Status.NumberOfCells)
+	}
+	return allBmsData, fieldErrors
+}
+
 // Enable charge MOSFET switch (if on, the BMS will allow charging)
 func (bms *DalyBMSIstance) EnableChargeMosfet(isOn bool) error {
-	extraBytesHex := "00"
+	return bms.EnableChargeMosfetCtx(context.Background(), isOn)
+}
+
+// EnableChargeMosfet with cancellation support
+func (bms *DalyBMSIstance) EnableChargeMosfetCtx(ctx context.Context, isOn bool) error {
+	var state byte
 	if isOn {
-		extraBytesHex = "01"
+		state = 1
 	}
 
-	response, err := bms.sendReadRequest("da", extraBytesHex, 1, false)
+	// a switch-off is never delayed, it may be a safety cutoff
+	if isOn {
+		if err := bms.limitControl(ctx, protocol.CommandSetChargeMosfet, []byte{state}); err != nil {
+			return err
+		}
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandSetChargeMosfet, []byte{state}, 1, false)
 	if err != nil {
 		return err
 	}
 	if response == nil {
 		return fmt.Errorf("no response from EnableChargeMosfet")
 	}
-	log.Printf("EnableChargeMosfet response: %x\n", response)
-	return nil
+	bms.logf("EnableChargeMosfet response: %x\n", response)
+	return bms.verifyMosfet(ctx, "EnableChargeMosfet", true, isOn)
 }
 
 // Enable discharge MOSFET switch (if on, the BMS will allow discharging)
 func (bms *DalyBMSIstance) EnableDischargeMosfet(isOn bool) error {
-	extraBytesHex := "00"
+	return bms.EnableDischargeMosfetCtx(context.Background(), isOn)
+}
+
+// EnableDischargeMosfet with cancellation support
+func (bms *DalyBMSIstance) EnableDischargeMosfetCtx(ctx context.Context, isOn bool) error {
+	var state byte
 	if isOn {
-		extraBytesHex = "01"
+		state = 1
 	}
 
-	response, err := bms.sendReadRequest("d9", extraBytesHex, 1, false)
+	// a switch-off is never delayed, it may be a safety cutoff
+	if isOn {
+		if err := bms.limitControl(ctx, protocol.CommandSetDischargeMosfet, []byte{state}); err != nil {
+			return err
+		}
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandSetDischargeMosfet, []byte{state}, 1, false)
 	if err != nil {
 		return err
 	}
 	if response == nil {
 		return fmt.Errorf("no response from EnableDischargeMosfet")
 	}
-	log.Printf("EnableDischargeMosfet response: %x\n", response)
-	return nil
+	bms.logf("EnableDischargeMosfet response: %x\n", response)
+	return bms.verifyMosfet(ctx, "EnableDischargeMosfet", false, isOn)
+}
+
+// Switch the charge and discharge MOSFETs to the given states, writing only those that differ from
+// the current ones so repeated calls don't toggle them. Returns the states read after the writes.
+func (bms *DalyBMSIstance) SetMosfetState(charge bool, discharge bool) (*MosfetStatusData, error) {
+	return bms.SetMosfetStateCtx(context.Background(), charge, discharge)
+}
+
+// SetMosfetState with cancellation support
+func (bms *DalyBMSIstance) SetMosfetStateCtx(ctx context.Context, charge bool, discharge bool) (*MosfetStatusData, error) {
+	// no other request can switch them between the read and the writes
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	status, err := bms.GetMosfetStatusCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.ChargingMosfet == charge && status.DischargingMosfet == discharge {
+		return status, nil
+	}
+
+	if status.ChargingMosfet != charge {
+		if err := bms.EnableChargeMosfetCtx(ctx, charge); err != nil {
+			return nil, err
+		}
+	}
+	if status.DischargingMosfet != discharge {
+		if err := bms.EnableDischargeMosfetCtx(ctx, discharge); err != nil {
+			return nil, err
+		}
+	}
+	return bms.GetMosfetStatusCtx(ctx)
 }
 
 // Set SoC percentage (0..100)
 func (bms *DalyBMSIstance) SetSOC(socPercent float64) error {
+	return bms.SetSOCCtx(context.Background(), socPercent)
+}
+
+// SetSOC with cancellation support
+func (bms *DalyBMSIstance) SetSOCCtx(ctx context.Context, socPercent float64) error {
 	rawValue := int(socPercent * 10.0)
 	if rawValue > 1000 {
 		rawValue = 1000
@@ -547,29 +813,42 @@ func (bms *DalyBMSIstance) SetSOC(socPercent float64) error {
 		rawValue = 0
 	}
 
-	// Format: '000000000000%04X'
-	extraBytesHex := fmt.Sprintf("000000000000%04X", rawValue)
+	// SOC in 0.1% in the last 2 data bytes
+	var payload [8]byte
+	binary.BigEndian.PutUint16(payload[6:8], uint16(rawValue))
 
-	response, err := bms.sendReadRequest("21", extraBytesHex, 1, false)
+	if err := bms.limitControl(ctx, protocol.CommandSetSOC, payload[:]); err != nil {
+		return err
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, protocol.CommandSetSOC, payload[:], 1, false)
 	if err != nil {
 		return err
 	}
 	if response == nil {
 		return fmt.Errorf("no response from SetSOC")
 	}
-	log.Printf("SetSOC response: %x\n", response)
-	return nil
+	bms.logf("SetSOC response: %x\n", response)
+	return bms.verifySOC(ctx, float64(rawValue)/10)
 }
 
 // Restart device. The effect may depend on device firmware.
 func (bms *DalyBMSIstance) Restart() error {
-	response, err := bms.readSerialResponse("00", "", 1, false)
+	return bms.RestartCtx(context.Background())
+}
+
+// Restart with cancellation support
+func (bms *DalyBMSIstance) RestartCtx(ctx context.Context) error {
+	response, err := bms.readSerialResponseCtx(ctx, protocol.CommandReset, nil, 1, false, nil)
 	if err != nil {
 		return err
 	}
 	if response == nil {
 		return fmt.Errorf("no response from Restart")
 	}
-	log.Printf("Restart response: %v\n", response)
+	bms.logf("Restart response: %v\n", response)
+	bms.forgetAuthentication()
 	return nil
 }
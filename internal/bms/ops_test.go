@@ -0,0 +1,188 @@
+package dalybms
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Responses of a 16S pack with 2 sensors, discharging at 12.5 A, in the frame layout of the
+// protocol. Every field has a distinct, non-zero value so a wrong offset or scale shows up.
+var goldenResponses = map[protocol.Command][]string{
+	protocol.CommandSOC:              {"a50190080214000074b30369e7"}, // 53.2 V, -12.5 A, 87.3 %
+	protocol.CommandCellVoltageRange: {"a50191080d11070ce50c000061"}, // 3.345 V cell 7, 3.301 V cell 12
+	protocol.CommandTemperatureRange: {"a501920841023a0100000000be"}, // 25 °C sensor 2, 18 °C sensor 1
+	protocol.CommandMosfetStatus:     {"a50193080201012a00033644ec"}, // discharging, both on, 210.5 Ah
+	protocol.CommandStatus:           {"a501940810020001210123009a"}, // 16 cells, 2 sensors, load on, DI1 DO2, 291 cycles
+	protocol.CommandCellVoltages: {
+		"a5019508010d020d030d110081",
+		"a5019508020cf80cf60cfd0054",
+		"a5019508030d110d010d050084",
+		"a5019508040cff0cfa0ce50049",
+		"a5019508050cee0cf30d0c005a",
+		"a5019508060d0800000000005e",
+	},
+	protocol.CommandTemperatures:    {"a5019608013a410000000000c0"}, // 18 °C, 25 °C
+	protocol.CommandBalancingStatus: {"a501970800000000000002044b"}, // cells 3 and 10
+	protocol.CommandErrors:          {"a5019808020000000100000049"}, // byte 0 bit 1, byte 4 bit 0
+}
+
+// goldenTransport answers each request with the frames recorded for its command
+type goldenTransport struct {
+	t         *testing.T
+	responses map[protocol.Command][]string
+	pending   bytes.Buffer
+}
+
+func (transport *goldenTransport) Write(data []byte) (int, error) {
+	request, err := protocol.Decode(data)
+	if err != nil {
+		transport.t.Errorf("invalid request %x: %v", data, err)
+		return len(data), nil
+	}
+	for _, frame := range transport.responses[request.Command] {
+		response, err := hex.DecodeString(frame)
+		if err != nil {
+			transport.t.Fatalf("response %s: %v", frame, err)
+		}
+		transport.pending.Write(response)
+	}
+	return len(data), nil
+}
+
+func (transport *goldenTransport) Read(data []byte) (int, error) {
+	if transport.pending.Len() == 0 {
+		time.Sleep(time.Millisecond)
+		return 0, nil
+	}
+	return transport.pending.Read(data)
+}
+
+func (transport *goldenTransport) Close() error { return nil }
+
+func newGoldenClient(t *testing.T) *DalyBMSIstance {
+	bms := NewClient()
+	if err := bms.ConnectTransport(&goldenTransport{t: t, responses: goldenResponses}); err != nil {
+		t.Fatalf("ConnectTransport: %v", err)
+	}
+	t.Cleanup(func() { bms.Disconnect() })
+	return bms
+}
+
+func TestGettersDecodeGoldenFrames(t *testing.T) {
+	bms := newGoldenClient(t)
+
+	soc, err := bms.GetSOC()
+	if err != nil {
+		t.Fatalf("GetSOC: %v", err)
+	}
+	soc.Time = time.Time{}
+	if want := (&SOCData{TotalVoltage: 53.2, Current: -12.5, SOCPercent: 87.3}); !reflect.DeepEqual(soc, want) {
+		t.Errorf("GetSOC = %+v, want %+v", soc, want)
+	}
+
+	status, err := bms.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	status.Time = time.Time{}
+	wantStatus := &StatusData{
+		NumberOfCells:              16,
+		NumberOfTemperatureSensors: 2,
+		IsChargerRunning:           false,
+		IsLoadRunning:              true,
+		States: map[string]bool{
+			"DI1": true, "DI2": false, "DI3": false, "DI4": false,
+			"DO1": false, "DO2": true, "DO3": false, "DO4": false,
+		},
+		CycleCount: 291,
+	}
+	if !reflect.DeepEqual(status, wantStatus) {
+		t.Errorf("GetStatus = %+v, want %+v", status, wantStatus)
+	}
+
+	cellRange, err := bms.GetCellVoltageRange()
+	if err != nil {
+		t.Fatalf("GetCellVoltageRange: %v", err)
+	}
+	cellRange.Time = time.Time{}
+	wantCellRange := &CellVoltageRangeData{
+		HighestVoltage:   3.345,
+		HighestCell:      7,
+		HighestCellLabel: "cell 7",
+		LowestVoltage:    3.301,
+		LowestCell:       12,
+		LowestCellLabel:  "cell 12",
+	}
+	if !reflect.DeepEqual(cellRange, wantCellRange) {
+		t.Errorf("GetCellVoltageRange = %+v, want %+v", cellRange, wantCellRange)
+	}
+
+	temperatureRange, err := bms.GetTemperatureRange()
+	if err != nil {
+		t.Fatalf("GetTemperatureRange: %v", err)
+	}
+	temperatureRange.Time = time.Time{}
+	wantTemperatureRange := &TemperatureRangeData{HighestTemperature: 25, HighestSensor: 2, LowestTemperature: 18, LowestSensor: 1, Unit: Celsius}
+	if !reflect.DeepEqual(temperatureRange, wantTemperatureRange) {
+		t.Errorf("GetTemperatureRange = %+v, want %+v", temperatureRange, wantTemperatureRange)
+	}
+
+	mosfet, err := bms.GetMosfetStatus()
+	if err != nil {
+		t.Fatalf("GetMosfetStatus: %v", err)
+	}
+	mosfet.Time = time.Time{}
+	wantMosfet := &MosfetStatusData{Mode: "discharging", ChargingMosfet: true, DischargingMosfet: true, CapacityAh: 210.5}
+	if !reflect.DeepEqual(mosfet, wantMosfet) {
+		t.Errorf("GetMosfetStatus = %+v, want %+v", mosfet, wantMosfet)
+	}
+
+	cellVoltages, err := bms.GetCellVoltages()
+	if err != nil {
+		t.Fatalf("GetCellVoltages: %v", err)
+	}
+	wantCellVoltages := map[int]float64{
+		1: 3.330, 2: 3.331, 3: 3.345, 4: 3.320, 5: 3.318, 6: 3.325, 7: 3.345, 8: 3.329,
+		9: 3.333, 10: 3.327, 11: 3.322, 12: 3.301, 13: 3.310, 14: 3.315, 15: 3.340, 16: 3.336,
+	}
+	if !reflect.DeepEqual(cellVoltages, wantCellVoltages) {
+		t.Errorf("GetCellVoltages = %v, want %v", cellVoltages, wantCellVoltages)
+	}
+
+	temperatures, err := bms.GetTemperatures()
+	if err != nil {
+		t.Fatalf("GetTemperatures: %v", err)
+	}
+	if want := map[int]float64{1: 18, 2: 25}; !reflect.DeepEqual(temperatures, want) {
+		t.Errorf("GetTemperatures = %v, want %v", temperatures, want)
+	}
+
+	balancing, err := bms.GetBalancingStatus()
+	if err != nil {
+		t.Fatalf("GetBalancingStatus: %v", err)
+	}
+	wantBalancing := make(map[int]bool)
+	for cellIndex := 1; cellIndex <= 16; cellIndex++ {
+		wantBalancing[cellIndex] = cellIndex == 3 || cellIndex == 10
+	}
+	if !reflect.DeepEqual(balancing, wantBalancing) {
+		t.Errorf("GetBalancingStatus = %v, want %v", balancing, wantBalancing)
+	}
+
+	bmsErrors, err := bms.GetErrors()
+	if err != nil {
+		t.Fatalf("GetErrors: %v", err)
+	}
+	wantErrors := BMSErrors{
+		{Code: 1, Byte: 0, Bit: 1, Severity: SeverityAlarm, Category: CategoryVoltage, Message: protocol.ErrorCodes[0][1]},
+		{Code: 32, Byte: 4, Bit: 0, Severity: SeverityWarning, Category: CategoryMOS, Message: protocol.ErrorCodes[4][0]},
+	}
+	if !reflect.DeepEqual(bmsErrors, wantErrors) {
+		t.Errorf("GetErrors = %+v, want %+v", bmsErrors, wantErrors)
+	}
+}
@@ -0,0 +1,160 @@
+package dalybms
+
+import (
+	"log"
+	"time"
+)
+
+// Logger receives the diagnostic messages of the client. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Option configures a client created with NewClient
+type Option func(bms *DalyBMSIstance)
+
+// Source address a Daly board answers with
+const DefaultResponseAddress = 0x01
+
+// ForeignFrameHandler receives the response frames sent by another address, see WithForeignFrameHandler
+type ForeignFrameHandler func(address byte, frame []byte)
+
+// NewClient creates a client. Defaults:
+// RS485 address 4, responses accepted from address 1 only, 3 tries per request,
+// messages logged with the standard logger, last 100 error flag changes kept in memory.
+func NewClient(options ...Option) *DalyBMSIstance {
+	bms := &DalyBMSIstance{
+		retryPolicy:     DefaultRetryPolicy(),
+		address:         4, // default for RS485
+		responseAddress: DefaultResponseAddress,
+		logger:          log.Default(),
+		errorHistory:    errorHistory{size: 100}, // default
+		created:         time.Now(),
+	}
+	for _, option := range options {
+		option(bms)
+	}
+	if bms.languageError != nil {
+		bms.logf("Warning: %v", bms.languageError)
+	}
+	// after all the options, WithLanguage() and WithErrorMessages() work in any order
+	bms.errorMessages = mergeErrorMessages(bms.errorLanguage, bms.errorOverrides)
+	return bms
+}
+
+// Set the BMS address, 4 for RS485 (default) or 8 for Bluetooth
+func WithAddress(address int) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.address = address
+	}
+}
+
+// Set the source address responses must come from, DefaultResponseAddress unless the boards on
+// a multi-drop bus are numbered. Frames from other addresses are dropped, 0 accepts any address.
+func WithResponseAddress(address byte) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.responseAddress = address
+	}
+}
+
+// Pass responses from other addresses to handler instead of dropping them, eg to route them
+// to the client of another board. handler runs while the bus is held and must not call the BMS.
+func WithForeignFrameHandler(handler ForeignFrameHandler) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.foreignFrames = handler
+	}
+}
+
+// Fail requests with a *FrameError (ErrCRCMismatch, ErrHeaderMismatch) holding the offending
+// frame instead of logging and skipping it. Useful to diagnose noisy buses; retries still apply.
+func WithStrictFrames() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.strictFrames = true
+	}
+}
+
+// Treat received frames identical to the request as responses. By default they are discarded
+// as the echo of half-duplex RS485 adapters, which loop the transmitted bytes back.
+func WithKeepEcho() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.keepEcho = true
+	}
+}
+
+// Set how many times a request is tried before failing, at least 1
+func WithRetries(tries int) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.retryPolicy.MaxAttempts = max(tries, 1)
+	}
+}
+
+// Set the retry policy, see RetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.retryPolicy = policy
+	}
+}
+
+// Send diagnostic messages to a custom logger, nil discards them
+func WithLogger(logger Logger) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.logger = logger
+	}
+}
+
+// Set an overall deadline per command, retries included. A command then fails with ErrTimeout
+// after at most timeout plus one transport read timeout. 0 (default) disables it.
+func WithResponseTimeout(timeout time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.responseTimeout = timeout
+	}
+}
+
+// Enforce a minimum gap between requests, for firmware that drops a request sent right after
+// another one. Applies within GetAllData() and across goroutines too. 0 (default) disables it.
+func WithInterCommandDelay(delay time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.commandDelay = delay
+	}
+}
+
+// Keep waiting for a response after an empty transport read, up to latency since the request
+// or the latest received byte. For links that deliver data late or in bursts, such as WiFi UART
+// bridges. 0 (default) ends the response at the first empty read.
+func WithLatency(latency time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.linkLatency = latency
+	}
+}
+
+// Settings for ESPHome, ESP-Link and similar UART over WiFi bridges: 500ms latency and
+// 50ms between requests. Pair it with RunKeepAlive when the bridge drops idle connections.
+func WithWiFiBridge() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.linkLatency = 500 * time.Millisecond
+		bms.commandDelay = 50 * time.Millisecond
+	}
+}
+
+// Round readings to the resolution of the protocol (Daly: 1 mV cells, 0.1 V pack, 0.1 A, 0.1 %, 1 °C)
+// and the derived Stats to a precision the readings support, so float noise such as
+// 158.39999999999998 W doesn't reach logs and JSON.
+func WithRounding() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.rounding = true
+	}
+}
+
+// Use an already opened transport. No request is sent until the first call.
+func WithTransport(transport Transport) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.transport = transport
+	}
+}
+
+// logf logs through the configured logger
+func (bms *DalyBMSIstance) logf(format string, v ...any) {
+	if bms.logger != nil {
+		bms.logger.Printf(format, v...)
+	}
+}
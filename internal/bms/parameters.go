@@ -0,0 +1,478 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Protection parameters are read with 0x59..0x5E and written with the matching 0x19..0x1E commands.
+// Level 1 is the warning threshold, level 2 the protection (cut-off) threshold.
+// Rated parameters are read with 0x50 and written with 0x10, balance settings with 0x5F and 0x1F,
+// the battery configuration with 0x51 and 0x11.
+
+// Rated pack capacity, Ah, and nominal cell voltage, V (0x50)
+type RatedParams struct {
+	RatedCapacityAh    float32 `json:"rated_capacity_ah"`
+	NominalCellVoltage float32 `json:"nominal_cell_voltage"`
+}
+
+// Cell voltage thresholds, V (0x59)
+type VoltageThresholds struct {
+	CellHighLevel1 float32 `json:"cell_high_level_1"`
+	CellHighLevel2 float32 `json:"cell_high_level_2"`
+	CellLowLevel1  float32 `json:"cell_low_level_1"`
+	CellLowLevel2  float32 `json:"cell_low_level_2"`
+}
+
+// Pack voltage thresholds, V (0x5A)
+type PackVoltageThresholds struct {
+	HighLevel1 float32 `json:"high_level_1"`
+	HighLevel2 float32 `json:"high_level_2"`
+	LowLevel1  float32 `json:"low_level_1"`
+	LowLevel2  float32 `json:"low_level_2"`
+}
+
+// Over-current thresholds, A (0x5B). All values are positive, the BMS stores the
+// discharge ones as negative currents like GetSOC() reports discharging.
+type CurrentThresholds struct {
+	ChargeLevel1    float32 `json:"charge_level_1"`
+	ChargeLevel2    float32 `json:"charge_level_2"`
+	DischargeLevel1 float32 `json:"discharge_level_1"`
+	DischargeLevel2 float32 `json:"discharge_level_2"`
+}
+
+// Temperature thresholds, °C (0x5C for charging, 0x5D for discharging)
+type TemperatureThresholds struct {
+	HighLevel1 float32 `json:"high_level_1"`
+	HighLevel2 float32 `json:"high_level_2"`
+	LowLevel1  float32 `json:"low_level_1"`
+	LowLevel2  float32 `json:"low_level_2"`
+}
+
+// Cell voltage (V) and temperature (°C) difference thresholds (0x5E)
+type DifferenceThresholds struct {
+	VoltageLevel1     float32 `json:"voltage_level_1"`
+	VoltageLevel2     float32 `json:"voltage_level_2"`
+	TemperatureLevel1 float32 `json:"temperature_level_1"`
+	TemperatureLevel2 float32 `json:"temperature_level_2"`
+}
+
+// Get cell voltage thresholds
+func (bms *DalyBMSIstance) GetVoltageThresholds() (*VoltageThresholds, error) {
+	return bms.GetVoltageThresholdsCtx(context.Background())
+}
+
+// GetVoltageThresholds with cancellation support
+func (bms *DalyBMSIstance) GetVoltageThresholdsCtx(ctx context.Context) (*VoltageThresholds, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandCellVoltageThresholds, "get_voltage_thresholds")
+	if err != nil {
+		return nil, err
+	}
+	return &VoltageThresholds{
+		CellHighLevel1: float32(binary.BigEndian.Uint16(data[0:2])) / 1000.0,
+		CellHighLevel2: float32(binary.BigEndian.Uint16(data[2:4])) / 1000.0,
+		CellLowLevel1:  float32(binary.BigEndian.Uint16(data[4:6])) / 1000.0,
+		CellLowLevel2:  float32(binary.BigEndian.Uint16(data[6:8])) / 1000.0,
+	}, nil
+}
+
+// Set cell voltage thresholds
+func (bms *DalyBMSIstance) SetVoltageThresholds(thresholds VoltageThresholds) error {
+	return bms.SetVoltageThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetVoltageThresholds with cancellation support
+func (bms *DalyBMSIstance) SetVoltageThresholdsCtx(ctx context.Context, thresholds VoltageThresholds) error {
+	var data [8]byte
+	binary.BigEndian.PutUint16(data[0:2], scaleUnsigned(thresholds.CellHighLevel1, 1000))
+	binary.BigEndian.PutUint16(data[2:4], scaleUnsigned(thresholds.CellHighLevel2, 1000))
+	binary.BigEndian.PutUint16(data[4:6], scaleUnsigned(thresholds.CellLowLevel1, 1000))
+	binary.BigEndian.PutUint16(data[6:8], scaleUnsigned(thresholds.CellLowLevel2, 1000))
+	return bms.writeParameter(ctx, protocol.CommandSetCellVoltageThresholds, data, "SetVoltageThresholds")
+}
+
+// Get pack voltage thresholds
+func (bms *DalyBMSIstance) GetPackVoltageThresholds() (*PackVoltageThresholds, error) {
+	return bms.GetPackVoltageThresholdsCtx(context.Background())
+}
+
+// GetPackVoltageThresholds with cancellation support
+func (bms *DalyBMSIstance) GetPackVoltageThresholdsCtx(ctx context.Context) (*PackVoltageThresholds, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandPackVoltageThresholds, "get_pack_voltage_thresholds")
+	if err != nil {
+		return nil, err
+	}
+	return &PackVoltageThresholds{
+		HighLevel1: float32(binary.BigEndian.Uint16(data[0:2])) / 10.0,
+		HighLevel2: float32(binary.BigEndian.Uint16(data[2:4])) / 10.0,
+		LowLevel1:  float32(binary.BigEndian.Uint16(data[4:6])) / 10.0,
+		LowLevel2:  float32(binary.BigEndian.Uint16(data[6:8])) / 10.0,
+	}, nil
+}
+
+// Set pack voltage thresholds
+func (bms *DalyBMSIstance) SetPackVoltageThresholds(thresholds PackVoltageThresholds) error {
+	return bms.SetPackVoltageThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetPackVoltageThresholds with cancellation support
+func (bms *DalyBMSIstance) SetPackVoltageThresholdsCtx(ctx context.Context, thresholds PackVoltageThresholds) error {
+	var data [8]byte
+	binary.BigEndian.PutUint16(data[0:2], scaleUnsigned(thresholds.HighLevel1, 10))
+	binary.BigEndian.PutUint16(data[2:4], scaleUnsigned(thresholds.HighLevel2, 10))
+	binary.BigEndian.PutUint16(data[4:6], scaleUnsigned(thresholds.LowLevel1, 10))
+	binary.BigEndian.PutUint16(data[6:8], scaleUnsigned(thresholds.LowLevel2, 10))
+	return bms.writeParameter(ctx, protocol.CommandSetPackVoltageThresholds, data, "SetPackVoltageThresholds")
+}
+
+// Get charge/discharge over-current thresholds
+func (bms *DalyBMSIstance) GetCurrentThresholds() (*CurrentThresholds, error) {
+	return bms.GetCurrentThresholdsCtx(context.Background())
+}
+
+// GetCurrentThresholds with cancellation support
+func (bms *DalyBMSIstance) GetCurrentThresholdsCtx(ctx context.Context) (*CurrentThresholds, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandCurrentThresholds, "get_current_thresholds")
+	if err != nil {
+		return nil, err
+	}
+
+	// 0.1A with a 30000 offset, like the current in GetSOC()
+	decode := func(raw []byte) float32 {
+		return float32(int(binary.BigEndian.Uint16(raw))-30000) / 10.0
+	}
+	return &CurrentThresholds{
+		ChargeLevel1:    decode(data[0:2]),
+		ChargeLevel2:    decode(data[2:4]),
+		DischargeLevel1: -decode(data[4:6]),
+		DischargeLevel2: -decode(data[6:8]),
+	}, nil
+}
+
+// Set charge/discharge over-current thresholds
+func (bms *DalyBMSIstance) SetCurrentThresholds(thresholds CurrentThresholds) error {
+	return bms.SetCurrentThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetCurrentThresholds with cancellation support
+func (bms *DalyBMSIstance) SetCurrentThresholdsCtx(ctx context.Context, thresholds CurrentThresholds) error {
+	encode := func(current float32) uint16 {
+		return uint16(30000 + int(current*10))
+	}
+
+	var data [8]byte
+	binary.BigEndian.PutUint16(data[0:2], encode(thresholds.ChargeLevel1))
+	binary.BigEndian.PutUint16(data[2:4], encode(thresholds.ChargeLevel2))
+	binary.BigEndian.PutUint16(data[4:6], encode(-thresholds.DischargeLevel1))
+	binary.BigEndian.PutUint16(data[6:8], encode(-thresholds.DischargeLevel2))
+	return bms.writeParameter(ctx, protocol.CommandSetCurrentThresholds, data, "SetCurrentThresholds")
+}
+
+// Get charging temperature thresholds
+func (bms *DalyBMSIstance) GetChargeTemperatureThresholds() (*TemperatureThresholds, error) {
+	return bms.GetChargeTemperatureThresholdsCtx(context.Background())
+}
+
+// GetChargeTemperatureThresholds with cancellation support
+func (bms *DalyBMSIstance) GetChargeTemperatureThresholdsCtx(ctx context.Context) (*TemperatureThresholds, error) {
+	return bms.getTemperatureThresholds(ctx, protocol.CommandChargeTemperatureThresholds, "get_charge_temperature_thresholds")
+}
+
+// Set charging temperature thresholds
+func (bms *DalyBMSIstance) SetChargeTemperatureThresholds(thresholds TemperatureThresholds) error {
+	return bms.SetChargeTemperatureThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetChargeTemperatureThresholds with cancellation support
+func (bms *DalyBMSIstance) SetChargeTemperatureThresholdsCtx(ctx context.Context, thresholds TemperatureThresholds) error {
+	return bms.setTemperatureThresholds(ctx, protocol.CommandSetChargeTemperatureThresholds, thresholds, "SetChargeTemperatureThresholds")
+}
+
+// Get discharging temperature thresholds
+func (bms *DalyBMSIstance) GetDischargeTemperatureThresholds() (*TemperatureThresholds, error) {
+	return bms.GetDischargeTemperatureThresholdsCtx(context.Background())
+}
+
+// GetDischargeTemperatureThresholds with cancellation support
+func (bms *DalyBMSIstance) GetDischargeTemperatureThresholdsCtx(ctx context.Context) (*TemperatureThresholds, error) {
+	return bms.getTemperatureThresholds(ctx, protocol.CommandDischargeTemperatureThresholds, "get_discharge_temperature_thresholds")
+}
+
+// Set discharging temperature thresholds
+func (bms *DalyBMSIstance) SetDischargeTemperatureThresholds(thresholds TemperatureThresholds) error {
+	return bms.SetDischargeTemperatureThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetDischargeTemperatureThresholds with cancellation support
+func (bms *DalyBMSIstance) SetDischargeTemperatureThresholdsCtx(ctx context.Context, thresholds TemperatureThresholds) error {
+	return bms.setTemperatureThresholds(ctx, protocol.CommandSetDischargeTemperatureThresholds, thresholds, "SetDischargeTemperatureThresholds")
+}
+
+// Get cell voltage and temperature difference thresholds
+func (bms *DalyBMSIstance) GetDifferenceThresholds() (*DifferenceThresholds, error) {
+	return bms.GetDifferenceThresholdsCtx(context.Background())
+}
+
+// GetDifferenceThresholds with cancellation support
+func (bms *DalyBMSIstance) GetDifferenceThresholdsCtx(ctx context.Context) (*DifferenceThresholds, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandDifferenceThresholds, "get_difference_thresholds")
+	if err != nil {
+		return nil, err
+	}
+	return &DifferenceThresholds{
+		VoltageLevel1:     float32(binary.BigEndian.Uint16(data[0:2])) / 1000.0,
+		VoltageLevel2:     float32(binary.BigEndian.Uint16(data[2:4])) / 1000.0,
+		TemperatureLevel1: float32(data[4]),
+		TemperatureLevel2: float32(data[5]),
+	}, nil
+}
+
+// Set cell voltage and temperature difference thresholds
+func (bms *DalyBMSIstance) SetDifferenceThresholds(thresholds DifferenceThresholds) error {
+	return bms.SetDifferenceThresholdsCtx(context.Background(), thresholds)
+}
+
+// SetDifferenceThresholds with cancellation support
+func (bms *DalyBMSIstance) SetDifferenceThresholdsCtx(ctx context.Context, thresholds DifferenceThresholds) error {
+	var data [8]byte
+	binary.BigEndian.PutUint16(data[0:2], scaleUnsigned(thresholds.VoltageLevel1, 1000))
+	binary.BigEndian.PutUint16(data[2:4], scaleUnsigned(thresholds.VoltageLevel2, 1000))
+	data[4] = byte(scaleUnsigned(thresholds.TemperatureLevel1, 1))
+	data[5] = byte(scaleUnsigned(thresholds.TemperatureLevel2, 1))
+	return bms.writeParameter(ctx, protocol.CommandSetDifferenceThresholds, data, "SetDifferenceThresholds")
+}
+
+// Cell voltage above which balancing starts and cell difference that triggers it, V (0x5F)
+type BalanceSettings struct {
+	StartVoltage float32 `json:"start_voltage"`
+	DeltaVoltage float32 `json:"delta_voltage"`
+}
+
+// Get rated capacity and nominal cell voltage, used by the BMS for SOC calculation
+func (bms *DalyBMSIstance) GetRatedParams() (*RatedParams, error) {
+	return bms.GetRatedParamsCtx(context.Background())
+}
+
+// GetRatedParams with cancellation support
+func (bms *DalyBMSIstance) GetRatedParamsCtx(ctx context.Context) (*RatedParams, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandRatedParams, "get_rated_params")
+	if err != nil {
+		return nil, err
+	}
+	// capacity in mAh (4 bytes), nominal cell voltage in mV (2 bytes), 2 reserved bytes
+	return &RatedParams{
+		RatedCapacityAh:    float32(binary.BigEndian.Uint32(data[0:4])) / 1000.0,
+		NominalCellVoltage: float32(binary.BigEndian.Uint16(data[4:6])) / 1000.0,
+	}, nil
+}
+
+// Set rated capacity, Ah. The nominal cell voltage is read back and kept.
+func (bms *DalyBMSIstance) SetRatedCapacity(capacityAh float32) error {
+	return bms.SetRatedCapacityCtx(context.Background(), capacityAh)
+}
+
+// SetRatedCapacity with cancellation support
+func (bms *DalyBMSIstance) SetRatedCapacityCtx(ctx context.Context, capacityAh float32) error {
+	if capacityAh <= 0 {
+		return fmt.Errorf("invalid rated capacity: %v Ah", capacityAh)
+	}
+	current, err := bms.readParameter(ctx, protocol.CommandRatedParams, "get_rated_params")
+	if err != nil {
+		return err
+	}
+
+	var data [8]byte
+	copy(data[:], current)
+	binary.BigEndian.PutUint32(data[0:4], uint32(capacityAh*1000+0.5))
+	return bms.writeParameter(ctx, protocol.CommandSetRatedParams, data, "SetRatedCapacity")
+}
+
+// Get balancing start voltage and delta
+func (bms *DalyBMSIstance) GetBalanceSettings() (*BalanceSettings, error) {
+	return bms.GetBalanceSettingsCtx(context.Background())
+}
+
+// GetBalanceSettings with cancellation support
+func (bms *DalyBMSIstance) GetBalanceSettingsCtx(ctx context.Context) (*BalanceSettings, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandBalanceSettings, "get_balance_settings")
+	if err != nil {
+		return nil, err
+	}
+	return &BalanceSettings{
+		StartVoltage: float32(binary.BigEndian.Uint16(data[0:2])) / 1000.0,
+		DeltaVoltage: float32(binary.BigEndian.Uint16(data[2:4])) / 1000.0,
+	}, nil
+}
+
+// Set balancing start voltage and delta
+func (bms *DalyBMSIstance) SetBalanceSettings(settings BalanceSettings) error {
+	return bms.SetBalanceSettingsCtx(context.Background(), settings)
+}
+
+// SetBalanceSettings with cancellation support
+func (bms *DalyBMSIstance) SetBalanceSettingsCtx(ctx context.Context, settings BalanceSettings) error {
+	var data [8]byte
+	binary.BigEndian.PutUint16(data[0:2], scaleUnsigned(settings.StartVoltage, 1000))
+	binary.BigEndian.PutUint16(data[2:4], scaleUnsigned(settings.DeltaVoltage, 1000))
+	return bms.writeParameter(ctx, protocol.CommandSetBalanceSettings, data, "SetBalanceSettings")
+}
+
+// Acquisition boards and the cells/temperature sensors wired to each (0x51). Up to 3 boards.
+type BatteryConfig struct {
+	CellsPerBoard   []int `json:"cells_per_board"`
+	SensorsPerBoard []int `json:"sensors_per_board"`
+}
+
+// Total cells of all boards
+func (config *BatteryConfig) NumberOfCells() int {
+	total := 0
+	for _, cells := range config.CellsPerBoard {
+		total += cells
+	}
+	return total
+}
+
+// Total temperature sensors of all boards
+func (config *BatteryConfig) NumberOfSensors() int {
+	total := 0
+	for _, sensors := range config.SensorsPerBoard {
+		total += sensors
+	}
+	return total
+}
+
+// Get the number of cells and temperature sensors per acquisition board
+func (bms *DalyBMSIstance) GetBatteryConfig() (*BatteryConfig, error) {
+	return bms.GetBatteryConfigCtx(context.Background())
+}
+
+// GetBatteryConfig with cancellation support
+func (bms *DalyBMSIstance) GetBatteryConfigCtx(ctx context.Context) (*BatteryConfig, error) {
+	data, err := bms.readParameter(ctx, protocol.CommandBatteryConfig, "get_battery_config")
+	if err != nil {
+		return nil, err
+	}
+	// number of boards, cells of boards 1-3, sensors of boards 1-3
+	boards := min(int(data[0]), 3)
+	config := &BatteryConfig{}
+	for boardIndex := 0; boardIndex < boards; boardIndex++ {
+		config.CellsPerBoard = append(config.CellsPerBoard, int(data[1+boardIndex]))
+		config.SensorsPerBoard = append(config.SensorsPerBoard, int(data[4+boardIndex]))
+	}
+	return config, nil
+}
+
+// Set the number of cells and temperature sensors per acquisition board, eg when commissioning
+// a new board. Some boards apply it only after Restart().
+func (bms *DalyBMSIstance) SetBatteryConfig(config BatteryConfig) error {
+	return bms.SetBatteryConfigCtx(context.Background(), config)
+}
+
+// SetBatteryConfig with cancellation support
+func (bms *DalyBMSIstance) SetBatteryConfigCtx(ctx context.Context, config BatteryConfig) error {
+	boards := len(config.CellsPerBoard)
+	if boards == 0 || boards > 3 || len(config.SensorsPerBoard) != boards {
+		return fmt.Errorf("invalid battery config: 1 to 3 boards with cells and sensors for each expected")
+	}
+	var data [8]byte
+	data[0] = byte(boards)
+	for boardIndex := 0; boardIndex < boards; boardIndex++ {
+		cells, sensors := config.CellsPerBoard[boardIndex], config.SensorsPerBoard[boardIndex]
+		if cells < 1 || cells > 48 || sensors < 0 || sensors > 16 {
+			return fmt.Errorf("invalid battery config for board %d: %d cells, %d sensors", boardIndex+1, cells, sensors)
+		}
+		data[1+boardIndex] = byte(cells)
+		data[4+boardIndex] = byte(sensors)
+	}
+	if err := bms.writeParameter(ctx, protocol.CommandSetBatteryConfig, data, "SetBatteryConfig"); err != nil {
+		return err
+	}
+
+	// the cached status holds the old cell count, GetStatus() fetches the new one
+	bms.cacheStatus(ctx, nil)
+	return nil
+}
+
+// Set the number of cells (series string) and temperature sensors of a single board pack
+func (bms *DalyBMSIstance) SetNumberOfCells(cells int, sensors int) error {
+	return bms.SetNumberOfCellsCtx(context.Background(), cells, sensors)
+}
+
+// SetNumberOfCells with cancellation support
+func (bms *DalyBMSIstance) SetNumberOfCellsCtx(ctx context.Context, cells int, sensors int) error {
+	return bms.SetBatteryConfigCtx(ctx, BatteryConfig{CellsPerBoard: []int{cells}, SensorsPerBoard: []int{sensors}})
+}
+
+// temperatures are raw_value - 40, one byte each
+func (bms *DalyBMSIstance) getTemperatureThresholds(ctx context.Context, command protocol.Command, operation string) (*TemperatureThresholds, error) {
+	data, err := bms.readParameter(ctx, command, operation)
+	if err != nil {
+		return nil, err
+	}
+	return &TemperatureThresholds{
+		HighLevel1: float32(data[0]) - 40.0,
+		HighLevel2: float32(data[1]) - 40.0,
+		LowLevel1:  float32(data[2]) - 40.0,
+		LowLevel2:  float32(data[3]) - 40.0,
+	}, nil
+}
+
+func (bms *DalyBMSIstance) setTemperatureThresholds(ctx context.Context, command protocol.Command, thresholds TemperatureThresholds, operation string) error {
+	var data [8]byte
+	data[0] = byte(scaleUnsigned(thresholds.HighLevel1+40, 1))
+	data[1] = byte(scaleUnsigned(thresholds.HighLevel2+40, 1))
+	data[2] = byte(scaleUnsigned(thresholds.LowLevel1+40, 1))
+	data[3] = byte(scaleUnsigned(thresholds.LowLevel2+40, 1))
+	return bms.writeParameter(ctx, command, data, operation)
+}
+
+// readParameter reads the 8 data bytes of a parameter register
+func (bms *DalyBMSIstance) readParameter(ctx context.Context, command protocol.Command, operation string) ([]byte, error) {
+	response, err := bms.sendReadRequestCtx(ctx, command, nil, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, fmt.Errorf("no data for %s", operation)
+	}
+
+	responseBytes, ok := response.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type for %s", operation)
+	}
+	if len(responseBytes) < 8 {
+		return nil, fmt.Errorf("insufficient data length for %s", operation)
+	}
+	return responseBytes, nil
+}
+
+// writeParameter writes the 8 data bytes of a parameter register
+func (bms *DalyBMSIstance) writeParameter(ctx context.Context, command protocol.Command, data [8]byte, operation string) error {
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+	response, err := bms.sendReadRequestCtx(ctx, command, data[:], 1, false)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("no response from %s", operation)
+	}
+	bms.logf("%s response: %x\n", operation, response)
+	return nil
+}
+
+// scaleUnsigned converts a value to its raw unsigned representation, rounding and clamping to 0..65535
+func scaleUnsigned(value float32, scale float32) uint16 {
+	raw := value*scale + 0.5
+	if raw < 0 {
+		return 0
+	}
+	if raw > 65535 {
+		return 65535
+	}
+	return uint16(raw)
+}
@@ -0,0 +1,244 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ConnectFunc (re)connects the BMS, used by the poller after a failure
+type ConnectFunc func(ctx context.Context, bms *DalyBMSIstance) error
+
+// SerialConnector returns a ConnectFunc opening the given serial device
+func SerialConnector(serialDevicePath string) ConnectFunc {
+	return SerialConnectorWithConfig(serialDevicePath, DefaultSerialConfig())
+}
+
+// SerialConnectorWithConfig returns a ConnectFunc opening the given serial device with custom settings
+func SerialConnectorWithConfig(serialDevicePath string, config SerialConfig) ConnectFunc {
+	return func(ctx context.Context, bms *DalyBMSIstance) error {
+		return bms.ConnectWithConfigCtx(ctx, serialDevicePath, config)
+	}
+}
+
+// Single poll outcome. Data is nil when Err is set.
+type PollResult struct {
+	Data *AllBMSData
+	Err  error
+	Time time.Time
+}
+
+// pollResultJSON is the wire form of PollResult, errors are serialized as their message
+type pollResultJSON struct {
+	Data  *AllBMSData `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+func (result PollResult) MarshalJSON() ([]byte, error) {
+	wireResult := pollResultJSON{Data: result.Data, Time: result.Time}
+	if result.Err != nil {
+		wireResult.Error = result.Err.Error()
+	}
+	return json.Marshal(wireResult)
+}
+
+func (result *PollResult) UnmarshalJSON(data []byte) error {
+	var wireResult pollResultJSON
+	if err := json.Unmarshal(data, &wireResult); err != nil {
+		return err
+	}
+
+	*result = PollResult{Data: wireResult.Data, Time: wireResult.Time}
+	if wireResult.Error != "" {
+		result.Err = errors.New(wireResult.Error)
+	}
+	return nil
+}
+
+// Poller samples the BMS at a fixed interval, reconnecting after failures
+type Poller struct {
+	bms            *DalyBMSIstance
+	connect        ConnectFunc
+	Interval       time.Duration
+	ReconnectDelay time.Duration
+
+	// Optional interval per group, eg {PollSOC: time.Second, PollCells: 10 * time.Second}.
+	// Groups not listed use Interval. Each result then holds the groups due at that time merged
+	// with the latest values of the others, so a slow bus can follow the current closely.
+	GroupIntervals map[PollGroup]time.Duration
+
+	mutex       sync.Mutex
+	subscribers []chan PollResult
+	callbacks   []func(PollResult)
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewPoller creates a poller. A nil connect polls the current connection only: once it fails,
+// every reconnect attempt publishes an error.
+func NewPoller(bms *DalyBMSIstance, connect ConnectFunc, interval time.Duration) *Poller {
+	if connect == nil {
+		connect = noReconnect
+	}
+	return &Poller{
+		bms:            bms,
+		connect:        connect,
+		Interval:       interval,
+		ReconnectDelay: 1 * time.Second, // default
+	}
+}
+
+// noReconnect is the ConnectFunc of pollers created without one
+func noReconnect(ctx context.Context, bms *DalyBMSIstance) error {
+	return fmt.Errorf("not connected and the poller has no ConnectFunc")
+}
+
+// Subscribe returns a channel receiving every poll result. Results are dropped when the
+// channel buffer is full. The channel is closed when the poller stops.
+func (poller *Poller) Subscribe(bufferSize int) <-chan PollResult {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	channel := make(chan PollResult, bufferSize)
+	poller.subscribers = append(poller.subscribers, channel)
+	return channel
+}
+
+// OnResult registers a callback called from the polling goroutine for every result
+func (poller *Poller) OnResult(callback func(PollResult)) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	poller.callbacks = append(poller.callbacks, callback)
+}
+
+// Start polling in a background goroutine, see Stop
+func (poller *Poller) Start() {
+	ctx, done, ok := poller.begin(context.Background())
+	if ok {
+		go poller.loop(ctx, done)
+	}
+}
+
+// Run polls until ctx is done or Stop is called, then closes the transport and the subscriber
+// channels and returns nil. Composes with errgroup based services:
+//
+//	group.Go(func() error { return poller.Run(ctx) })
+func (poller *Poller) Run(ctx context.Context) error {
+	runCtx, done, ok := poller.begin(ctx)
+	if !ok {
+		return fmt.Errorf("poller already running")
+	}
+	poller.loop(runCtx, done)
+
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	if poller.cancel != nil && poller.done == done {
+		// ended by ctx, not by Stop
+		poller.cancel()
+		poller.cancel = nil
+	}
+	return nil
+}
+
+// Stop polling, waits for the current sample to be aborted and closes subscriber channels
+func (poller *Poller) Stop() {
+	poller.mutex.Lock()
+	cancel, done := poller.cancel, poller.done
+	poller.cancel = nil
+	poller.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// begin marks the poller as running, ok is false if it already is
+func (poller *Poller) begin(parent context.Context) (context.Context, chan struct{}, bool) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	if poller.cancel != nil {
+		return nil, nil, false
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	poller.cancel = cancel
+	poller.done = make(chan struct{})
+	return ctx, poller.done, true
+}
+
+// closeSubscribers closes the subscriber channels once polling ended
+func (poller *Poller) closeSubscribers() {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	for _, channel := range poller.subscribers {
+		close(channel)
+	}
+	poller.subscribers = nil
+}
+
+func (poller *Poller) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	defer poller.closeSubscribers()
+	defer poller.bms.Disconnect()
+
+	connected := poller.bms.isConnected()
+	schedule := &pollSchedule{next: map[PollGroup]time.Time{}}
+	for ctx.Err() == nil {
+		if !connected {
+			if err := poller.connect(ctx, poller.bms); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				poller.publish(PollResult{Err: err, Time: time.Now()})
+				sleepCtx(ctx, poller.ReconnectDelay)
+				continue
+			}
+			connected = true
+		}
+
+		data, err := poller.sample(ctx, schedule)
+		if ctx.Err() != nil {
+			return
+		}
+		poller.publish(PollResult{Data: data, Err: err, Time: time.Now()})
+
+		if err != nil {
+			// drop the connection and start over
+			if disconnectErr := poller.bms.Disconnect(); disconnectErr != nil {
+				poller.bms.logf("Poller disconnect failed: %v", disconnectErr)
+			}
+			connected = false
+			sleepCtx(ctx, poller.ReconnectDelay)
+			continue
+		}
+
+		sleepCtx(ctx, poller.delay(schedule))
+	}
+}
+
+// publish delivers a result to callbacks and subscribers without blocking on slow subscribers.
+// They are called without the mutex held, so a callback may Subscribe() or call OnResult().
+func (poller *Poller) publish(result PollResult) {
+	poller.mutex.Lock()
+	callbacks := slices.Clone(poller.callbacks)
+	subscribers := slices.Clone(poller.subscribers)
+	poller.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(result)
+	}
+	for _, channel := range subscribers {
+		select {
+		case channel <- result:
+		default:
+			poller.bms.logf("Poller subscriber too slow, dropping result")
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Serial device found by ListSerialPorts(). The USB fields are empty for other ports and on
+// platforms that don't expose them.
+type SerialPortInfo struct {
+	Path         string `json:"path"` // for Connect(), eg "/dev/ttyUSB0" or "COM3"
+	Description  string `json:"description,omitempty"`
+	USB          bool   `json:"usb"`
+	VendorID     string `json:"vendor_id,omitempty"`  // hex, eg "1a86"
+	ProductID    string `json:"product_id,omitempty"` // hex, eg "7523"
+	SerialNumber string `json:"serial_number,omitempty"`
+}
+
+// String returns the path with the description and USB IDs, eg "/dev/ttyUSB0 (USB Serial, 1a86:7523)"
+func (port SerialPortInfo) String() string {
+	var details []string
+	if port.Description != "" {
+		details = append(details, port.Description)
+	}
+	if port.VendorID != "" {
+		details = append(details, port.VendorID+":"+port.ProductID)
+	}
+	if len(details) == 0 {
+		return port.Path
+	}
+	return fmt.Sprintf("%s (%s)", port.Path, strings.Join(details, ", "))
+}
+
+// ListSerialPorts returns the serial devices a BMS may be plugged into, USB adapters first.
+// On Linux they come from sysfs, with the USB IDs and names, on macOS from the /dev/cu.* USB
+// devices, and on Windows they are the COM ports that can be opened.
+func ListSerialPorts() ([]SerialPortInfo, error) {
+	ports, err := listSerialPorts()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ports, func(i, j int) bool {
+		if ports[i].USB != ports[j].USB {
+			return ports[i].USB
+		}
+		return ports[i].Path < ports[j].Path
+	})
+	return ports, nil
+}
+
+// Result of AutoConnect(): the port the client connected to and the settings probed on it
+type AutoConnectResult struct {
+	Port SerialPortInfo `json:"port"`
+	ProbeResult
+}
+
+// AutoConnect probes the ports of ListSerialPorts() in turn and connects to the first one a BMS
+// answers on, with the address, protocol and baud rate found. Probing a silent port takes a few
+// seconds, pass the path to Connect() once known.
+func (bms *DalyBMSIstance) AutoConnect() (*AutoConnectResult, error) {
+	return bms.AutoConnectCtx(context.Background())
+}
+
+// AutoConnect with cancellation support
+func (bms *DalyBMSIstance) AutoConnectCtx(ctx context.Context) (*AutoConnectResult, error) {
+	ports, err := ListSerialPorts()
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no serial ports found")
+	}
+
+	for _, port := range ports {
+		result, err := probePort(ctx, port.Path, bms.serialOpener)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			bms.logf("No BMS on %s: %v", port, err)
+			continue
+		}
+
+		bms.stateMutex.Lock()
+		for _, option := range result.Options() {
+			option(bms)
+		}
+		bms.stateMutex.Unlock()
+		config := DefaultSerialConfig()
+		config.BaudRate = result.BaudRate
+		if err := bms.ConnectWithConfigCtx(ctx, port.Path, config); err != nil {
+			return nil, err
+		}
+		return &AutoConnectResult{Port: port, ProbeResult: *result}, nil
+	}
+	return nil, fmt.Errorf("no answer from a BMS on %d serial ports", len(ports))
+}
@@ -0,0 +1,27 @@
+//go:build darwin
+
+package dalybms
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Call-out devices of USB serial drivers: FTDI and Prolific, CDC ACM, Silicon Labs CP210x, WCH CH34x
+var darwinPortPatterns = []string{"/dev/cu.usbserial*", "/dev/cu.usbmodem*", "/dev/cu.SLAB_USBtoUART*", "/dev/cu.wchusbserial*"}
+
+// listSerialPorts lists the USB serial devices of /dev. macOS only exposes their IDs through
+// IOKit, so VendorID and ProductID stay empty.
+func listSerialPorts() ([]SerialPortInfo, error) {
+	var ports []SerialPortInfo
+	for _, pattern := range darwinPortPatterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			ports = append(ports, SerialPortInfo{Path: path, USB: true, Description: strings.TrimPrefix(path, "/dev/cu.")})
+		}
+	}
+	return ports, nil
+}
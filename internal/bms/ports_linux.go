@@ -0,0 +1,56 @@
+//go:build linux
+
+package dalybms
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const sysClassTTY = "/sys/class/tty"
+
+// listSerialPorts lists the ttys of sysfs backed by a device, without the placeholders of the
+// legacy 8250 driver, which are listed whether a UART is present or not
+func listSerialPorts() ([]SerialPortInfo, error) {
+	entries, err := os.ReadDir(sysClassTTY)
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []SerialPortInfo
+	for _, entry := range entries {
+		ttyPath := filepath.Join(sysClassTTY, entry.Name())
+		devicePath, err := filepath.EvalSymlinks(filepath.Join(ttyPath, "device"))
+		if err != nil {
+			continue // virtual terminals and ptys
+		}
+		if readSysfs(ttyPath, "type") == "0" {
+			continue // no UART behind the port
+		}
+
+		port := SerialPortInfo{Path: "/dev/" + entry.Name()}
+		// the USB device holding the IDs is an ancestor of the interface the tty belongs to
+		for dir := devicePath; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			if vendorID := readSysfs(dir, "idVendor"); vendorID != "" {
+				port.USB = true
+				port.VendorID = vendorID
+				port.ProductID = readSysfs(dir, "idProduct")
+				port.SerialNumber = readSysfs(dir, "serial")
+				port.Description = strings.TrimSpace(readSysfs(dir, "manufacturer") + " " + readSysfs(dir, "product"))
+				break
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// readSysfs returns the trimmed content of a sysfs attribute, "" when missing
+func readSysfs(dir string, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
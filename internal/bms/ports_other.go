@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package dalybms
+
+import "path/filepath"
+
+// USB serial devices of the BSDs
+var otherPortPatterns = []string{"/dev/cuaU*", "/dev/ttyU*"}
+
+// listSerialPorts lists the USB serial devices of /dev, without their IDs
+func listSerialPorts() ([]SerialPortInfo, error) {
+	var ports []SerialPortInfo
+	for _, pattern := range otherPortPatterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			ports = append(ports, SerialPortInfo{Path: path, USB: true})
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package dalybms
+
+import "fmt"
+
+// Highest COM port tried by listSerialPorts()
+const maxCOMPort = 64
+
+// listSerialPorts lists the COM ports that can be opened. Ports held by another program are
+// missing, and the USB IDs need the SetupAPI, so they stay empty.
+func listSerialPorts() ([]SerialPortInfo, error) {
+	var ports []SerialPortInfo
+	for number := 1; number <= maxCOMPort; number++ {
+		path := fmt.Sprintf("COM%d", number)
+		port, err := OpenSerialPort(path, DefaultSerialConfig())
+		if err != nil {
+			continue
+		}
+		port.Close()
+		ports = append(ports, SerialPortInfo{Path: path})
+	}
+	return ports, nil
+}
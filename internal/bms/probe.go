@@ -0,0 +1,86 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Baud rates tried by Probe(), in order
+var probeBaudRates = []int{9600, 115200}
+
+// Settings that got an answer from the BMS, see Probe()
+type ProbeResult struct {
+	Address  int      `json:"address"`
+	BaudRate int      `json:"baud_rate"` // 0 when probing a transport
+	Protocol Protocol `json:"protocol"`
+}
+
+// Options configuring a client with the probed settings. The baud rate goes to ConnectWithConfig().
+func (result *ProbeResult) Options() []Option {
+	return []Option{WithAddress(result.Address), WithProtocol(result.Protocol)}
+}
+
+// Probe tries addresses 1-8 at 9600 and 115200 baud on a serial port, then the Sinowealth
+// protocol, and returns the first settings the BMS answers to. Eg 4 for UART/RS485, 8 for Bluetooth modules.
+// Local devices are opened with OpenSerialPort(), probe ports of another SerialOpener with ProbeTransport().
+func Probe(serialDevicePath string) (*ProbeResult, error) {
+	return ProbeCtx(context.Background(), serialDevicePath)
+}
+
+// Probe with cancellation support
+func ProbeCtx(ctx context.Context, serialDevicePath string) (*ProbeResult, error) {
+	return probePort(ctx, serialDevicePath, nil)
+}
+
+// probePort is ProbeCtx() opening local devices with opener, OpenSerialPort() when nil
+func probePort(ctx context.Context, serialDevicePath string, opener SerialOpener) (*ProbeResult, error) {
+	for _, baudRate := range probeBaudRates {
+		config := DefaultSerialConfig()
+		config.BaudRate = baudRate
+		port, err := openPort(ctx, serialDevicePath, config, opener)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := ProbeTransportCtx(ctx, port)
+		port.Close()
+		if err == nil {
+			result.BaudRate = baudRate
+			return result, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+	}
+	return nil, fmt.Errorf("no answer from the BMS on %s at %v baud", serialDevicePath, probeBaudRates)
+}
+
+// ProbeTransport tries addresses 1-8 and the Sinowealth protocol on an already opened transport
+func ProbeTransport(transport Transport) (*ProbeResult, error) {
+	return ProbeTransportCtx(context.Background(), transport)
+}
+
+// ProbeTransport with cancellation support. The transport is left open.
+func ProbeTransportCtx(ctx context.Context, transport Transport) (*ProbeResult, error) {
+	for address := 1; address <= 8; address++ {
+		probeClient := NewClient(WithAddress(address), WithLogger(nil), WithTransport(transport))
+		response, err := probeClient.readSerialResponseCtx(ctx, protocol.CommandSOC, nil, 1, false, nil)
+		if err == nil && response != nil {
+			return &ProbeResult{Address: address, Protocol: ProtocolDaly}, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	probeClient := NewClient(WithLogger(nil), WithTransport(transport))
+	if _, err := probeClient.sinowealthReadOnce(ctx, sinowealthRegSOC, 2); err == nil {
+		return &ProbeResult{Address: probeClient.address, Protocol: ProtocolSinowealth}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no answer from the BMS at addresses 1-8")
+}
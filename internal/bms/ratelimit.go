@@ -0,0 +1,58 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// ErrRateLimited is returned (wrapped) by a control command sent again before the interval of
+// WithControlRateLimit() elapsed
+var ErrRateLimited = errors.New("control command rate limited")
+
+// Suggested minimum interval between two identical control commands, see WithControlRateLimit()
+const DefaultControlInterval = time.Second
+
+// controlKey identifies a control command and the value written to a BMS for the rate limit
+type controlKey struct {
+	requestAddress byte
+	command        protocol.Command
+	value          string
+}
+
+// Set the minimum interval between two identical writes of a control command (charge MOSFET,
+// discharge MOSFET, SOC, heater) to a BMS. Faster repeats of the same value fail with
+// ErrRateLimited without reaching the board, so a runaway automation loop can't hammer it.
+// Writing another value, and switching a MOSFET off, is never limited. 0 disables the limit
+// (default).
+func WithControlRateLimit(interval time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.controlInterval = interval
+	}
+}
+
+// limitControl fails when command was sent with the same data to the BMS ctx targets less than
+// the interval of WithControlRateLimit() ago, else records it as sent now
+func (bms *DalyBMSIstance) limitControl(ctx context.Context, command protocol.Command, data []byte) error {
+	if bms.controlInterval <= 0 {
+		return nil
+	}
+
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	key := controlKey{requestAddress: bms.requestAddress(ctx), command: command, value: string(data)}
+	now := time.Now()
+	if last, ok := bms.lastControl[key]; ok {
+		if wait := bms.controlInterval - now.Sub(last); wait > 0 {
+			return fmt.Errorf("command %s: %w, retry in %v", command.Hex(), ErrRateLimited, wait.Round(time.Millisecond))
+		}
+	}
+	if bms.lastControl == nil {
+		bms.lastControl = map[controlKey]time.Time{}
+	}
+	bms.lastControl[key] = now
+	return nil
+}
@@ -0,0 +1,60 @@
+package dalybms
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newSimulatedClient(t *testing.T, options ...Option) *DalyBMSIstance {
+	bms := NewClient(options...)
+	if err := bms.ConnectTransport(NewSimulator(DefaultSimulatorConfig())); err != nil {
+		t.Fatalf("ConnectTransport: %v", err)
+	}
+	t.Cleanup(func() { bms.Disconnect() })
+	return bms
+}
+
+func TestControlRateLimitOff(t *testing.T) {
+	bms := newSimulatedClient(t)
+	for _, isOn := range []bool{true, true, true} {
+		if err := bms.EnableChargeMosfet(isOn); err != nil {
+			t.Fatalf("EnableChargeMosfet(%v) without a rate limit: %v", isOn, err)
+		}
+	}
+}
+
+func TestControlRateLimitSwitchOff(t *testing.T) {
+	bms := newSimulatedClient(t, WithControlRateLimit(time.Minute))
+
+	if err := bms.EnableChargeMosfet(true); err != nil {
+		t.Fatalf("EnableChargeMosfet(true): %v", err)
+	}
+	if err := bms.EnableChargeMosfet(false); err != nil {
+		t.Fatalf("EnableChargeMosfet(false) right after switching on: %v", err)
+	}
+	if err := bms.EnableChargeMosfet(false); err != nil {
+		t.Fatalf("repeated EnableChargeMosfet(false): %v", err)
+	}
+	status, err := bms.GetMosfetStatus()
+	if err != nil {
+		t.Fatalf("GetMosfetStatus: %v", err)
+	}
+	if status.ChargingMosfet {
+		t.Errorf("charge MOSFET still on after switching it off")
+	}
+
+	// another value passes, the same value again is limited
+	if err := bms.EnableChargeMosfet(true); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("repeated EnableChargeMosfet(true) error = %v, want ErrRateLimited", err)
+	}
+	if err := bms.SetSOC(50); err != nil {
+		t.Fatalf("SetSOC(50): %v", err)
+	}
+	if err := bms.SetSOC(60); err != nil {
+		t.Errorf("SetSOC(60) right after SetSOC(50): %v", err)
+	}
+	if err := bms.SetSOC(60); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("repeated SetSOC(60) error = %v, want ErrRateLimited", err)
+	}
+}
@@ -0,0 +1,47 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Response frame of a raw request
+type Frame struct {
+	Command byte   `json:"command"`
+	Data    []byte `json:"data"` // the 8 data bytes
+}
+
+// Max response frames collected by SendRaw, enough for the cell voltages of a 48S pack
+const maxRawResponses = 64
+
+// SendRaw sends a request with any command code and up to 8 payload bytes, and returns the
+// response frames. Gives access to registers the library has no method for, eg 0x51 battery type.
+// Responses are collected until the transport read times out, so each call takes at least one read timeout.
+// Fails with ErrTimeout when the BMS does not answer.
+func (bms *DalyBMSIstance) SendRaw(command byte, payload []byte) ([]Frame, error) {
+	return bms.SendRawCtx(context.Background(), command, payload)
+}
+
+// SendRaw with cancellation support
+func (bms *DalyBMSIstance) SendRawCtx(ctx context.Context, command byte, payload []byte) ([]Frame, error) {
+	if len(payload) > 8 {
+		return nil, fmt.Errorf("payload too long: %d bytes, max 8", len(payload))
+	}
+
+	response, err := bms.sendReadRequestCtx(ctx, protocol.Command(command), payload, maxRawResponses, true)
+	if err != nil {
+		return nil, err
+	}
+	dataFrames, ok := response.([][]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type for command %02x", command)
+	}
+
+	frames := make([]Frame, 0, len(dataFrames))
+	for _, data := range dataFrames {
+		frames = append(frames, Frame{Command: command, Data: append([]byte(nil), data...)})
+	}
+	return frames, nil
+}
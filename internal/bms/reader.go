@@ -0,0 +1,122 @@
+package dalybms
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	backgroundFrameWait = 100 * time.Millisecond // default wait for the next frame, like the serial read timeout
+	backgroundIdleDelay = 5 * time.Millisecond   // pause after an empty or failed read
+)
+
+// Read frames in a dedicated goroutine feeding a channel, instead of reading the transport during
+// each request. A response then ends once no frame arrived for the link latency (100ms when unset,
+// see WithLatency()) measured with a timer rather than the transport read timeout, and frames the
+// BMS pushes between requests are delivered as they arrive, see WithUnsolicitedFrames(). The
+// transport must allow a Read concurrent with Write, as serial ports and sockets do. Daly protocol
+// only, Sinowealth exchanges stop the reader.
+func WithBackgroundReader() Option {
+	return func(bms *DalyBMSIstance) {
+		bms.backgroundReads = true
+	}
+}
+
+// frameResult is a frame or a strict mode *FrameError read by the background reader
+type frameResult struct {
+	frame []byte
+	err   error
+}
+
+type backgroundReader struct {
+	frames chan frameResult
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startBackgroundReader starts the reader goroutine when enabled and not running yet.
+// Must be called with busMutex held.
+func (bms *DalyBMSIstance) startBackgroundReader() {
+	if !bms.backgroundReads || bms.background != nil || bms.transport == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &backgroundReader{
+		frames: make(chan frameResult, 64),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	bms.background = reader
+	go reader.run(ctx, bms, &frameReader{bms: bms, transport: bms.transport})
+}
+
+// stopBackgroundReader stops the reader goroutine, if running, and waits for its last read.
+// Must be called with busMutex held.
+func (bms *DalyBMSIstance) stopBackgroundReader() {
+	if bms.background == nil {
+		return
+	}
+	bms.background.cancel()
+	<-bms.background.done
+	bms.background = nil
+}
+
+func (reader *backgroundReader) run(ctx context.Context, bms *DalyBMSIstance, frames *frameReader) {
+	defer close(reader.done)
+	for ctx.Err() == nil {
+		frame, err := frames.next(ctx)
+		if frame == nil && err == nil {
+			// nothing to read, or the transport failed: don't spin
+			sleepCtx(ctx, backgroundIdleDelay)
+			continue
+		}
+		if frame != nil && !bms.awaitingResponse.Load() && bms.routeUnsolicited(frame) {
+			continue
+		}
+		select {
+		case reader.frames <- frameResult{frame: frame, err: err}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// next returns the next frame, nil when none arrived within wait or ctx is done
+func (reader *backgroundReader) next(ctx context.Context, wait time.Duration) ([]byte, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case result := <-reader.frames:
+		return result.frame, result.err
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, nil
+	}
+}
+
+// drain empties the frames read since the previous request, delivering them as unsolicited
+func (reader *backgroundReader) drain(bms *DalyBMSIstance) {
+	for {
+		select {
+		case result := <-reader.frames:
+			if result.frame != nil {
+				bms.routeUnsolicited(result.frame)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// nextFrame returns the next frame of a response, from the background reader when running.
+// Must be called with busMutex held.
+func (bms *DalyBMSIstance) nextFrame(ctx context.Context, reader *frameReader) ([]byte, error) {
+	if bms.background == nil {
+		return reader.next(ctx)
+	}
+	wait := bms.linkLatency
+	if wait <= 0 {
+		wait = backgroundFrameWait
+	}
+	return bms.background.next(ctx, wait)
+}
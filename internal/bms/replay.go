@@ -0,0 +1,147 @@
+package dalybms
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A frame recorded by NewFrameTraceWriter()
+type TraceRecord struct {
+	Time      time.Time
+	Direction Direction
+	Frame     []byte
+}
+
+// ReadFrameTrace parses a trace written by NewFrameTraceWriter(). Empty lines and lines starting with # are skipped.
+func ReadFrameTrace(reader io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("trace line %d: expected 3 fields, got %d", lineNumber, len(fields))
+		}
+
+		recordTime, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", lineNumber, err)
+		}
+
+		var direction Direction
+		switch fields[1] {
+		case "tx":
+			direction = DirectionTX
+		case "rx":
+			direction = DirectionRX
+		default:
+			return nil, fmt.Errorf("trace line %d: unknown direction %s", lineNumber, fields[1])
+		}
+
+		frame, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", lineNumber, err)
+		}
+
+		records = append(records, TraceRecord{Time: recordTime, Direction: direction, Frame: frame})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ReplayTransport plays a recorded trace back: each written request is matched with the next
+// recorded request for the same command, and the frames received after it are returned by Read.
+type ReplayTransport struct {
+	mutex    sync.Mutex
+	records  []TraceRecord
+	position int // index of the next record to consider
+	pending  []byte
+	closed   bool
+}
+
+func NewReplayTransport(records []TraceRecord) *ReplayTransport {
+	return &ReplayTransport{
+		records: records,
+	}
+}
+
+func (replay *ReplayTransport) Write(frame []byte) (int, error) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+
+	if replay.closed {
+		return 0, fmt.Errorf("replay transport closed")
+	}
+	if len(frame) < 3 {
+		return 0, fmt.Errorf("invalid request frame: %x", frame)
+	}
+
+	requestIndex := -1
+	for recordIndex := replay.position; recordIndex < len(replay.records); recordIndex++ {
+		record := replay.records[recordIndex]
+		if record.Direction == DirectionTX && len(record.Frame) >= 3 && record.Frame[2] == frame[2] {
+			requestIndex = recordIndex
+			break
+		}
+	}
+	if requestIndex < 0 {
+		return 0, fmt.Errorf("no recorded request left for command %02x", frame[2])
+	}
+
+	// queue every frame received until the next request
+	replay.pending = nil
+	replay.position = requestIndex + 1
+	for replay.position < len(replay.records) && replay.records[replay.position].Direction == DirectionRX {
+		replay.pending = append(replay.pending, replay.records[replay.position].Frame...)
+		replay.position++
+	}
+	return len(frame), nil
+}
+
+// Read returns the recorded response bytes, or 0 bytes when none are left (like a serial read timeout)
+func (replay *ReplayTransport) Read(b []byte) (int, error) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+
+	if replay.closed {
+		return 0, fmt.Errorf("replay transport closed")
+	}
+	bytesRead := copy(b, replay.pending)
+	replay.pending = replay.pending[bytesRead:]
+	return bytesRead, nil
+}
+
+func (replay *ReplayTransport) Close() error {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.closed = true
+	return nil
+}
+
+// Number of recorded requests not replayed yet
+func (replay *ReplayTransport) Remaining() int {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+
+	remaining := 0
+	for _, record := range replay.records[replay.position:] {
+		if record.Direction == DirectionTX {
+			remaining++
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,71 @@
+package dalybms
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how requests are retried when the BMS does not answer.
+// Delays grow as InitialDelay * Multiplier^(attempt-1), capped to MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration // 0 = no cap
+	Multiplier   float64       // 1 = constant delay, 2 = exponential backoff
+	Jitter       float64       // 0..1, each delay is randomly changed by up to this fraction
+
+	// Policies for specific commands, eg 0x95 for cell voltages. Their own Overrides are ignored.
+	Overrides map[byte]RetryPolicy
+}
+
+// 3 attempts, 200ms apart
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: 200 * time.Millisecond,
+		Multiplier:   1,
+	}
+}
+
+// Exponential backoff with jitter, for busy buses with several masters
+func BackoffRetryPolicy(maxAttempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  maxAttempts,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     2 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.3,
+	}
+}
+
+// forCommand returns the policy to use for a command, eg 0x90
+func (policy RetryPolicy) forCommand(command byte) RetryPolicy {
+	if override, ok := policy.Overrides[command]; ok {
+		return override
+	}
+	return policy
+}
+
+// attempts returns MaxAttempts, at least 1
+func (policy RetryPolicy) attempts() int {
+	return max(policy.MaxAttempts, 1)
+}
+
+// delay returns the wait before retrying after the given failed attempt (1-based)
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	delay := float64(policy.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+
+	if policy.Jitter > 0 {
+		delay *= 1 + policy.Jitter*(rand.Float64()*2-1)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,93 @@
+package dalybms
+
+import "math"
+
+// Resolution of the readings of a protocol, in decimals, see WithRounding()
+type resolution struct {
+	packVoltage int
+	current     int
+	soc         int
+	cellVoltage int
+	temperature int
+	capacity    int
+}
+
+// Daly: 0.1 V, 0.1 A, 0.1 %, 1 mV, 1 °C, 1 mAh
+var dalyResolution = resolution{packVoltage: 1, current: 1, soc: 1, cellVoltage: 3, temperature: 0, capacity: 3}
+
+// Sinowealth: 1 mV pack voltage, 1 % SOC, the rest converted to the Daly units
+var sinowealthResolution = resolution{packVoltage: 3, current: 1, soc: 0, cellVoltage: 3, temperature: 0, capacity: 3}
+
+// roundTo rounds to the given number of decimals, returning the float64 closest to the decimal value
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
+
+func roundFloat32(value float32, decimals int) float32 {
+	return float32(roundTo(Widen(value), decimals))
+}
+
+func roundValues(values map[int]float64, decimals int) map[int]float64 {
+	for key, value := range values {
+		values[key] = roundTo(value, decimals)
+	}
+	return values
+}
+
+// The helpers below return their argument, rounded when WithRounding() is set
+
+func (bms *DalyBMSIstance) roundSOC(data *SOCData, resolution resolution) *SOCData {
+	if bms.rounding {
+		data.TotalVoltage = roundFloat32(data.TotalVoltage, resolution.packVoltage)
+		data.Current = roundFloat32(data.Current, resolution.current)
+		data.SOCPercent = roundFloat32(data.SOCPercent, resolution.soc)
+	}
+	return data
+}
+
+func (bms *DalyBMSIstance) roundCellVoltageRange(data *CellVoltageRangeData, resolution resolution) *CellVoltageRangeData {
+	if bms.rounding {
+		data.HighestVoltage = roundFloat32(data.HighestVoltage, resolution.cellVoltage)
+		data.LowestVoltage = roundFloat32(data.LowestVoltage, resolution.cellVoltage)
+	}
+	return data
+}
+
+func (bms *DalyBMSIstance) roundTemperatureRange(data *TemperatureRangeData, resolution resolution) *TemperatureRangeData {
+	if bms.rounding {
+		data.HighestTemperature = roundFloat32(data.HighestTemperature, resolution.temperature)
+		data.LowestTemperature = roundFloat32(data.LowestTemperature, resolution.temperature)
+	}
+	return data
+}
+
+func (bms *DalyBMSIstance) roundMosfetStatus(data *MosfetStatusData, resolution resolution) *MosfetStatusData {
+	if bms.rounding {
+		data.CapacityAh = roundFloat32(data.CapacityAh, resolution.capacity)
+	}
+	return data
+}
+
+func (bms *DalyBMSIstance) roundCellVoltages(voltages map[int]float64, resolution resolution) map[int]float64 {
+	if bms.rounding {
+		roundValues(voltages, resolution.cellVoltage)
+	}
+	return voltages
+}
+
+func (bms *DalyBMSIstance) roundTemperatures(temperatures map[int]float64, resolution resolution) map[int]float64 {
+	if bms.rounding {
+		roundValues(temperatures, resolution.temperature)
+	}
+	return temperatures
+}
+
+// round limits derived values to a precision the readings support
+func (stats *Stats) round() {
+	stats.AverageCellVoltage = roundTo(stats.AverageCellVoltage, 4)
+	stats.CellVoltageDelta = roundTo(stats.CellVoltageDelta, 3)
+	stats.AverageTemperature = roundTo(stats.AverageTemperature, 1)
+	stats.PowerW = roundTo(stats.PowerW, 1)
+	stats.RemainingEnergyWh = roundTo(stats.RemainingEnergyWh, 1)
+}
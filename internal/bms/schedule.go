@@ -0,0 +1,154 @@
+package dalybms
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Data read together by a Poller with GroupIntervals
+type PollGroup int
+
+const (
+	PollSOC          PollGroup = iota // pack voltage, current and SOC (0x90)
+	PollCells                         // cell voltage range, cell voltages and balancing (0x91, 0x95, 0x97)
+	PollTemperatures                  // temperature range and sensors (0x92, 0x96)
+	PollStatus                        // MOSFET status, status and errors (0x93, 0x94, 0x98)
+)
+
+var pollGroups = []PollGroup{PollSOC, PollCells, PollTemperatures, PollStatus}
+
+// groupInterval returns the interval of a group, Interval when it has none
+func (poller *Poller) groupInterval(group PollGroup) time.Duration {
+	if interval, ok := poller.GroupIntervals[group]; ok && interval > 0 {
+		return interval
+	}
+	return poller.Interval
+}
+
+// readGroupsCtx reads the given groups in one batch and returns a copy of previous with them
+// replaced. previous may be nil when every group is read.
+func (bms *DalyBMSIstance) readGroupsCtx(ctx context.Context, groups []PollGroup, previous *AllBMSData) (*AllBMSData, error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	data := &AllBMSData{SchemaVersion: SchemaVersion, TemperatureUnit: bms.temperatureUnit, Rounded: bms.rounding}
+	if previous != nil {
+		*data = *previous
+		data.ReadTimes = maps.Clone(previous.ReadTimes)
+	}
+	data.Time = time.Now()
+	sinowealth, err := bms.isSinowealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// status first, the cell and sensor counts are needed by the other groups
+	for _, group := range []PollGroup{PollStatus, PollSOC, PollCells, PollTemperatures} {
+		if !slices.Contains(groups, group) {
+			continue
+		}
+		switch group {
+		case PollSOC:
+			if data.SOC, err = bms.GetSOCCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("soc")
+		case PollCells:
+			if data.CellVoltageRange, err = bms.GetCellVoltageRangeCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("cell_voltage_range")
+			if data.CellVoltages, err = bms.GetCellVoltagesCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("cell_voltages")
+			if !sinowealth {
+				if data.BalancingStatus, err = bms.GetBalancingStatusCtx(ctx); err != nil {
+					return nil, err
+				}
+				data.markRead("balancing_status")
+			}
+		case PollTemperatures:
+			if data.TemperatureRange, err = bms.GetTemperatureRangeCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("temperature_range")
+			if data.Temperatures, err = bms.GetTemperaturesCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("temperatures")
+			data.MOSTemperature = bms.mosTemperature(data.Temperatures)
+		case PollStatus:
+			if data.MosfetStatus, err = bms.GetMosfetStatusCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("mosfet_status")
+			if data.Status, err = bms.GetStatusCtx(ctx); err != nil {
+				return nil, err
+			}
+			data.markRead("status")
+			if !sinowealth {
+				if data.Errors, err = bms.GetErrorsCtx(ctx); err != nil {
+					return nil, err
+				}
+				data.markRead("errors")
+			}
+			data.CellLabels = bms.cellLabels(data.Status.NumberOfCells)
+		}
+	}
+	return data, nil
+}
+
+// pollSchedule tracks the groups of a Poller with GroupIntervals
+type pollSchedule struct {
+	next map[PollGroup]time.Time // when each group is due
+	data *AllBMSData             // latest value of every group, nil until read or after a failure
+}
+
+// sample reads the due groups and returns them merged with the latest values of the others,
+// or reads everything when GroupIntervals is empty
+func (poller *Poller) sample(ctx context.Context, schedule *pollSchedule) (*AllBMSData, error) {
+	if len(poller.GroupIntervals) == 0 {
+		return poller.bms.GetAllDataCtx(ctx)
+	}
+
+	now := time.Now()
+	var due []PollGroup
+	for _, group := range pollGroups {
+		if schedule.data == nil || !now.Before(schedule.next[group]) {
+			due = append(due, group)
+		}
+	}
+	data, err := poller.bms.readGroupsCtx(ctx, due, schedule.data)
+	if err != nil {
+		// read everything again after the reconnection
+		schedule.data = nil
+		return nil, err
+	}
+	for _, group := range due {
+		// stay on the initial time grid, so groups with multiple intervals are read together
+		next := schedule.next[group].Add(poller.groupInterval(group))
+		if next.Before(now) {
+			next = now.Add(poller.groupInterval(group))
+		}
+		schedule.next[group] = next
+	}
+	schedule.data = data
+	return data, nil
+}
+
+// delay returns the time until the next sample
+func (poller *Poller) delay(schedule *pollSchedule) time.Duration {
+	if len(poller.GroupIntervals) == 0 {
+		return poller.Interval
+	}
+	var earliest time.Time
+	for _, group := range pollGroups {
+		if next := schedule.next[group]; earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	return time.Until(earliest)
+}
@@ -0,0 +1,162 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+type Parity byte
+
+const (
+	ParityNone Parity = 'N'
+	ParityOdd  Parity = 'O'
+	ParityEven Parity = 'E'
+)
+
+type StopBits byte
+
+const (
+	StopBits1 StopBits = 1
+	StopBits2 StopBits = 2
+)
+
+// Serial port settings
+type SerialConfig struct {
+	BaudRate    int
+	ReadTimeout time.Duration // max wait for a single read
+	DataBits    byte
+	Parity      Parity
+	StopBits    StopBits
+}
+
+// Settings used by Connect: 9600 8N1, 100ms read timeout
+func DefaultSerialConfig() SerialConfig {
+	return SerialConfig{
+		BaudRate:    9600,
+		ReadTimeout: 100 * time.Millisecond,
+		DataBits:    8,
+		Parity:      ParityNone,
+		StopBits:    StopBits1,
+	}
+}
+
+// ConnectWithConfig opens the serial port with custom settings. Eg "/dev/ttyUSB0", or
+// "tcp://192.168.1.50:2000" for a remote port, see DialTCPTransport
+func (bms *DalyBMSIstance) ConnectWithConfig(serialDevicePath string, config SerialConfig) error {
+	return bms.ConnectWithConfigCtx(context.Background(), serialDevicePath, config)
+}
+
+// ConnectWithConfig with cancellation support. ctx also bounds the initial status fetch.
+func (bms *DalyBMSIstance) ConnectWithConfigCtx(ctx context.Context, serialDevicePath string, config SerialConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	openedPort, err := openPort(ctx, serialDevicePath, config, bms.serialOpener)
+	if err != nil {
+		return err
+	}
+
+	return bms.ConnectTransportCtx(ctx, openedPort)
+}
+
+// SerialOpener opens a local serial device with the given settings, see WithSerialOpener(). The
+// transport must return from Read after config.ReadTimeout when no data arrives.
+type SerialOpener func(serialDevicePath string, config SerialConfig) (Transport, error)
+
+// Open local serial devices with another serial library than the built-in one, eg one handling a
+// platform or adapter better. tcp:// and rfc2217:// addresses are still opened by the client.
+func WithSerialOpener(opener SerialOpener) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.serialOpener = opener
+	}
+}
+
+// openPort opens a local serial device with opener, OpenSerialPort() when nil, or a remote one
+// for tcp:// and rfc2217:// addresses
+func openPort(ctx context.Context, serialDevicePath string, config SerialConfig, opener SerialOpener) (Transport, error) {
+	if IsNetworkAddress(serialDevicePath) {
+		return DialTCPTransport(ctx, serialDevicePath, config)
+	}
+	if opener == nil {
+		opener = OpenSerialPort
+	}
+	return opener(serialDevicePath, config)
+}
+
+// OpenSerialPort opens a local serial device with the built-in serial library, the default
+// SerialOpener. Eg "/dev/ttyUSB0" on Linux, "COM3" on Windows, where ports above COM9 work too.
+func OpenSerialPort(serialDevicePath string, config SerialConfig) (Transport, error) {
+	if runtime.GOOS == "windows" {
+		serialDevicePath = windowsDevicePath(serialDevicePath)
+	}
+	portConfig, err := config.toPortConfig(serialDevicePath)
+	if err != nil {
+		return nil, err
+	}
+
+	openedPort, err := serial.OpenPort(portConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port: %w", err)
+	}
+	return openedPort, nil
+}
+
+// windowsDevicePath maps "COM10" to `\\.\COM10`, the form Windows requires above COM9 and
+// accepts for every port. Other names, and names already prefixed, are returned unchanged.
+func windowsDevicePath(serialDevicePath string) string {
+	number, found := strings.CutPrefix(strings.ToUpper(serialDevicePath), "COM")
+	if !found || number == "" {
+		return serialDevicePath
+	}
+	for _, digit := range number {
+		if digit < '0' || digit > '9' {
+			return serialDevicePath
+		}
+	}
+	return `\\.\` + serialDevicePath
+}
+
+// toPortConfig validates the settings and converts them for the serial library
+func (config SerialConfig) toPortConfig(serialDevicePath string) (*serial.Config, error) {
+	if config.BaudRate <= 0 {
+		return nil, fmt.Errorf("invalid baud rate: %d", config.BaudRate)
+	}
+	if config.ReadTimeout <= 0 {
+		return nil, fmt.Errorf("invalid read timeout: %s", config.ReadTimeout)
+	}
+
+	portConfig := &serial.Config{
+		Name:        serialDevicePath,
+		Baud:        config.BaudRate,
+		ReadTimeout: config.ReadTimeout,
+		Size:        config.DataBits,
+	}
+
+	switch config.Parity {
+	case ParityNone:
+		portConfig.Parity = serial.ParityNone
+	case ParityOdd:
+		portConfig.Parity = serial.ParityOdd
+	case ParityEven:
+		portConfig.Parity = serial.ParityEven
+	default:
+		return nil, fmt.Errorf("invalid parity: %c", config.Parity)
+	}
+
+	switch config.StopBits {
+	case StopBits1:
+		portConfig.StopBits = serial.Stop1
+	case StopBits2:
+		portConfig.StopBits = serial.Stop2
+	default:
+		return nil, fmt.Errorf("invalid stop bits: %d", config.StopBits)
+	}
+
+	return portConfig, nil
+}
@@ -0,0 +1,27 @@
+package dalybms
+
+import "testing"
+
+func TestWindowsDevicePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"COM1", `\\.\COM1`},
+		{"COM9", `\\.\COM9`},
+		{"COM10", `\\.\COM10`},
+		{"COM256", `\\.\COM256`},
+		{"com12", `\\.\com12`},
+		{`\\.\COM12`, `\\.\COM12`},
+		{"COM", "COM"},
+		{"COMX", "COMX"},
+		{"COM1A", "COM1A"},
+		{"/dev/ttyUSB0", "/dev/ttyUSB0"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := windowsDevicePath(test.path); got != test.want {
+			t.Errorf("windowsDevicePath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package dalybms
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Simulator settings
+type SimulatorConfig struct {
+	NumberOfCells              int
+	NumberOfTemperatureSensors int
+	CellVoltage                float64 // V, mean cell voltage
+	VoltageDrift               float64 // V, max deviation of a cell from the mean
+	Temperature                float64 // °C
+	Current                    float64 // A, positive when charging
+	SOCPercent                 float64
+	CapacityAh                 float64
+	CycleCount                 int
+	FirmwareVersion            string
+	HardwareVersion            string
+	BatteryCode                string
+	CRCErrorRate               float64       // 0..1, probability of a response frame with a corrupted CRC
+	ChunkSize                  int           // max bytes per read, 0 => everything pending
+	Jitter                     time.Duration // max random delay before each chunk, like a WiFi UART bridge
+	Seed                       int64         // 0 => time based
+}
+
+// Sensible defaults for a 4S LiFePO4 pack
+func DefaultSimulatorConfig() SimulatorConfig {
+	return SimulatorConfig{
+		NumberOfCells:              4,
+		NumberOfTemperatureSensors: 1,
+		CellVoltage:                3.3,
+		VoltageDrift:               0.02,
+		Temperature:                20,
+		SOCPercent:                 60,
+		CapacityAh:                 100,
+		CycleCount:                 10,
+		FirmwareVersion:            "20210222-1.01T",
+		HardwareVersion:            "DL-R16L-F8S",
+		BatteryCode:                "SIMULATOR-0001",
+	}
+}
+
+// Simulator is an in-memory BMS speaking the Daly UART protocol.
+// Use it as a Transport to exercise code depending on this package without hardware:
+//
+//	bms := dalybms.NewClient()
+//	bms.ConnectTransport(dalybms.NewSimulator(dalybms.DefaultSimulatorConfig()))
+type Simulator struct {
+	config          SimulatorConfig
+	mutex           sync.Mutex
+	random          *rand.Rand
+	cellVoltages    []float64
+	chargeMosfet    bool
+	dischargeMosfet bool
+	errorBytes      [8]byte
+	parameters      map[byte][8]byte // protection parameters by read command
+	pending         []byte
+	readyAt         time.Time // next chunk available, see Jitter
+	closed          bool
+}
+
+// Read timeout emulated when Jitter is set
+const simulatorReadTimeout = 20 * time.Millisecond
+
+func NewSimulator(config SimulatorConfig) *Simulator {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	sim := &Simulator{
+		config:          config,
+		random:          rand.New(rand.NewSource(seed)),
+		cellVoltages:    make([]float64, config.NumberOfCells),
+		chargeMosfet:    true,
+		dischargeMosfet: true,
+		parameters:      defaultSimulatorParameters(config),
+	}
+	for cellIndex := range sim.cellVoltages {
+		sim.cellVoltages[cellIndex] = config.CellVoltage
+	}
+	return sim
+}
+
+// Set or clear an error flag, see DalyErrorCodes for the meaning of each byte/bit
+func (sim *Simulator) SetErrorFlag(byteIndex int, bitIndex int, active bool) error {
+	if byteIndex < 0 || byteIndex >= len(sim.errorBytes) || bitIndex < 0 || bitIndex > 7 {
+		return fmt.Errorf("invalid error flag byte=%d bit=%d", byteIndex, bitIndex)
+	}
+
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if active {
+		sim.errorBytes[byteIndex] |= 1 << bitIndex
+	} else {
+		sim.errorBytes[byteIndex] &^= 1 << bitIndex
+	}
+	return nil
+}
+
+// Clear all error flags
+func (sim *Simulator) ClearErrors() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.errorBytes = [8]byte{}
+}
+
+// Set the simulated pack current in A, positive when charging
+func (sim *Simulator) SetCurrent(current float64) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.config.Current = current
+}
+
+// Set the CRC corruption probability (0..1)
+func (sim *Simulator) SetCRCErrorRate(rate float64) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.config.CRCErrorRate = rate
+}
+
+// Write handles one request frame and queues the response frames
+func (sim *Simulator) Write(frame []byte) (int, error) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if sim.closed {
+		return 0, fmt.Errorf("simulator closed")
+	}
+	request, err := protocol.Decode(frame)
+	if errors.Is(err, protocol.ErrChecksum) {
+		// a real BMS ignores corrupted requests
+		return len(frame), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid request frame %x: %w", frame, err)
+	}
+
+	command := byte(request.Command)
+	for _, data := range sim.respond(command, request.Data[:]) {
+		sim.queueFrame(command, data)
+	}
+	sim.readyAt = time.Now().Add(sim.jitter())
+	return len(frame), nil
+}
+
+// Read returns queued response bytes, or 0 bytes when nothing is pending (like a serial read timeout).
+// With Jitter set it waits up to 20ms for the next chunk.
+func (sim *Simulator) Read(b []byte) (int, error) {
+	sim.mutex.Lock()
+	wait := time.Until(sim.readyAt)
+	sim.mutex.Unlock()
+	if wait > 0 {
+		time.Sleep(min(wait, simulatorReadTimeout))
+	}
+
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if sim.closed {
+		return 0, fmt.Errorf("simulator closed")
+	}
+	if time.Now().Before(sim.readyAt) {
+		return 0, nil
+	}
+	if sim.config.ChunkSize > 0 && len(b) > sim.config.ChunkSize {
+		b = b[:1+sim.random.Intn(sim.config.ChunkSize)]
+	}
+	bytesRead := copy(b, sim.pending)
+	sim.pending = sim.pending[bytesRead:]
+	if bytesRead > 0 {
+		sim.readyAt = time.Now().Add(sim.jitter())
+	}
+	return bytesRead, nil
+}
+
+// jitter returns a random delay up to Jitter
+func (sim *Simulator) jitter() time.Duration {
+	if sim.config.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(sim.random.Int63n(int64(sim.config.Jitter)))
+}
+
+func (sim *Simulator) Close() error {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.closed = true
+	sim.pending = nil
+	return nil
+}
+
+// queueFrame appends a 13-byte response frame, corrupting its CRC according to CRCErrorRate
+func (sim *Simulator) queueFrame(command byte, data [8]byte) {
+	responseFrame := protocol.Frame{Address: protocol.AddressBMS, Command: protocol.Command(command), Data: data}.Encode()
+	if sim.config.CRCErrorRate > 0 && sim.random.Float64() < sim.config.CRCErrorRate {
+		responseFrame[frameLength-1] ^= 0xff
+	}
+	sim.pending = append(sim.pending, responseFrame...)
+}
+
+// drift moves each cell voltage randomly, staying within VoltageDrift of the mean
+func (sim *Simulator) drift() {
+	maxDrift := sim.config.VoltageDrift
+	for cellIndex := range sim.cellVoltages {
+		step := (sim.random.Float64()*2 - 1) * maxDrift / 4
+		voltage := sim.cellVoltages[cellIndex] + step
+		voltage = math.Max(sim.config.CellVoltage-maxDrift, math.Min(sim.config.CellVoltage+maxDrift, voltage))
+		sim.cellVoltages[cellIndex] = voltage
+	}
+}
+
+// cellRange returns the 1-based indexes of the highest and lowest cells
+func (sim *Simulator) cellRange() (int, int) {
+	highest, lowest := 0, 0
+	for cellIndex, voltage := range sim.cellVoltages {
+		if voltage > sim.cellVoltages[highest] {
+			highest = cellIndex
+		}
+		if voltage < sim.cellVoltages[lowest] {
+			lowest = cellIndex
+		}
+	}
+	return highest + 1, lowest + 1
+}
+
+func (sim *Simulator) totalVoltage() float64 {
+	var total float64
+	for _, voltage := range sim.cellVoltages {
+		total += voltage
+	}
+	return total
+}
+
+// configureCells changes the number of cells and sensors, new cells start at the mean voltage
+func (sim *Simulator) configureCells(cells int, sensors int) {
+	for len(sim.cellVoltages) < cells {
+		sim.cellVoltages = append(sim.cellVoltages, sim.config.CellVoltage)
+	}
+	sim.cellVoltages = sim.cellVoltages[:cells]
+	sim.config.NumberOfCells = cells
+	sim.config.NumberOfTemperatureSensors = sensors
+}
+
+// respond builds the data section of the response frames for a command
+func (sim *Simulator) respond(command byte, requestData []byte) [][8]byte {
+	var data [8]byte
+
+	switch protocol.Command(command) {
+	case protocol.CommandSOC:
+		sim.drift()
+		binary.BigEndian.PutUint16(data[0:2], uint16(math.Round(sim.totalVoltage()*10)))
+		binary.BigEndian.PutUint16(data[4:6], uint16(int(math.Round(sim.config.Current*10))+30000))
+		binary.BigEndian.PutUint16(data[6:8], uint16(math.Round(sim.config.SOCPercent*10)))
+
+	case protocol.CommandCellVoltageRange:
+		if len(sim.cellVoltages) == 0 {
+			break
+		}
+		highest, lowest := sim.cellRange()
+		binary.BigEndian.PutUint16(data[0:2], uint16(math.Round(sim.cellVoltages[highest-1]*1000)))
+		data[2] = byte(highest)
+		binary.BigEndian.PutUint16(data[3:5], uint16(math.Round(sim.cellVoltages[lowest-1]*1000)))
+		data[5] = byte(lowest)
+
+	case protocol.CommandTemperatureRange:
+		rawTemperature := byte(int(math.Round(sim.config.Temperature)) + 40)
+		data[0], data[1], data[2], data[3] = rawTemperature, 1, rawTemperature, 1
+
+	case protocol.CommandMosfetStatus:
+		switch {
+		case sim.config.Current > 0:
+			data[0] = 1
+		case sim.config.Current < 0:
+			data[0] = 2
+		}
+		data[1] = boolByte(sim.chargeMosfet)
+		data[2] = boolByte(sim.dischargeMosfet)
+		remainingMilliAh := sim.config.CapacityAh * sim.config.SOCPercent / 100 * 1000
+		binary.BigEndian.PutUint32(data[4:8], uint32(math.Round(remainingMilliAh)))
+
+	case protocol.CommandStatus:
+		data[0] = byte(sim.config.NumberOfCells)
+		data[1] = byte(sim.config.NumberOfTemperatureSensors)
+		data[2] = boolByte(sim.config.Current > 0)
+		data[3] = boolByte(sim.config.Current < 0)
+		binary.BigEndian.PutUint16(data[5:7], uint16(sim.config.CycleCount))
+
+	case protocol.CommandCellVoltages:
+		var frames [][8]byte
+		for firstCell := 0; firstCell < len(sim.cellVoltages); firstCell += 3 {
+			var frame [8]byte
+			frame[0] = byte(len(frames) + 1)
+			for offset := 0; offset < 3 && firstCell+offset < len(sim.cellVoltages); offset++ {
+				millivolts := uint16(math.Round(sim.cellVoltages[firstCell+offset] * 1000))
+				binary.BigEndian.PutUint16(frame[1+offset*2:3+offset*2], millivolts)
+			}
+			frames = append(frames, frame)
+		}
+		return frames
+
+	case protocol.CommandTemperatures:
+		var frames [][8]byte
+		for firstSensor := 0; firstSensor < sim.config.NumberOfTemperatureSensors; firstSensor += 7 {
+			var frame [8]byte
+			frame[0] = byte(len(frames) + 1)
+			for offset := 0; offset < 7 && firstSensor+offset < sim.config.NumberOfTemperatureSensors; offset++ {
+				frame[1+offset] = byte(int(math.Round(sim.config.Temperature)) + 40)
+			}
+			frames = append(frames, frame)
+		}
+		return frames
+
+	case protocol.CommandBalancingStatus:
+		// cells above the mean are balancing, cell 1 is the least significant bit
+		var balancingBits uint64
+		for cellIndex, voltage := range sim.cellVoltages {
+			if voltage > sim.config.CellVoltage+sim.config.VoltageDrift/2 {
+				balancingBits |= 1 << cellIndex
+			}
+		}
+		binary.BigEndian.PutUint64(data[:], balancingBits)
+
+	case protocol.CommandFirmwareVersion:
+		return textFrames(sim.config.FirmwareVersion, 2)
+
+	case protocol.CommandHardwareVersion:
+		return textFrames(sim.config.HardwareVersion, 2)
+
+	case protocol.CommandBatteryCode:
+		return textFrames(sim.config.BatteryCode, 5)
+
+	case protocol.CommandErrors:
+		data = sim.errorBytes
+
+	case protocol.CommandRatedParams, protocol.CommandBatteryConfig,
+		protocol.CommandCellVoltageThresholds, protocol.CommandPackVoltageThresholds, protocol.CommandCurrentThresholds,
+		protocol.CommandChargeTemperatureThresholds, protocol.CommandDischargeTemperatureThresholds,
+		protocol.CommandDifferenceThresholds, protocol.CommandBalanceSettings:
+		data = sim.parameters[command]
+
+	case protocol.CommandSetRatedParams, protocol.CommandSetBatteryConfig,
+		protocol.CommandSetCellVoltageThresholds, protocol.CommandSetPackVoltageThresholds, protocol.CommandSetCurrentThresholds,
+		protocol.CommandSetChargeTemperatureThresholds, protocol.CommandSetDischargeTemperatureThresholds,
+		protocol.CommandSetDifferenceThresholds, protocol.CommandSetBalanceSettings:
+		copy(data[:], requestData)
+		sim.parameters[command+protocol.ParameterWriteOffset] = data
+		switch protocol.Command(command) {
+		case protocol.CommandSetRatedParams:
+			sim.config.CapacityAh = float64(binary.BigEndian.Uint32(data[0:4])) / 1000
+		case protocol.CommandSetBatteryConfig:
+			sim.configureCells(int(data[1])+int(data[2])+int(data[3]), int(data[4])+int(data[5])+int(data[6]))
+		}
+
+	case protocol.CommandSetDischargeMosfet:
+		sim.dischargeMosfet = requestData[0] == 1
+		copy(data[:], requestData)
+
+	case protocol.CommandSetChargeMosfet:
+		sim.chargeMosfet = requestData[0] == 1
+		copy(data[:], requestData)
+
+	case protocol.CommandSetSOC:
+		sim.config.SOCPercent = float64(binary.BigEndian.Uint16(requestData[6:8])) / 10
+		copy(data[:], requestData)
+
+	case protocol.CommandReset:
+		// restart, acknowledged with an empty frame
+
+	case protocol.CommandFactoryReset:
+		sim.parameters = defaultSimulatorParameters(sim.config)
+
+	default:
+		// unknown commands get no response
+		return nil
+	}
+
+	return [][8]byte{data}
+}
+
+// defaultSimulatorParameters returns typical LiFePO4 protection parameters in their raw form
+func defaultSimulatorParameters(config SimulatorConfig) map[byte][8]byte {
+	encode := func(values ...uint16) [8]byte {
+		var data [8]byte
+		for valueIndex, value := range values {
+			binary.BigEndian.PutUint16(data[valueIndex*2:], value)
+		}
+		return data
+	}
+	cells := uint16(config.NumberOfCells)
+
+	var rated [8]byte
+	binary.BigEndian.PutUint32(rated[0:4], uint32(math.Round(config.CapacityAh*1000)))
+	binary.BigEndian.PutUint16(rated[4:6], uint16(math.Round(config.CellVoltage*1000)))
+
+	return map[byte][8]byte{
+		0x50: rated,                                                           // mAh, mV
+		0x51: {1, byte(cells), 0, 0, byte(config.NumberOfTemperatureSensors)}, // boards, cells and sensors per board
+		0x59: encode(3650, 3750, 2800, 2500),                                  // mV
+		0x5a: encode(cells*36, cells*37, cells*28, cells*25),                  // 0.1V
+		0x5b: encode(30000+500, 30000+1000, 30000-1000, 30000-1500),           // 0.1A, 30000 offset
+		0x5c: {55 + 40, 60 + 40, 0 + 40, 40 - 5},                              // °C + 40
+		0x5d: {60 + 40, 65 + 40, 40 - 10, 40 - 20},                            // °C + 40
+		0x5e: {0x00, 0xc8, 0x01, 0x2c, 10, 15},                                // 200mV, 300mV, 10°C, 15°C
+		0x5f: encode(3400, 30),                                                // balance start and delta, mV
+	}
+}
+
+// textFrames splits an ASCII value in numbered frames of 7 chars, padded with zeros
+func textFrames(text string, numberOfFrames int) [][8]byte {
+	frames := make([][8]byte, numberOfFrames)
+	for frameIndex := range frames {
+		frames[frameIndex][0] = byte(frameIndex + 1)
+		if start := frameIndex * 7; start < len(text) {
+			copy(frames[frameIndex][1:], text[start:])
+		}
+	}
+	return frames
+}
+
+func boolByte(value bool) byte {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package dalybms
+
+import (
+	"testing"
+	"time"
+)
+
+// A WiFi UART bridge delivers responses a byte at a time with gaps longer than the serial read
+// timeout: with the link latency options reads must reassemble the frames without resyncing.
+func TestGetAllDataOverJitteryBridge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes a few seconds of simulated link delays")
+	}
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{"latency", []Option{WithLatency(200 * time.Millisecond)}},
+		{"wifi bridge", []Option{WithWiFiBridge()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultSimulatorConfig()
+			config.Jitter = 40 * time.Millisecond
+			config.ChunkSize = 1
+			config.Seed = 1
+
+			bms := NewClient(test.options...)
+			if err := bms.ConnectTransport(NewSimulator(config)); err != nil {
+				t.Fatalf("ConnectTransport: %v", err)
+			}
+			defer bms.Disconnect()
+
+			data, err := bms.GetAllData()
+			if err != nil {
+				t.Fatalf("GetAllData: %v", err)
+			}
+			if len(data.CellVoltages) != config.NumberOfCells {
+				t.Errorf("got %d cell voltages, want %d", len(data.CellVoltages), config.NumberOfCells)
+			}
+			if len(data.Temperatures) != config.NumberOfTemperatureSensors {
+				t.Errorf("got %d temperatures, want %d", len(data.Temperatures), config.NumberOfTemperatureSensors)
+			}
+
+			stats := bms.GetLinkStats()
+			if stats.CRCErrors != 0 || stats.HeaderErrors != 0 || stats.Timeouts != 0 || stats.Retries != 0 {
+				t.Errorf("link stats = %+v, want no resync, timeout or retry", stats)
+			}
+		})
+	}
+}
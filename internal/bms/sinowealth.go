@@ -0,0 +1,411 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Wire protocol spoken by the board
+type Protocol int
+
+const (
+	ProtocolDaly       Protocol = iota // 0xA5 frames, most Daly boards
+	ProtocolSinowealth                 // register reads of Daly-branded boards based on Sinowealth chips
+	ProtocolAuto                       // detected on first use, see DetectProtocol()
+)
+
+func (protocol Protocol) String() string {
+	switch protocol {
+	case ProtocolDaly:
+		return "daly"
+	case ProtocolSinowealth:
+		return "sinowealth"
+	case ProtocolAuto:
+		return "auto"
+	}
+	return "unknown"
+}
+
+// Protocols are serialized by name, eg "sinowealth"
+func (protocol Protocol) MarshalText() ([]byte, error) {
+	return []byte(protocol.String()), nil
+}
+
+// ErrUnsupported is returned for requests the protocol of the board does not offer
+var ErrUnsupported = errors.New("not supported by the BMS protocol")
+
+// Select the wire protocol, ProtocolDaly by default
+func WithProtocol(protocol Protocol) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.protocol = protocol
+	}
+}
+
+// Sinowealth registers. A request is 0x0A, register, data length. The response is the
+// data followed by a CRC-8 (polynomial 0x07) of the request and the data. Values are big-endian.
+const (
+	sinowealthStartByte         = 0x0a
+	sinowealthRegStatus         = 0x00 // byte 1: bit 0 charge MOSFET, bit 1 discharge MOSFET, bit 2 charging, bit 3 discharging
+	sinowealthRegPackConfig     = 0x04 // byte 1 bits 0-2: number of cells - 3
+	sinowealthRegRemainingCap   = 0x0b // mAh, int32
+	sinowealthRegFullCapacity   = 0x0e // mAh, int32
+	sinowealthRegCycleCount     = 0x0f // uint16
+	sinowealthRegSOC            = 0x2a // byte 1: %
+	sinowealthRegTotalVoltage   = 0x2b // mV, uint16
+	sinowealthRegCurrent        = 0x2c // mA, int32, positive when charging
+	sinowealthRegTemperature1   = 0x2f // 0.1 K, uint16, external sensor 1
+	sinowealthRegTemperature2   = 0x30 // 0.1 K, uint16, external sensor 2
+	sinowealthRegFirstCell      = 0x34 // mV, uint16, one register per cell
+	sinowealthTemperatureSensor = 2
+)
+
+// DetectProtocol probes the board with a Daly SOC request, then with a Sinowealth SOC read,
+// and uses the protocol that answered for the following requests
+func (bms *DalyBMSIstance) DetectProtocol() (Protocol, error) {
+	return bms.DetectProtocolCtx(context.Background())
+}
+
+// DetectProtocol with cancellation support
+func (bms *DalyBMSIstance) DetectProtocolCtx(ctx context.Context) (Protocol, error) {
+	detected, err := bms.probeProtocol(ctx)
+	if err != nil {
+		return ProtocolAuto, err
+	}
+	bms.stateMutex.Lock()
+	bms.protocol = detected
+	bms.stateMutex.Unlock()
+	bms.logf("Detected %s protocol", detected)
+	return detected, nil
+}
+
+func (bms *DalyBMSIstance) probeProtocol(ctx context.Context) (Protocol, error) {
+	for attempt := 0; attempt < bms.retryPolicy.attempts(); attempt++ {
+		if response, err := bms.readSerialResponseCtx(ctx, protocol.CommandSOC, nil, 1, false, nil); err == nil && response != nil {
+			return ProtocolDaly, nil
+		}
+		if _, err := bms.sinowealthReadOnce(ctx, sinowealthRegSOC, 2); err == nil {
+			return ProtocolSinowealth, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return ProtocolAuto, err
+		}
+	}
+	return ProtocolAuto, fmt.Errorf("no answer to Daly or Sinowealth requests")
+}
+
+// activeProtocol returns the protocol in use, detecting it first with ProtocolAuto
+func (bms *DalyBMSIstance) activeProtocol(ctx context.Context) (Protocol, error) {
+	bms.stateMutex.Lock()
+	protocol := bms.protocol
+	bms.stateMutex.Unlock()
+
+	if protocol != ProtocolAuto {
+		return protocol, nil
+	}
+	return bms.DetectProtocolCtx(ctx)
+}
+
+// isSinowealth reports whether requests must go through the Sinowealth protocol
+func (bms *DalyBMSIstance) isSinowealth(ctx context.Context) (bool, error) {
+	protocol, err := bms.activeProtocol(ctx)
+	if err == nil {
+		err = checkTarget(ctx, protocol)
+	}
+	return protocol == ProtocolSinowealth, err
+}
+
+// sinowealthRead reads a register according to the retry policy
+func (bms *DalyBMSIstance) sinowealthRead(ctx context.Context, register byte, length int) ([]byte, error) {
+	policy := bms.retryPolicy.forCommand(register)
+	var lastErr error
+	for attemptIndex := 0; attemptIndex < policy.attempts(); attemptIndex++ {
+		if attemptIndex > 0 {
+			bms.countLink(func(stats *LinkStats) { stats.Retries++ })
+			sleepCtx(ctx, policy.delay(attemptIndex))
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("register %02x cancelled: %w", register, err)
+		}
+
+		data, err := bms.sinowealthReadOnce(ctx, register, length)
+		if err == nil {
+			return data, nil
+		}
+		bms.logf("Attempt %d for register %02x failed: %v", attemptIndex+1, register, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("register %02x failed after %d tries: %w", register, policy.attempts(), lastErr)
+}
+
+func (bms *DalyBMSIstance) sinowealthReadOnce(ctx context.Context, register byte, length int) ([]byte, error) {
+	batch := bms.batchFrom(ctx)
+	if batch == nil {
+		bms.busMutex.Lock()
+		defer bms.busMutex.Unlock()
+	}
+	if bms.transport == nil {
+		return nil, fmt.Errorf("transport not connected")
+	}
+	// responses are not Daly frames
+	bms.stopBackgroundReader()
+	if err := bms.drainReadBuffer(); err != nil {
+		bms.logf("Warning: draining buffer: %v", err)
+	}
+
+	request := []byte{sinowealthStartByte, register, byte(length)}
+	if err := bms.waitCommandDelay(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := bms.transport.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to write register %02x request: %w", register, err)
+	}
+	bms.observeFrame(DirectionTX, request)
+	bms.countLink(func(stats *LinkStats) { stats.FramesSent++ })
+
+	response := make([]byte, 0, length+1)
+	readBuffer := make([]byte, length+1)
+	lastActivity := time.Now()
+	for len(response) < length+1 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		bytesRead, err := bms.transport.Read(readBuffer[:length+1-len(response)])
+		if err == nil && bytesRead == 0 && bms.awaitingData(lastActivity) {
+			continue
+		}
+		if err != nil || bytesRead == 0 {
+			bms.countLink(func(stats *LinkStats) { stats.Timeouts++ })
+			return nil, ErrTimeout
+		}
+		response = append(response, readBuffer[:bytesRead]...)
+		lastActivity = time.Now()
+	}
+	bms.observeFrame(DirectionRX, response)
+
+	if computed := crc8(append(request, response[:length]...)); computed != response[length] {
+		bms.countLink(func(stats *LinkStats) { stats.CRCErrors++ })
+		return nil, fmt.Errorf("CRC mismatch: computed %02x != %02x", computed, response[length])
+	}
+	bms.countLink(func(stats *LinkStats) { stats.FramesReceived++ })
+	return response[:length], nil
+}
+
+// crc8 with polynomial 0x07, initial value 0
+func crc8(data []byte) byte {
+	var crc byte
+	for _, value := range data {
+		crc ^= value
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func (bms *DalyBMSIstance) sinowealthUint16(ctx context.Context, register byte) (uint16, error) {
+	data, err := bms.sinowealthRead(ctx, register, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(data), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthInt32(ctx context.Context, register byte) (int32, error) {
+	data, err := bms.sinowealthRead(ctx, register, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(data)), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthStatus(ctx context.Context) (*StatusData, error) {
+	config, err := bms.sinowealthRead(ctx, sinowealthRegPackConfig, 2)
+	if err != nil {
+		return nil, err
+	}
+	status, err := bms.sinowealthRead(ctx, sinowealthRegStatus, 2)
+	if err != nil {
+		return nil, err
+	}
+	cycles, err := bms.sinowealthUint16(ctx, sinowealthRegCycleCount)
+	if err != nil {
+		return nil, err
+	}
+
+	statusData := &StatusData{
+		NumberOfCells:              int(config[1]&0x07) + 3,
+		NumberOfTemperatureSensors: sinowealthTemperatureSensor,
+		IsChargerRunning:           status[1]&0x04 != 0,
+		IsLoadRunning:              status[1]&0x08 != 0,
+		States:                     map[string]bool{},
+		CycleCount:                 int16(cycles),
+		Time:                       time.Now(),
+	}
+
+	bms.cacheStatus(ctx, statusData)
+	return statusData, nil
+}
+
+func (bms *DalyBMSIstance) sinowealthSOC(ctx context.Context) (*SOCData, error) {
+	voltage, err := bms.sinowealthUint16(ctx, sinowealthRegTotalVoltage)
+	if err != nil {
+		return nil, err
+	}
+	current, err := bms.sinowealthInt32(ctx, sinowealthRegCurrent)
+	if err != nil {
+		return nil, err
+	}
+	soc, err := bms.sinowealthRead(ctx, sinowealthRegSOC, 2)
+	if err != nil {
+		return nil, err
+	}
+	socData := &SOCData{
+		TotalVoltage: float32(voltage) / 1000,
+		Current:      float32(CurrentFromAmps(float64(current) / 1000).Amps()),
+		SOCPercent:   float32(soc[1]),
+		Time:         time.Now(),
+	}
+	return bms.roundSOC(bms.applyCurrentSign(socData), sinowealthResolution), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthMosfetStatus(ctx context.Context) (*MosfetStatusData, error) {
+	status, err := bms.sinowealthRead(ctx, sinowealthRegStatus, 2)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := bms.sinowealthInt32(ctx, sinowealthRegRemainingCap)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := "stationary"
+	if status[1]&0x04 != 0 {
+		mode = "charging"
+	} else if status[1]&0x08 != 0 {
+		mode = "discharging"
+	}
+	mosfetStatus := &MosfetStatusData{
+		Mode:              mode,
+		ChargingMosfet:    status[1]&0x01 != 0,
+		DischargingMosfet: status[1]&0x02 != 0,
+		CapacityAh:        float32(remaining) / 1000,
+		Time:              time.Now(),
+	}
+	bms.detectMosfetActions(ctx, mosfetStatus)
+	return bms.roundMosfetStatus(mosfetStatus, sinowealthResolution), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthCellVoltages(ctx context.Context) (map[int]float64, error) {
+	status := bms.cachedStatus(ctx)
+	if status == nil {
+		var err error
+		if status, err = bms.sinowealthStatus(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	voltages := make(map[int]float64, status.NumberOfCells)
+	for cellIndex := 1; cellIndex <= status.NumberOfCells; cellIndex++ {
+		millivolts, err := bms.sinowealthUint16(ctx, sinowealthRegFirstCell+byte(cellIndex-1))
+		if err != nil {
+			return nil, err
+		}
+		voltages[cellIndex] = float64(millivolts) / 1000
+	}
+	return bms.roundCellVoltages(voltages, sinowealthResolution), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthTemperatures(ctx context.Context) (map[int]float64, error) {
+	temperatures := make(map[int]float64)
+	for sensorIndex, register := range []byte{sinowealthRegTemperature1, sinowealthRegTemperature2} {
+		decikelvin, err := bms.sinowealthUint16(ctx, register)
+		if err != nil {
+			return nil, err
+		}
+		temperatures[sensorIndex+1] = float64(TemperatureFromCelsius(float64(decikelvin)/10 - 273.15))
+	}
+	return temperatures, nil
+}
+
+func (bms *DalyBMSIstance) sinowealthCellVoltageRange(ctx context.Context) (*CellVoltageRangeData, error) {
+	voltages, err := bms.sinowealthCellVoltages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rangeData := &CellVoltageRangeData{Time: time.Now()}
+	for cellIndex, voltage := range voltages {
+		if rangeData.HighestCell == 0 || float32(voltage) > rangeData.HighestVoltage {
+			rangeData.HighestVoltage, rangeData.HighestCell = float32(voltage), int8(cellIndex)
+		}
+		if rangeData.LowestCell == 0 || float32(voltage) < rangeData.LowestVoltage {
+			rangeData.LowestVoltage, rangeData.LowestCell = float32(voltage), int8(cellIndex)
+		}
+	}
+	rangeData.HighestCellLabel = bms.CellLabel(int(rangeData.HighestCell))
+	rangeData.LowestCellLabel = bms.CellLabel(int(rangeData.LowestCell))
+	return bms.roundCellVoltageRange(rangeData, sinowealthResolution), nil
+}
+
+func (bms *DalyBMSIstance) sinowealthTemperatureRange(ctx context.Context) (*TemperatureRangeData, error) {
+	temperatures, err := bms.sinowealthTemperatures(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rangeData := &TemperatureRangeData{Time: time.Now()}
+	for sensorIndex, temperature := range temperatures {
+		if rangeData.HighestSensor == 0 || float32(temperature) > rangeData.HighestTemperature {
+			rangeData.HighestTemperature, rangeData.HighestSensor = float32(temperature), int8(sensorIndex)
+		}
+		if rangeData.LowestSensor == 0 || float32(temperature) < rangeData.LowestTemperature {
+			rangeData.LowestTemperature, rangeData.LowestSensor = float32(temperature), int8(sensorIndex)
+		}
+	}
+	return bms.convertTemperatureRange(bms.roundTemperatureRange(rangeData, sinowealthResolution)), nil
+}
+
+// sinowealthAllData reads everything the protocol offers. Balancing and errors are not available and left nil.
+func (bms *DalyBMSIstance) sinowealthAllData(ctx context.Context) (*AllBMSData, error) {
+	data := &AllBMSData{SchemaVersion: SchemaVersion, Time: time.Now(), TemperatureUnit: bms.temperatureUnit, Rounded: bms.rounding}
+	var err error
+	if data.Status, err = bms.sinowealthStatus(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("status")
+	if data.SOC, err = bms.sinowealthSOC(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("soc")
+	if data.MosfetStatus, err = bms.sinowealthMosfetStatus(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("mosfet_status")
+	if data.CellVoltageRange, err = bms.sinowealthCellVoltageRange(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("cell_voltage_range")
+	if data.TemperatureRange, err = bms.sinowealthTemperatureRange(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("temperature_range")
+	if data.CellVoltages, err = bms.sinowealthCellVoltages(ctx); err != nil {
+		return nil, err
+	}
+	data.markRead("cell_voltages")
+	if data.Temperatures, err = bms.sinowealthTemperatures(ctx); err != nil {
+		return nil, err
+	}
+	bms.convertTemperatures(data.Temperatures)
+	data.MOSTemperature = bms.mosTemperature(data.Temperatures)
+	data.markRead("temperatures")
+	data.CellLabels = bms.cellLabels(data.Status.NumberOfCells)
+	return data, nil
+}
@@ -0,0 +1,129 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// Default wait after the wake frame before the request, see WithWakeOnIdle() and Wake()
+const DefaultWakeDelay = 200 * time.Millisecond
+
+// Command code that puts the board to sleep, for Sleep(). The published protocol has none and
+// the code varies with the firmware: take it from the board documentation or a capture of the
+// Daly app. Without it Sleep() returns ErrUnsupported.
+func WithSleepCommand(command protocol.Command) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.sleepCommand = &command
+	}
+}
+
+// Send a wake frame before a request when nothing was written for idle, and before the first
+// request after Connect() or Sleep(). Sleeping UART boards drop the frame that wakes them, so
+// without it the first poll after a quiet period times out. delay is the wait between the wake
+// frame and the request, DefaultWakeDelay if 0. 0 idle disables it (default).
+func WithWakeOnIdle(idle time.Duration, delay time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.wakeIdle = idle
+		bms.wakeDelay = delay
+	}
+}
+
+// Put the BMS to sleep, it stops answering until woken, see Wake() and WithWakeOnIdle().
+// Returns ErrUnsupported without WithSleepCommand().
+func (bms *DalyBMSIstance) Sleep() error {
+	return bms.SleepCtx(context.Background())
+}
+
+// Sleep with cancellation support
+func (bms *DalyBMSIstance) SleepCtx(ctx context.Context) error {
+	if bms.sleepCommand == nil {
+		return fmt.Errorf("sleep: %w", ErrUnsupported)
+	}
+	if err := bms.authorizeWrite(ctx); err != nil {
+		return err
+	}
+
+	// boards may go to sleep without answering, a missing response isn't an error
+	response, err := bms.readSerialResponseCtx(ctx, *bms.sleepCommand, nil, 1, false, nil)
+	if err != nil {
+		return err
+	}
+	bms.logf("Sleep response: %x\n", response)
+
+	bms.busMutex.Lock()
+	bms.asleep = true
+	bms.busMutex.Unlock()
+	return nil
+}
+
+// Wake the BMS with a wake frame and check that it answers again
+func (bms *DalyBMSIstance) Wake() error {
+	return bms.WakeCtx(context.Background())
+}
+
+// Wake with cancellation support
+func (bms *DalyBMSIstance) WakeCtx(ctx context.Context) error {
+	err := func() error {
+		// in a batch the bus is already held, see beginBatch()
+		if bms.batchFrom(ctx) == nil {
+			bms.busMutex.Lock()
+			defer bms.busMutex.Unlock()
+		}
+		if bms.transport == nil {
+			return fmt.Errorf("transport not connected")
+		}
+		return bms.sendWake(ctx)
+	}()
+	if err != nil {
+		return err
+	}
+
+	if _, err := bms.GetSOCCtx(ctx); err != nil {
+		return fmt.Errorf("no answer after the wake frame: %w", err)
+	}
+	return nil
+}
+
+// wakeIfIdle sends the wake frame when WithWakeOnIdle() is set and the bus has been idle
+// long enough, or the BMS was put to sleep. Must be called with busMutex held.
+func (bms *DalyBMSIstance) wakeIfIdle(ctx context.Context) error {
+	if bms.wakeIdle <= 0 || (!bms.asleep && time.Since(bms.lastWrite) < bms.wakeIdle) {
+		return nil
+	}
+	bms.logf("Bus idle since %s, sending wake frame", bms.lastWrite.Format(time.TimeOnly))
+	return bms.sendWake(ctx)
+}
+
+// sendWake writes a SOC request the board may drop while waking, waits the wake delay and
+// discards whatever answered it. Must be called with busMutex held.
+func (bms *DalyBMSIstance) sendWake(ctx context.Context) error {
+	wakeFrame, err := protocol.EncodeRequest(bms.requestAddress(ctx), protocol.CommandSOC, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build wake frame: %w", err)
+	}
+	if err := bms.waitCommandDelay(ctx); err != nil {
+		return fmt.Errorf("wake cancelled: %w", err)
+	}
+	if bytesWritten, err := bms.transport.Write(wakeFrame); err != nil || bytesWritten != len(wakeFrame) {
+		return fmt.Errorf("failed to write wake frame to transport")
+	}
+	bms.observeFrame(DirectionTX, wakeFrame)
+	bms.countLink(func(stats *LinkStats) { stats.FramesSent++ })
+
+	delay := bms.wakeDelay
+	if delay <= 0 {
+		delay = DefaultWakeDelay
+	}
+	sleepCtx(ctx, delay)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("wake cancelled: %w", err)
+	}
+	if err := bms.drainReadBuffer(); err != nil {
+		bms.logf("Warning: draining buffer: %v", err)
+	}
+	bms.asleep = false
+	return nil
+}
@@ -0,0 +1,79 @@
+package dalybms
+
+import (
+	"math"
+	"strconv"
+)
+
+// Values derived from a full read, see AllBMSData.Stats()
+type Stats struct {
+	AverageCellVoltage float64 `json:"average_cell_voltage"`
+	CellVoltageDelta   float64 `json:"cell_voltage_delta"` // highest - lowest cell voltage
+	AverageTemperature float64 `json:"average_temperature"`
+	PowerW             float64 `json:"power_w"`             // positive when charging, whatever the sign convention
+	RemainingEnergyWh  float64 `json:"remaining_energy_wh"` // remaining capacity at the current pack voltage
+}
+
+// Stats computes derived values. Values whose source data is missing are left at 0.
+func (data *AllBMSData) Stats() *Stats {
+	stats := &Stats{
+		AverageCellVoltage: average(data.CellVoltages),
+		AverageTemperature: average(data.Temperatures),
+	}
+
+	if len(data.CellVoltages) > 0 {
+		lowest, highest := valueRange(data.CellVoltages)
+		stats.CellVoltageDelta = math.Round((highest-lowest)*1000) / 1000 // readings have mV resolution
+	} else if data.CellVoltageRange != nil {
+		stats.CellVoltageDelta = Widen(data.CellVoltageRange.HighestVoltage) - Widen(data.CellVoltageRange.LowestVoltage)
+	}
+
+	if data.SOC != nil {
+		stats.PowerW = Widen(data.SOC.TotalVoltage) * data.SOC.ChargeCurrent()
+		if data.MosfetStatus != nil {
+			stats.RemainingEnergyWh = Widen(data.MosfetStatus.CapacityAh) * Widen(data.SOC.TotalVoltage)
+		}
+	}
+
+	if data.Rounded {
+		stats.round()
+	}
+	return stats
+}
+
+// Average temperature of all sensors, eg from GetTemperatures()
+func GetPackTemperatureAverage(temperatures map[int]float64) float64 {
+	return average(temperatures)
+}
+
+func average(values map[int]float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func valueRange(values map[int]float64) (lowest float64, highest float64) {
+	first := true
+	for _, value := range values {
+		if first || value < lowest {
+			lowest = value
+		}
+		if first || value > highest {
+			highest = value
+		}
+		first = false
+	}
+	return lowest, highest
+}
+
+// Widen converts a reading to float64 without float32 rounding noise, eg 52.4 instead of 52.400001525878906.
+// Use it before scaling readings for other protocols.
+func Widen(value float32) float64 {
+	widened, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return widened
+}
@@ -0,0 +1,19 @@
+package dalybms
+
+import "testing"
+
+func TestStatsPowerIgnoresCurrentSignConvention(t *testing.T) {
+	tests := []struct {
+		name string
+		soc  SOCData
+	}{
+		{"charge positive", SOCData{TotalVoltage: 50, Current: -10, CurrentConvention: ChargePositive}},
+		{"discharge positive", SOCData{TotalVoltage: 50, Current: 10, CurrentConvention: DischargePositive}},
+	}
+	for _, test := range tests {
+		data := &AllBMSData{SOC: &test.soc}
+		if power := data.Stats().PowerW; power != -500 {
+			t.Errorf("%s: PowerW = %v, want -500 while discharging", test.name, power)
+		}
+	}
+}
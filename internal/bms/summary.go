@@ -0,0 +1,85 @@
+package dalybms
+
+import (
+	"context"
+)
+
+// Compact pack overview, see GetPackSummary()
+type PackSummary struct {
+	Voltage            float64 `json:"voltage"` // V
+	Current            float64 `json:"current"` // A, positive when charging, see WithCurrentSignConvention()
+	PowerW             float64 `json:"power_w"` // positive when charging, whatever the sign convention, like Stats()
+	SOCPercent         float64 `json:"soc_percent"`
+	HighestCellVoltage float64 `json:"highest_cell_voltage"` // V
+	HighestCell        int     `json:"highest_cell"`
+	LowestCellVoltage  float64 `json:"lowest_cell_voltage"` // V
+	LowestCell         int     `json:"lowest_cell"`
+	CellVoltageDelta   float64 `json:"cell_voltage_delta"`  // V
+	HighestTemperature float64 `json:"highest_temperature"` // °C
+	LowestTemperature  float64 `json:"lowest_temperature"`  // °C
+	ErrorCount         int     `json:"error_count"`
+	WorstError         string  `json:"worst_error,omitempty"` // first alarm, else first warning
+	Alarm              bool    `json:"alarm"`                 // WorstError is a level two error or hardware failure
+}
+
+// Get a pack overview from 4 requests (SOC, cell voltage range, temperature range, errors)
+// instead of the full sweep of GetAllData(). Errors are not available on Sinowealth boards.
+func (bms *DalyBMSIstance) GetPackSummary() (*PackSummary, error) {
+	return bms.GetPackSummaryCtx(context.Background())
+}
+
+// GetPackSummary with cancellation support
+func (bms *DalyBMSIstance) GetPackSummaryCtx(ctx context.Context) (*PackSummary, error) {
+	ctx, release := bms.beginBatch(ctx)
+	defer release()
+
+	soc, err := bms.GetSOCCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cellRange, err := bms.GetCellVoltageRangeCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	temperatureRange, err := bms.GetTemperatureRangeCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errorList BMSErrors
+	sinowealth, err := bms.isSinowealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !sinowealth {
+		if errorList, err = bms.GetErrorsCtx(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := &PackSummary{
+		Voltage:            Widen(soc.TotalVoltage),
+		Current:            Widen(soc.Current),
+		SOCPercent:         Widen(soc.SOCPercent),
+		HighestCellVoltage: Widen(cellRange.HighestVoltage),
+		HighestCell:        int(cellRange.HighestCell),
+		LowestCellVoltage:  Widen(cellRange.LowestVoltage),
+		LowestCell:         int(cellRange.LowestCell),
+		HighestTemperature: Widen(temperatureRange.HighestTemperature),
+		LowestTemperature:  Widen(temperatureRange.LowestTemperature),
+		ErrorCount:         len(errorList),
+	}
+	summary.PowerW = roundTo(summary.Voltage*soc.ChargeCurrent(), 2)
+	summary.CellVoltageDelta = roundTo(summary.HighestCellVoltage-summary.LowestCellVoltage, 3)
+
+	for _, bmsError := range errorList {
+		if bmsError.Severity == SeverityAlarm {
+			summary.WorstError, summary.Alarm = bmsError.Message, true
+			break
+		}
+		if summary.WorstError == "" {
+			summary.WorstError = bmsError.Message
+		}
+	}
+	return summary, nil
+}
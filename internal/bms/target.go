@@ -0,0 +1,113 @@
+package dalybms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonamat/go-daly-bms/protocol"
+)
+
+// requestTarget overrides the addresses of the client for the requests made with a context
+type requestTarget struct {
+	requestAddress  byte // address byte of the request frames
+	responseAddress byte
+	err             error // invalid board number, returned by every request
+}
+
+type requestTargetKey struct{}
+
+// AtAddress returns a context whose requests go to another BMS on the same bus, eg
+// client.GetSOCCtx(dalybms.AtAddress(ctx, 4, 0x02)). address is the request address like
+// WithAddress(), responseAddress the source address of the answers like WithResponseAddress().
+// The state the client keeps for its own BMS is left alone: the target's cell and sensor counts
+// are cached apart, and MOSFET/alarm hooks and the error history ignore these reads.
+func AtAddress(ctx context.Context, address int, responseAddress byte) context.Context {
+	return context.WithValue(ctx, requestTargetKey{}, requestTarget{requestAddress: byte(address << 4), responseAddress: responseAddress})
+}
+
+// AtBoard is AtAddress() for a board number of a parallel system, from 1 to protocol.MaxBoard.
+// Requests made with an invalid number fail with protocol.ErrInvalidBoard.
+func AtBoard(ctx context.Context, board int) context.Context {
+	requestAddress, responseAddress, err := protocol.BoardAddresses(board)
+	return context.WithValue(ctx, requestTargetKey{}, requestTarget{requestAddress: requestAddress, responseAddress: responseAddress, err: err})
+}
+
+// targetFrom returns the target set with AtAddress(), false when requests go to the client's BMS
+func targetFrom(ctx context.Context) (requestTarget, bool) {
+	target, ok := ctx.Value(requestTargetKey{}).(requestTarget)
+	return target, ok
+}
+
+// isTargeted reports whether ctx sends the requests to another BMS than the client's
+func isTargeted(ctx context.Context) bool {
+	_, ok := targetFrom(ctx)
+	return ok
+}
+
+// requestAddress returns the address byte of the request frames made with ctx
+func (bms *DalyBMSIstance) requestAddress(ctx context.Context) byte {
+	if target, ok := targetFrom(ctx); ok {
+		return target.requestAddress
+	}
+	return byte(bms.address << 4)
+}
+
+// expectedResponseAddress returns the source address the answers to ctx must come from, 0 = any
+func (bms *DalyBMSIstance) expectedResponseAddress(ctx context.Context) byte {
+	if target, ok := targetFrom(ctx); ok {
+		return target.responseAddress
+	}
+	return bms.responseAddress
+}
+
+// checkTarget fails for invalid boards, and for AtAddress() requests over Sinowealth, a protocol
+// without addresses
+func checkTarget(ctx context.Context, protocol Protocol) error {
+	target, ok := targetFrom(ctx)
+	if !ok {
+		return nil
+	}
+	if target.err != nil {
+		return target.err
+	}
+	if protocol == ProtocolSinowealth {
+		return fmt.Errorf("request address: %w", ErrUnsupported)
+	}
+	return nil
+}
+
+// Get all data of a board of a parallel system, see AtBoard()
+func (bms *DalyBMSIstance) GetBoardData(board int) (*AllBMSData, error) {
+	return bms.GetBoardDataCtx(context.Background(), board)
+}
+
+// GetBoardData with cancellation support
+func (bms *DalyBMSIstance) GetBoardDataCtx(ctx context.Context, board int) (*AllBMSData, error) {
+	data, err := bms.GetAllDataCtx(AtBoard(ctx, board))
+	if err != nil {
+		return nil, fmt.Errorf("board %d: %w", board, err)
+	}
+	data.Board = board
+	return data, nil
+}
+
+// Get all data of boards 1 to boards of a parallel system, keyed by board number. Boards that
+// fail are missing from the data and their errors are returned by board number.
+func (bms *DalyBMSIstance) GetParallelData(boards int) (map[int]*AllBMSData, map[int]error) {
+	return bms.GetParallelDataCtx(context.Background(), boards)
+}
+
+// GetParallelData with cancellation support
+func (bms *DalyBMSIstance) GetParallelDataCtx(ctx context.Context, boards int) (map[int]*AllBMSData, map[int]error) {
+	boardData := make(map[int]*AllBMSData, boards)
+	boardErrors := make(map[int]error)
+	for board := protocol.FirstBoard; board <= boards; board++ {
+		data, err := bms.GetBoardDataCtx(ctx, board)
+		if err != nil {
+			boardErrors[board] = err
+			continue
+		}
+		boardData[board] = data
+	}
+	return boardData, boardErrors
+}
@@ -0,0 +1,245 @@
+package dalybms
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Remote serial ports exposed over TCP (ser2net, ESPHome stream server, RS485 to Ethernet
+// converters). "tcp://host:port" is a raw byte stream, "rfc2217://host:port" is a Telnet
+// session that also carries the serial settings (RFC 2217), so the baud rate of SerialConfig
+// is applied on the remote port.
+
+// Telnet bytes
+const (
+	telnetIAC  = 0xff
+	telnetDONT = 0xfe
+	telnetDO   = 0xfd
+	telnetWONT = 0xfc
+	telnetWILL = 0xfb
+	telnetSB   = 0xfa
+	telnetSE   = 0xf0
+
+	telnetBinary          = 0x00
+	telnetSuppressGoAhead = 0x03
+	telnetComPortOption   = 0x2c
+)
+
+// RFC 2217 client commands
+const (
+	comPortSetBaudRate = 1
+	comPortSetDataSize = 2
+	comPortSetParity   = 3
+	comPortSetStopSize = 4
+)
+
+const tcpDefaultDialTimeout = 5 * time.Second
+
+// IsNetworkAddress reports whether the path passed to Connect is a tcp:// or rfc2217:// URL
+func IsNetworkAddress(path string) bool {
+	return strings.HasPrefix(path, "tcp://") || strings.HasPrefix(path, "rfc2217://")
+}
+
+// DialTCPTransport connects to a remote serial port, eg "tcp://192.168.1.50:2000" or
+// "rfc2217://192.168.1.50:2001". Reads time out after config.ReadTimeout; the other
+// serial settings are only sent with rfc2217://.
+func DialTCPTransport(ctx context.Context, address string, config SerialConfig) (Transport, error) {
+	if _, err := config.toPortConfig(address); err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if parsed.Scheme != "tcp" && parsed.Scheme != "rfc2217" {
+		return nil, fmt.Errorf("unsupported scheme %q, use tcp:// or rfc2217://", parsed.Scheme)
+	}
+	if parsed.Port() == "" {
+		return nil, fmt.Errorf("missing port in %q", address)
+	}
+
+	dialer := &net.Dialer{Timeout: tcpDefaultDialTimeout}
+	connection, err := dialer.DialContext(ctx, "tcp", parsed.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", parsed.Host, err)
+	}
+	if tcpConnection, ok := connection.(*net.TCPConn); ok {
+		// frames are small, send them right away
+		tcpConnection.SetNoDelay(true)
+	}
+
+	transport := &tcpTransport{connection: connection, readTimeout: config.ReadTimeout}
+	if parsed.Scheme == "rfc2217" {
+		transport.telnet = true
+		if err := transport.negotiate(config); err != nil {
+			connection.Close()
+			return nil, fmt.Errorf("RFC 2217 negotiation with %s failed: %w", parsed.Host, err)
+		}
+	}
+	return transport, nil
+}
+
+// tcpTransport adapts a TCP connection to the Transport read timeout semantics
+type tcpTransport struct {
+	connection  net.Conn
+	readTimeout time.Duration
+
+	// Telnet decoding state, kept across reads since sequences can be split
+	telnet       bool
+	pending      []byte
+	subnegotiate bool
+}
+
+// Read returns 0 bytes without error when nothing arrives within the read timeout
+func (transport *tcpTransport) Read(buffer []byte) (int, error) {
+	for {
+		transport.connection.SetReadDeadline(time.Now().Add(transport.readTimeout))
+		bytesRead, err := transport.connection.Read(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return 0, nil
+			}
+			return 0, err
+		}
+		if !transport.telnet {
+			return bytesRead, nil
+		}
+
+		// strip Telnet commands, retry if the chunk carried no data
+		if bytesRead = transport.decodeTelnet(buffer[:bytesRead]); bytesRead > 0 {
+			return bytesRead, nil
+		}
+	}
+}
+
+// Write escapes 0xff in Telnet mode, a frame CRC can take that value
+func (transport *tcpTransport) Write(data []byte) (int, error) {
+	if !transport.telnet {
+		return transport.connection.Write(data)
+	}
+
+	escaped := make([]byte, 0, len(data)+2)
+	for _, value := range data {
+		escaped = append(escaped, value)
+		if value == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+	}
+	if _, err := transport.connection.Write(escaped); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (transport *tcpTransport) Close() error {
+	return transport.connection.Close()
+}
+
+// negotiate enables binary mode and the COM port option, then sends the serial settings
+func (transport *tcpTransport) negotiate(config SerialConfig) error {
+	request := []byte{
+		telnetIAC, telnetWILL, telnetBinary,
+		telnetIAC, telnetDO, telnetBinary,
+		telnetIAC, telnetWILL, telnetSuppressGoAhead,
+		telnetIAC, telnetDO, telnetSuppressGoAhead,
+		telnetIAC, telnetWILL, telnetComPortOption,
+	}
+
+	baudRate := binary.BigEndian.AppendUint32(nil, uint32(config.BaudRate))
+	request = appendComPortCommand(request, comPortSetBaudRate, baudRate...)
+	request = appendComPortCommand(request, comPortSetDataSize, config.DataBits)
+	parity := map[Parity]byte{ParityNone: 1, ParityOdd: 2, ParityEven: 3}[config.Parity]
+	request = appendComPortCommand(request, comPortSetParity, parity)
+	request = appendComPortCommand(request, comPortSetStopSize, byte(config.StopBits))
+
+	transport.connection.SetWriteDeadline(time.Now().Add(tcpDefaultDialTimeout))
+	defer transport.connection.SetWriteDeadline(time.Time{})
+	_, err := transport.connection.Write(request)
+	return err
+}
+
+// appendComPortCommand appends IAC SB COM-PORT-OPTION command value IAC SE
+func appendComPortCommand(data []byte, command byte, value ...byte) []byte {
+	data = append(data, telnetIAC, telnetSB, telnetComPortOption, command)
+	for _, char := range value {
+		data = append(data, char)
+		if char == telnetIAC {
+			data = append(data, telnetIAC)
+		}
+	}
+	return append(data, telnetIAC, telnetSE)
+}
+
+// decodeTelnet removes Telnet commands from data in place and returns the payload length.
+// Option requests from the server are refused, except the ones asked for in negotiate.
+func (transport *tcpTransport) decodeTelnet(data []byte) int {
+	input := append(transport.pending, data...)
+	transport.pending = nil
+
+	var replies []byte
+	payload := data[:0]
+	for index := 0; index < len(input); index++ {
+		value := input[index]
+		if value != telnetIAC {
+			if !transport.subnegotiate {
+				payload = append(payload, value)
+			}
+			continue
+		}
+
+		if index+1 >= len(input) {
+			transport.pending = append(transport.pending, input[index:]...)
+			break
+		}
+		command := input[index+1]
+		switch command {
+		case telnetIAC:
+			if !transport.subnegotiate {
+				payload = append(payload, telnetIAC)
+			}
+			index++
+		case telnetSB:
+			transport.subnegotiate = true
+			index++
+		case telnetSE:
+			transport.subnegotiate = false
+			index++
+		case telnetDO, telnetDONT, telnetWILL, telnetWONT:
+			if index+2 >= len(input) {
+				transport.pending = append(transport.pending, input[index:]...)
+				index = len(input)
+				break
+			}
+			replies = append(replies, telnetReply(command, input[index+2])...)
+			index += 2
+		default:
+			// NOP, GA and other two byte commands
+			index++
+		}
+	}
+
+	if len(replies) > 0 {
+		transport.connection.Write(replies)
+	}
+	return len(payload)
+}
+
+// telnetReply answers an option request, nil when no answer is due
+func telnetReply(command, option byte) []byte {
+	accepted := option == telnetBinary || option == telnetSuppressGoAhead || option == telnetComPortOption
+	switch {
+	case command == telnetDO && !accepted:
+		return []byte{telnetIAC, telnetWONT, option}
+	case command == telnetWILL && !accepted:
+		return []byte{telnetIAC, telnetDONT, option}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package dalybms
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Direction of a frame on the wire
+type Direction int
+
+const (
+	DirectionTX Direction = iota // sent to the BMS
+	DirectionRX                  // received from the BMS
+)
+
+func (direction Direction) String() string {
+	if direction == DirectionTX {
+		return "tx"
+	}
+	return "rx"
+}
+
+// FrameObserver is called for every frame sent or received, including corrupted ones.
+// It runs while the bus is held, or on the goroutine of WithBackgroundReader(), so it should
+// return quickly. frame is a copy.
+type FrameObserver func(direction Direction, frame []byte)
+
+// Set a callback invoked for every transmitted and received frame, nil disables it
+func (bms *DalyBMSIstance) SetFrameObserver(observer FrameObserver) {
+	bms.busMutex.Lock()
+	defer bms.busMutex.Unlock()
+	bms.stateMutex.Lock()
+	defer bms.stateMutex.Unlock()
+	bms.frameObserver = observer
+}
+
+// Observe every transmitted and received frame, see SetFrameObserver()
+func WithFrameObserver(observer FrameObserver) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.frameObserver = observer
+	}
+}
+
+// observeFrame notifies the frame observer
+func (bms *DalyBMSIstance) observeFrame(direction Direction, frame []byte) {
+	bms.stateMutex.Lock()
+	observer := bms.frameObserver
+	bms.stateMutex.Unlock()
+	if observer != nil {
+		observer(direction, append([]byte(nil), frame...))
+	}
+}
+
+// NewFrameTraceWriter returns an observer writing one line per frame to writer,
+// eg "2025-03-01T10:00:00.123456789Z tx a540900800000000000000007d". See ReadFrameTrace().
+func NewFrameTraceWriter(writer io.Writer) FrameObserver {
+	var mutex sync.Mutex
+	return func(direction Direction, frame []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		fmt.Fprintf(writer, "%s %s %x\n", time.Now().UTC().Format(time.RFC3339Nano), direction, frame)
+	}
+}
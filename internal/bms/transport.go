@@ -0,0 +1,36 @@
+package dalybms
+
+import (
+	"context"
+	"io"
+)
+
+// Transport carries the raw Daly protocol frames (serial port, TCP bridge, BLE, CAN adapter, mock...).
+// Read must not block forever: when no data is available it should return after a short
+// timeout with 0 bytes (or an error), like a serial port configured with a read timeout.
+type Transport interface {
+	io.ReadWriteCloser
+}
+
+// ConnectTransport uses an already opened transport instead of a serial device path
+func (bms *DalyBMSIstance) ConnectTransport(transport Transport) error {
+	return bms.ConnectTransportCtx(context.Background(), transport)
+}
+
+// ConnectTransport with cancellation support. ctx also bounds the initial status fetch.
+func (bms *DalyBMSIstance) ConnectTransportCtx(ctx context.Context, transport Transport) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bms.busMutex.Lock()
+	bms.stopBackgroundReader()
+	bms.transport = transport
+	bms.asleep = true // unknown until a request, see WithWakeOnIdle()
+	bms.busMutex.Unlock()
+	bms.forgetAuthentication()
+
+	// Optionally fetch initial status once connected
+	_, _ = bms.GetStatusCtx(ctx)
+	return nil
+}
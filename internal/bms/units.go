@@ -0,0 +1,309 @@
+package dalybms
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Typed readings holding the integer value the BMS works with, so they compare and print
+// exactly (3.279 V instead of 3.2790000438690186). The float fields of the data structs
+// are kept for compatibility, the ...Value() accessors return these types.
+
+// Voltage in millivolts
+type Voltage int32
+
+// Current in 0.1A steps, positive when charging unless WithCurrentSignConvention(DischargePositive)
+type Current int32
+
+// Temperature in °C, whatever WithTemperatureUnit()
+type Temperature int16
+
+// VoltageFromVolts rounds a value in volts to the nearest millivolt
+func VoltageFromVolts(volts float64) Voltage {
+	return Voltage(math.Round(volts * 1000))
+}
+
+func (voltage Voltage) Volts() float64 {
+	return float64(voltage) / 1000
+}
+
+func (voltage Voltage) Millivolts() int32 {
+	return int32(voltage)
+}
+
+// Eg "3.279 V"
+func (voltage Voltage) String() string {
+	return strconv.FormatFloat(voltage.Volts(), 'f', -1, 64) + " V"
+}
+
+// CurrentFromAmps rounds a value in amperes to the nearest 0.1A
+func CurrentFromAmps(amps float64) Current {
+	return Current(math.Round(amps * 10))
+}
+
+func (current Current) Amps() float64 {
+	return float64(current) / 10
+}
+
+func (current Current) Deciamps() int32 {
+	return int32(current)
+}
+
+func (current Current) Milliamps() int32 {
+	return int32(current) * 100
+}
+
+// Eg "-12.5 A"
+func (current Current) String() string {
+	return strconv.FormatFloat(current.Amps(), 'f', -1, 64) + " A"
+}
+
+// TemperatureFromCelsius rounds a value in °C to the nearest degree
+func TemperatureFromCelsius(celsius float64) Temperature {
+	return Temperature(math.Round(celsius))
+}
+
+func (temperature Temperature) Celsius() float64 {
+	return float64(temperature)
+}
+
+func (temperature Temperature) Fahrenheit() float64 {
+	return float64(temperature)*9/5 + 32
+}
+
+// Eg "25 °C"
+func (temperature Temperature) String() string {
+	return strconv.Itoa(int(temperature)) + " °C"
+}
+
+// Pack voltage, 0.1V resolution
+func (data *SOCData) TotalVoltageValue() Voltage {
+	return VoltageFromVolts(Widen(data.TotalVoltage))
+}
+
+func (data *SOCData) CurrentValue() Current {
+	return CurrentFromAmps(Widen(data.Current))
+}
+
+// Sign convention of the current, and so of the power
+type CurrentSign int
+
+const (
+	ChargePositive    CurrentSign = iota // positive when charging, like Victron (default)
+	DischargePositive                    // positive when discharging, like a load meter
+)
+
+// "charge_positive" or "discharge_positive"
+func (sign CurrentSign) String() string {
+	if sign == DischargePositive {
+		return "discharge_positive"
+	}
+	return "charge_positive"
+}
+
+func (sign CurrentSign) MarshalText() ([]byte, error) {
+	return []byte(sign.String()), nil
+}
+
+// Accepts the names of String(), empty is ChargePositive
+func (sign *CurrentSign) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "charge_positive", "":
+		*sign = ChargePositive
+	case "discharge_positive":
+		*sign = DischargePositive
+	default:
+		return fmt.Errorf("unknown current sign convention: %s", text)
+	}
+	return nil
+}
+
+// Report current, power and derived values with the given sign. Charge and discharge
+// detection (events, energy counters, Victron) is not affected.
+func WithCurrentSignConvention(sign CurrentSign) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.currentSign = sign
+	}
+}
+
+// ChargeCurrent returns the current in A, positive when charging whatever the sign convention
+func (data *SOCData) ChargeCurrent() float64 {
+	if data.CurrentConvention == DischargePositive {
+		return -Widen(data.Current)
+	}
+	return Widen(data.Current)
+}
+
+// applyCurrentSign converts a charge positive current to the configured convention
+func (bms *DalyBMSIstance) applyCurrentSign(data *SOCData) *SOCData {
+	data.CurrentConvention = bms.currentSign
+	if bms.currentSign == DischargePositive && data.Current != 0 {
+		data.Current = -data.Current
+	}
+	return data
+}
+
+func (data *CellVoltageRangeData) HighestVoltageValue() Voltage {
+	return VoltageFromVolts(Widen(data.HighestVoltage))
+}
+
+func (data *CellVoltageRangeData) LowestVoltageValue() Voltage {
+	return VoltageFromVolts(Widen(data.LowestVoltage))
+}
+
+func (data *TemperatureRangeData) HighestTemperatureValue() Temperature {
+	return TemperatureFromCelsius(data.HighestCelsius())
+}
+
+func (data *TemperatureRangeData) LowestTemperatureValue() Temperature {
+	return TemperatureFromCelsius(data.LowestCelsius())
+}
+
+// Cell voltages by cell index
+func (data *AllBMSData) CellVoltageValues() map[int]Voltage {
+	values := make(map[int]Voltage, len(data.CellVoltages))
+	for cellIndex, volts := range data.CellVoltages {
+		values[cellIndex] = VoltageFromVolts(volts)
+	}
+	return values
+}
+
+// Temperatures by sensor index
+func (data *AllBMSData) TemperatureValues() map[int]Temperature {
+	values := make(map[int]Temperature, len(data.Temperatures))
+	for sensorIndex, temperature := range data.Temperatures {
+		values[sensorIndex] = TemperatureFromCelsius(data.TemperatureUnit.ToCelsius(temperature))
+	}
+	return values
+}
+
+// Unit of the temperatures, see WithTemperatureUnit()
+type TemperatureUnit int
+
+const (
+	Celsius    TemperatureUnit = iota // the unit of the BMS (default)
+	Fahrenheit                        // °F
+	Kelvin                            // K
+)
+
+// Report temperatures in the given unit: ranges, sensors, statistics, alarm thresholds and
+// the JSON output. Charge limits keep their °C curves, Prometheus, InfluxDB, Modbus, SignalK
+// and Victron keep the unit of their schema.
+func WithTemperatureUnit(unit TemperatureUnit) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.temperatureUnit = unit
+	}
+}
+
+func (unit TemperatureUnit) FromCelsius(celsius float64) float64 {
+	switch unit {
+	case Fahrenheit:
+		return celsius*9/5 + 32
+	case Kelvin:
+		return celsius + 273.15
+	}
+	return celsius
+}
+
+func (unit TemperatureUnit) ToCelsius(temperature float64) float64 {
+	switch unit {
+	case Fahrenheit:
+		return (temperature - 32) * 5 / 9
+	case Kelvin:
+		return temperature - 273.15
+	}
+	return temperature
+}
+
+// Eg "°F"
+func (unit TemperatureUnit) Symbol() string {
+	switch unit {
+	case Fahrenheit:
+		return "°F"
+	case Kelvin:
+		return "K"
+	}
+	return "°C"
+}
+
+// "celsius", "fahrenheit" or "kelvin"
+func (unit TemperatureUnit) String() string {
+	switch unit {
+	case Fahrenheit:
+		return "fahrenheit"
+	case Kelvin:
+		return "kelvin"
+	}
+	return "celsius"
+}
+
+func (unit TemperatureUnit) MarshalText() ([]byte, error) {
+	return []byte(unit.String()), nil
+}
+
+// Accepts the names of String() and C, F, K, case insensitive
+func (unit *TemperatureUnit) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "celsius", "c", "":
+		*unit = Celsius
+	case "fahrenheit", "f":
+		*unit = Fahrenheit
+	case "kelvin", "k":
+		*unit = Kelvin
+	default:
+		return fmt.Errorf("unknown temperature unit: %s", text)
+	}
+	return nil
+}
+
+// HighestCelsius returns the highest temperature in °C whatever the unit of the client
+func (data *TemperatureRangeData) HighestCelsius() float64 {
+	return data.Unit.ToCelsius(Widen(data.HighestTemperature))
+}
+
+// LowestCelsius returns the lowest temperature in °C whatever the unit of the client
+func (data *TemperatureRangeData) LowestCelsius() float64 {
+	return data.Unit.ToCelsius(Widen(data.LowestTemperature))
+}
+
+// TemperaturesCelsius returns the sensor temperatures in °C whatever the unit of the client
+func (data *AllBMSData) TemperaturesCelsius() map[int]float64 {
+	if data.TemperatureUnit == Celsius || data.Temperatures == nil {
+		return data.Temperatures
+	}
+	temperatures := make(map[int]float64, len(data.Temperatures))
+	for sensorIndex, temperature := range data.Temperatures {
+		temperatures[sensorIndex] = data.TemperatureUnit.ToCelsius(temperature)
+	}
+	return temperatures
+}
+
+// convertTemperatureRange converts a range read in °C to the configured unit
+func (bms *DalyBMSIstance) convertTemperatureRange(data *TemperatureRangeData) *TemperatureRangeData {
+	data.Unit = bms.temperatureUnit
+	if bms.temperatureUnit != Celsius {
+		data.HighestTemperature = float32(bms.convertTemperature(Widen(data.HighestTemperature)))
+		data.LowestTemperature = float32(bms.convertTemperature(Widen(data.LowestTemperature)))
+	}
+	return data
+}
+
+// convertTemperatures converts sensor temperatures read in °C to the configured unit, in place
+func (bms *DalyBMSIstance) convertTemperatures(temperatures map[int]float64) map[int]float64 {
+	if bms.temperatureUnit != Celsius {
+		for sensorIndex, celsius := range temperatures {
+			temperatures[sensorIndex] = bms.convertTemperature(celsius)
+		}
+	}
+	return temperatures
+}
+
+func (bms *DalyBMSIstance) convertTemperature(celsius float64) float64 {
+	temperature := bms.temperatureUnit.FromCelsius(celsius)
+	if bms.rounding {
+		temperature = roundTo(temperature, 2) // 273.15 and 9/5 add decimals to whole degrees
+	}
+	return temperature
+}
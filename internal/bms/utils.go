@@ -2,211 +2,342 @@ package dalybms
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
-	"log"
 	"math"
 	"time"
+
+	"github.com/jonamat/go-daly-bms/protocol"
 )
 
+// statusCtx returns the cached status, reading it from the BMS if GetStatus() was not called yet
+func (bms *DalyBMSIstance) statusCtx(ctx context.Context) (*StatusData, error) {
+	if status := bms.cachedStatus(ctx); status != nil {
+		return status, nil
+	}
+	status, err := bms.GetStatusCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get_status: %w", err)
+	}
+	return status, nil
+}
+
 // calculateNumberOfResponses determines how many 13-byte response frames we expect
-// for given data (like cells or temperature sensors).
-func (bms *DalyBMSIstance) calculateNumberOfResponses(statusField string, itemCountPerFrame int) (int, error) {
-	if bms.latestStatus == nil {
-		return 0, fmt.Errorf("getStatus must be called before retrieving %s", statusField)
+// for given data (like cells or temperature sensors), reading the status if needed.
+func (bms *DalyBMSIstance) calculateNumberOfResponses(ctx context.Context, statusField string, itemCountPerFrame int) (int, error) {
+	status, err := bms.statusCtx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("retrieving %s: %w", statusField, err)
 	}
 
 	switch statusField {
 	case "cells":
-		// ! bt not supported
-		if bms.address == 8 {
-			// Bluetooth returns all frames up to 16
-			return 16, nil
+		frames := int(math.Ceil(float64(status.NumberOfCells) / float64(itemCountPerFrame)))
+		if bms.requestAddress(ctx) == bleAddress<<4 {
+			// Bluetooth returns at least 16 frames, padded with zeros
+			return max(frames, 16), nil
 		}
-		return int(math.Ceil(float64(bms.latestStatus.NumberOfCells) / float64(itemCountPerFrame))), nil
+		return frames, nil
 
 	case "temperature_sensors":
-		// ! bt not supported
-		if bms.address == 8 {
-			// Bluetooth returns up to 3 frames
-			return 3, nil
+		frames := int(math.Ceil(float64(status.NumberOfTemperatureSensors) / float64(itemCountPerFrame)))
+		if bms.requestAddress(ctx) == bleAddress<<4 {
+			// Bluetooth returns at least 3 frames, padded with zeros
+			return max(frames, 3), nil
 		}
-		return int(math.Ceil(float64(bms.latestStatus.NumberOfTemperatureSensors) / float64(itemCountPerFrame))), nil
+		return frames, nil
 	}
 
 	return 0, fmt.Errorf("unknown status field: %s", statusField)
 }
 
 // splitFramesForData is a helper that unpacks multi-frame responses for cell or temperature data.
+// When frames are missing the values of the others come with a *MissingFramesError.
 func (bms *DalyBMSIstance) splitFramesForData(
+	ctx context.Context,
 	frames [][]byte,
 	statusField string,
 	itemsPerFrame int,
 ) (map[int]float64, error) {
 
-	if bms.latestStatus == nil {
-		return nil, fmt.Errorf("getStatus must be called before retrieving %s", statusField)
+	status := bms.cachedStatus(ctx)
+	if status == nil {
+		return nil, fmt.Errorf("status unavailable for %s", statusField)
 	}
 
 	var needed int
 	if statusField == "cells" {
-		needed = bms.latestStatus.NumberOfCells
+		needed = status.NumberOfCells
 	} else if statusField == "temperature_sensors" {
-		needed = bms.latestStatus.NumberOfTemperatureSensors
+		needed = status.NumberOfTemperatureSensors
 	} else {
 		return nil, fmt.Errorf("unknown field: %s", statusField)
 	}
 
-	results := make(map[int]float64)
-	expectedFrameIndex := 1
+	itemSize, command := 2, protocol.CommandCellVoltages // cells: int16 mV, temperature sensors: int8 °C + 40
+	if statusField == "temperature_sensors" {
+		itemSize, command = 1, protocol.CommandTemperatures
+	}
+	values, missingFrames := decodeFrameValues(frames, needed, itemsPerFrame, itemSize)
+	if len(missingFrames) > 0 {
+		return values, &MissingFramesError{Command: command, Frames: missingFrames}
+	}
+	return values, nil
+}
 
-	for _, frame := range frames {
-		if len(frame) < 1 {
-			// skip
-			continue
-		}
+// sendReadRequestCtx is a higher-level function that retries the readSerialResponseCtx
+// according to the retry policy, giving up as soon as ctx is done or the response
+// timeout expires (ErrTimeout).
+func (bms *DalyBMSIstance) sendReadRequestCtx(
+	ctx context.Context,
+	command protocol.Command,
+	payload []byte,
+	maxResponses int,
+	returnList bool,
+) (interface{}, error) {
 
-		frameNumber := int(frame[0])
-		if frameNumber != expectedFrameIndex {
-			log.Printf("splitFramesForData warning: expected frame=%d, got frame=%d", expectedFrameIndex, frameNumber)
-		}
+	return bms.streamReadRequestCtx(ctx, command, payload, maxResponses, returnList, nil)
+}
 
-		frameReader := bytes.NewReader(frame[1:]) // skip the frame index byte
-		for itemIndex := 0; itemIndex < itemsPerFrame; itemIndex++ {
-			// "cells": we read int16 each
-			// "temperature_sensors": we read int8 each
-			if statusField == "cells" {
-				var cellValue int16
-				if err := binary.Read(frameReader, binary.BigEndian, &cellValue); err != nil {
-					break
-				}
-				results[len(results)+1] = float64(cellValue)
-			} else {
-				var temperatureValue int8
-				if err := binary.Read(frameReader, binary.BigEndian, &temperatureValue); err != nil {
-					break
-				}
-				results[len(results)+1] = float64(temperatureValue)
-			}
+// streamReadRequestCtx is sendReadRequestCtx also passing the data bytes of every
+// response frame to onFrame as soon as it is read. onFrame may be nil. After a retry,
+// frames already seen in a failed attempt are passed again.
+func (bms *DalyBMSIstance) streamReadRequestCtx(
+	ctx context.Context,
+	command protocol.Command,
+	payload []byte,
+	maxResponses int,
+	returnList bool,
+	onFrame func(data []byte),
+) (interface{}, error) {
 
-			if len(results) == needed {
-				// We have all items
-				return results, nil
-			}
-		}
-		expectedFrameIndex++
+	if sinowealth, err := bms.isSinowealth(ctx); err != nil {
+		return nil, err
+	} else if sinowealth {
+		return nil, fmt.Errorf("command %s: %w", command.Hex(), ErrUnsupported)
+	}
+
+	if bms.responseTimeout <= 0 {
+		return bms.sendWithRetries(ctx, command, payload, maxResponses, returnList, onFrame)
 	}
 
-	return results, nil
+	deadlineCtx, cancel := context.WithTimeout(ctx, bms.responseTimeout)
+	defer cancel()
+
+	result, err := bms.sendWithRetries(deadlineCtx, command, payload, maxResponses, returnList, onFrame)
+	if err != nil && ctx.Err() == nil && deadlineCtx.Err() != nil {
+		// our own deadline expired, not the caller's
+		return nil, fmt.Errorf("command %s: %w after %s", command.Hex(), ErrTimeout, bms.responseTimeout)
+	}
+	return result, err
 }
 
-// sendReadRequest is a higher-level function that retries the readSerialResponse
-// up to bms.requestRetries times.
-func (bms *DalyBMSIstance) sendReadRequest(
-	command string,
-	extraHexData string,
+// sendWithRetries tries readSerialResponseCtx according to the retry policy of the command
+func (bms *DalyBMSIstance) sendWithRetries(
+	ctx context.Context,
+	command protocol.Command,
+	payload []byte,
 	maxResponses int,
 	returnList bool,
+	onFrame func(data []byte),
 ) (interface{}, error) {
 
 	var finalResult interface{}
 	var finalErr error
 
-	for attemptIndex := 0; attemptIndex < bms.requestRetries; attemptIndex++ {
-		readResult, readErr := bms.readSerialResponse(command, extraHexData, maxResponses, returnList)
+	policy := bms.retryPolicy.forCommand(byte(command))
+	for attemptIndex := 0; attemptIndex < policy.attempts(); attemptIndex++ {
+		if attemptIndex > 0 {
+			bms.countLink(func(stats *LinkStats) { stats.Retries++ })
+			sleepCtx(ctx, policy.delay(attemptIndex))
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("command %s cancelled: %w", command.Hex(), err)
+		}
+
+		readResult, readErr := bms.readSerialResponseCtx(ctx, command, payload, maxResponses, returnList, onFrame)
 		if readErr != nil {
-			log.Printf("Attempt %d for command %s failed: %v", attemptIndex+1, command, readErr)
-			time.Sleep(200 * time.Millisecond)
+			if ctx.Err() != nil {
+				return nil, readErr
+			}
+			bms.logf("Attempt %d for command %s failed: %v", attemptIndex+1, command.Hex(), readErr)
 			finalErr = readErr
 			continue
 		}
 		if readResult == nil {
-			log.Printf("Attempt %d for command %s returned nil response; retrying", attemptIndex+1, command)
-			time.Sleep(200 * time.Millisecond)
-			finalErr = fmt.Errorf("nil response")
+			bms.countLink(func(stats *LinkStats) { stats.Timeouts++ })
+			bms.logf("Attempt %d for command %s returned nil response; retrying", attemptIndex+1, command.Hex())
+			finalErr = ErrTimeout
 			continue
 		}
 		// success
 		return readResult, nil
 	}
-	return finalResult, fmt.Errorf("command %s failed after %d tries: %w", command, bms.requestRetries, finalErr)
+	return finalResult, fmt.Errorf("command %s failed after %d tries: %w", command.Hex(), policy.attempts(), finalErr)
+}
+
+// sleepCtx waits for the given duration or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// Responses split over frames numbered from 1 in their first data byte
+var numberedFrameCommands = map[protocol.Command]bool{
+	protocol.CommandCellVoltages:    true,
+	protocol.CommandTemperatures:    true,
+	protocol.CommandBatteryCode:     true,
+	protocol.CommandFirmwareVersion: true,
+	protocol.CommandHardwareVersion: true,
 }
 
-// readSerialResponse writes a command to the BMS and attempts to read a specified
-// number of 13-byte responses. If returnList is false, and we only get one response,
+// readSerialResponseCtx writes a command to the BMS and attempts to read a specified
+// number of 13-byte responses, see frameReader. If returnList is false, and we only get one response,
 // we return the raw 8 data bytes. If multiple frames are returned or returnList=true,
-// we return a slice of slices.
-func (bms *DalyBMSIstance) readSerialResponse(
-	command string,
-	extraHexData string,
+// we return a slice of slices. ctx is checked between frames. onFrame, if not nil, receives
+// the data bytes of each frame as it arrives.
+func (bms *DalyBMSIstance) readSerialResponseCtx(
+	ctx context.Context,
+	command protocol.Command,
+	payload []byte,
 	maxResponses int,
 	returnList bool,
+	onFrame func(data []byte),
 ) (interface{}, error) {
 
-	if bms.serialPort == nil {
-		return nil, fmt.Errorf("serial port not open")
+	// in a batch the bus is already held, see beginBatch()
+	batch := bms.batchFrom(ctx)
+	if batch == nil {
+		bms.busMutex.Lock()
+		defer bms.busMutex.Unlock()
+	}
+
+	if bms.transport == nil {
+		return nil, fmt.Errorf("transport not connected")
 	}
 
-	requestFrame, err := bms.buildRequestFrame(command, extraHexData)
+	requestFrame, err := protocol.EncodeRequest(bms.requestAddress(ctx), command, payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build request frame: %w", err)
 	}
+	bms.startBackgroundReader()
 
-	// Drain any leftover data.
-	if err := bms.drainReadBuffer(); err != nil {
-		// not fatal, just log
-		log.Printf("Warning: draining buffer: %v", err)
+	// Drain any leftover data. Within a batch only after a failed exchange.
+	if batch == nil || batch.dirty {
+		if err := bms.drainReadBuffer(); err != nil {
+			// not fatal, just log
+			bms.logf("Warning: draining buffer: %v", err)
+		}
+	}
+	if batch != nil {
+		// cleared once the exchange completes
+		batch.dirty = true
 	}
 
+	// Frames read from now on answer this request
+	bms.awaitingResponse.Store(true)
+	defer bms.awaitingResponse.Store(false)
+
 	// Write out the command.
-	bytesWritten, err := bms.serialPort.Write(requestFrame)
+	if err := bms.wakeIfIdle(ctx); err != nil {
+		return nil, fmt.Errorf("command %s: %w", command.Hex(), err)
+	}
+	if err := bms.waitCommandDelay(ctx); err != nil {
+		return nil, fmt.Errorf("command %s cancelled: %w", command.Hex(), err)
+	}
+	bytesWritten, err := bms.transport.Write(requestFrame)
 	if err != nil || bytesWritten != len(requestFrame) {
-		return nil, fmt.Errorf("failed to write command %s to serial port", command)
+		return nil, fmt.Errorf("failed to write command %s to transport", command.Hex())
 	}
+	bms.observeFrame(DirectionTX, requestFrame)
+	bms.countLink(func(stats *LinkStats) { stats.FramesSent++ })
 
 	var collectedData [][]byte
+	seenFrames := make(map[byte]bool)
+	responseAddress := bms.expectedResponseAddress(ctx)
+	reader := &frameReader{bms: bms, transport: bms.transport, lastActivity: time.Now()}
 
-	// Each full response is 13 bytes: 4 for header, 8 for data, 1 for CRC
-	for frameIndex := 0; frameIndex < maxResponses; frameIndex++ {
-		readBuffer := make([]byte, 13)
-		bytesRead, readErr := bms.serialPort.Read(readBuffer)
-		if readErr != nil || bytesRead == 0 {
+	for len(collectedData) < maxResponses {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("command %s cancelled: %w", command.Hex(), err)
+		}
+
+		responseFrame, err := bms.nextFrame(ctx, reader)
+		if err != nil {
+			return nil, fmt.Errorf("command %s: %w", command.Hex(), err)
+		}
+		if responseFrame == nil {
 			// Probably a timeout or no more data
 			break
 		}
 
-		if bytesRead < 13 {
-			// partial read
-			log.Printf("Partial response for command %s: got %d bytes (expected 13)", command, bytesRead)
-			break
+		// Half-duplex adapters loop the request back before the response
+		if !bms.keepEcho && bytes.Equal(responseFrame, requestFrame) {
+			bms.countLink(func(stats *LinkStats) { stats.Echoes++ })
+			continue
 		}
 
-		// Check CRC
-		computedCRC := computeCRC(readBuffer[:12])
-		if computedCRC != readBuffer[12] {
-			log.Printf("CRC mismatch for command %s: computed %02x != %02x", command, computedCRC, readBuffer[12])
+		// Validate the source address, another BMS may answer on a multi-drop bus
+		if responseAddress != 0 && responseFrame[1] != responseAddress {
+			if bms.foreignFrames != nil {
+				bms.foreignFrames(responseFrame[1], responseFrame)
+				continue
+			}
+			reason := fmt.Sprintf("address %02x, expected %02x", responseFrame[1], responseAddress)
+			bms.countLink(func(stats *LinkStats) { stats.HeaderErrors++ })
+			if bms.strictFrames {
+				return nil, fmt.Errorf("command %s: %w", command.Hex(), &FrameError{Err: ErrHeaderMismatch, Frame: responseFrame, Reason: reason})
+			}
+			bms.logf("Dropping response for command %s: %s", command.Hex(), reason)
 			continue
 		}
 
-		// Validate the command nibble in header
-		headerHex := fmt.Sprintf("%02x%02x%02x%02x", readBuffer[0], readBuffer[1], readBuffer[2], readBuffer[3])
-		if len(headerHex) >= 6 && headerHex[4:6] != command {
-			log.Printf("Invalid header for command %s: got %s (mismatched command code)", command, headerHex)
+		// Validate the command code in header
+		if protocol.Command(responseFrame[2]) != command {
+			if bms.routeUnsolicited(responseFrame) {
+				continue
+			}
+			bms.countLink(func(stats *LinkStats) { stats.HeaderErrors++ })
+			if bms.strictFrames {
+				reason := fmt.Sprintf("command %02x, expected %s", responseFrame[2], command.Hex())
+				return nil, fmt.Errorf("command %s: %w", command.Hex(), &FrameError{Err: ErrHeaderMismatch, Frame: responseFrame, Reason: reason})
+			}
+			bms.logf("Invalid header for command %s: got %x (mismatched command code)", command.Hex(), responseFrame[:4])
 			continue
 		}
 
-		// The 8 data bytes are readBuffer[4:12]
-		dataBytes := readBuffer[4:12]
-		collectedData = append(collectedData, dataBytes)
+		// Numbered responses count frames from 1, a repeated frame doesn't take a slot
+		if numberedFrameCommands[command] {
+			frameNumber := responseFrame[4]
+			if frameNumber == 0 || int(frameNumber) > maxResponses {
+				bms.logf("Dropping frame %d of command %s, expected 1-%d", frameNumber, command.Hex(), maxResponses)
+				continue
+			}
+			if seenFrames[frameNumber] {
+				bms.logf("Dropping repeated frame %d of command %s", frameNumber, command.Hex())
+				continue
+			}
+			seenFrames[frameNumber] = true
+		}
 
-		if len(collectedData) == maxResponses {
-			break
+		// The 8 data bytes are responseFrame[4:12]
+		collectedData = append(collectedData, responseFrame[4:12])
+		if onFrame != nil {
+			onFrame(responseFrame[4:12])
 		}
 	}
 
 	if len(collectedData) == 0 {
 		return nil, nil
 	}
+	if batch != nil {
+		batch.dirty = false
+	}
 
 	// If multiple frames or returnList is explicitly requested
 	if returnList || len(collectedData) > 1 {
@@ -217,33 +348,20 @@ func (bms *DalyBMSIstance) readSerialResponse(
 	return collectedData[0], nil
 }
 
-// buildRequestFrame constructs the hex message plus CRC for a command like "90" with optional extra data.
-// The result is a 13-byte packet: 12 bytes (in hex form) + 1-byte CRC.
-func (bms *DalyBMSIstance) buildRequestFrame(command string, extraHex string) ([]byte, error) {
-	// Example: "a5[address]0[cmd]08[extra]" => pad to 24 hex digits => then 1-byte CRC.
-	// e.g. "a5409008000000000000000000" + CRC => 13 total bytes.
-
-	hexString := fmt.Sprintf("a5%x0%s08%s", bms.address, command, extraHex)
-
-	// Pad out to 24 hex characters
-	for len(hexString) < 24 {
-		hexString += "0"
+// drainReadBuffer attempts to read any leftover data so it doesn't mix with new responses.
+// Must be called with busMutex held.
+func (bms *DalyBMSIstance) drainReadBuffer() error {
+	if bms.transport == nil {
+		return fmt.Errorf("drain requested but transport is nil")
 	}
 
-	rawBytes, err := decodeHexString(hexString)
-	if err != nil {
-		return nil, fmt.Errorf("hex decode error: %w", err)
+	if bms.background != nil {
+		bms.background.drain(bms)
+		return nil
 	}
-
-	finalCRC := computeCRC(rawBytes)
-	rawBytes = append(rawBytes, finalCRC)
-	return rawBytes, nil
-}
-
-// drainReadBuffer attempts to read any leftover data so it doesn't mix with new responses.
-func (bms *DalyBMSIstance) drainReadBuffer() error {
-	if bms.serialPort == nil {
-		return fmt.Errorf("drain requested but serialPort is nil")
+	if bms.unsolicitedFrames != nil {
+		bms.drainUnsolicited()
+		return nil
 	}
 
 	leftoverBuffer := make([]byte, 256)
@@ -251,7 +369,7 @@ func (bms *DalyBMSIstance) drainReadBuffer() error {
 	// Repeatedly read until .Read() returns 0 or an error,
 	// meaning there's no more data immediately available in the driver buffer.
 	for {
-		bytesRead, readErr := bms.serialPort.Read(leftoverBuffer)
+		bytesRead, readErr := bms.transport.Read(leftoverBuffer)
 		if readErr != nil || bytesRead == 0 {
 			break
 		}
@@ -259,30 +377,14 @@ func (bms *DalyBMSIstance) drainReadBuffer() error {
 	return nil
 }
 
-// computeCRC sums all bytes and returns the low byte of the sum.
-func computeCRC(message []byte) byte {
-	var sum uint32
-	for _, singleByte := range message {
-		sum += uint32(singleByte)
-	}
-	return byte(sum & 0xFF)
-}
-
-// decodeHexString decodes a hex string to raw bytes.
-func decodeHexString(hexText string) ([]byte, error) {
-	raw := make([]byte, len(hexText)/2)
-	_, err := fmt.Sscanf(hexText, "%x", &raw)
-	if err != nil {
-		return nil, err
-	}
-	return raw, nil
-}
-
-// bigEndianToUint64 interprets a byte slice as a big-endian 64-bit integer.
-func bigEndianToUint64(data []byte) uint64 {
-	var val uint64
-	for _, b := range data {
-		val = (val << 8) | uint64(b)
+// waitCommandDelay waits until the inter-command delay since the previous write has elapsed
+// and records the upcoming write. Must be called with busMutex held.
+func (bms *DalyBMSIstance) waitCommandDelay(ctx context.Context) error {
+	if bms.commandDelay > 0 {
+		if remaining := bms.commandDelay - time.Since(bms.lastWrite); remaining > 0 {
+			sleepCtx(ctx, remaining)
+		}
 	}
-	return val
+	bms.lastWrite = time.Now()
+	return ctx.Err()
 }
@@ -0,0 +1,94 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrWriteNotApplied is returned (wrapped in a *WriteNotAppliedError) when a verified write is
+// acknowledged but the value read back differs, see WithWriteVerification()
+var ErrWriteNotApplied = errors.New("write not applied")
+
+// WriteNotAppliedError holds the value written and the last value read back
+type WriteNotAppliedError struct {
+	Operation string
+	Want      any
+	Got       any
+}
+
+func (notApplied *WriteNotAppliedError) Error() string {
+	return fmt.Sprintf("%s: %v, want %v, read %v", notApplied.Operation, ErrWriteNotApplied, notApplied.Want, notApplied.Got)
+}
+
+func (notApplied *WriteNotAppliedError) Unwrap() error {
+	return ErrWriteNotApplied
+}
+
+// Read back the MOSFET states after EnableChargeMosfet()/EnableDischargeMosfet() (0x93) and the
+// SOC after SetSOC() (0x90), failing with ErrWriteNotApplied when the board acknowledged the
+// write but ignored it. The value is read up to tries times, delay apart, as some boards apply
+// writes a moment after the acknowledgement.
+func WithWriteVerification(tries int, delay time.Duration) Option {
+	return func(bms *DalyBMSIstance) {
+		bms.verifyTries = max(tries, 1)
+		bms.verifyDelay = delay
+	}
+}
+
+// verifyWrite reads back a written value with read until it reports it applied, see
+// WithWriteVerification(). Does nothing without verification.
+func (bms *DalyBMSIstance) verifyWrite(ctx context.Context, operation string, want any, read func(ctx context.Context) (got any, applied bool, err error)) error {
+	if bms.verifyTries == 0 {
+		return nil
+	}
+
+	var got any
+	for attemptIndex := range bms.verifyTries {
+		if attemptIndex > 0 {
+			sleepCtx(ctx, bms.verifyDelay)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var applied bool
+		var err error
+		got, applied, err = read(ctx)
+		if err != nil {
+			return fmt.Errorf("verify %s: %w", operation, err)
+		}
+		if applied {
+			return nil
+		}
+	}
+	return &WriteNotAppliedError{Operation: operation, Want: want, Got: got}
+}
+
+// verifyMosfet reads back the state of the charge or the discharge MOSFET
+func (bms *DalyBMSIstance) verifyMosfet(ctx context.Context, operation string, charge bool, isOn bool) error {
+	return bms.verifyWrite(ctx, operation, isOn, func(ctx context.Context) (any, bool, error) {
+		status, err := bms.GetMosfetStatusCtx(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		state := status.DischargingMosfet
+		if charge {
+			state = status.ChargingMosfet
+		}
+		return state, state == isOn, nil
+	})
+}
+
+// verifySOC reads back the SOC, equal to socPercent within the 0.1% resolution of the protocol
+func (bms *DalyBMSIstance) verifySOC(ctx context.Context, socPercent float64) error {
+	return bms.verifyWrite(ctx, "SetSOC", socPercent, func(ctx context.Context) (any, bool, error) {
+		soc, err := bms.GetSOCCtx(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		return soc.SOCPercent, math.Abs(float64(soc.SOCPercent)-socPercent) < 0.15, nil
+	})
+}
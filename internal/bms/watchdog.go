@@ -0,0 +1,186 @@
+package dalybms
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Critical condition handled by a Watchdog, eg cell above 3.65 V:
+//
+//	WatchdogRule{Name: "cell_overvoltage", Metric: MetricHighestCellVoltage, Condition: AlarmAbove, Threshold: 3.65, DisableCharge: true}
+type WatchdogRule struct {
+	Name             string
+	Metric           AlarmMetric
+	Condition        AlarmCondition
+	Threshold        float64
+	DisableCharge    bool
+	DisableDischarge bool
+}
+
+func (rule *WatchdogRule) tripped(data *AllBMSData) (float64, bool) {
+	if rule.Metric == nil {
+		return 0, false
+	}
+	value, ok := rule.Metric(data)
+	if !ok {
+		return 0, false
+	}
+	if rule.Condition == AlarmBelow {
+		return value, value < rule.Threshold
+	}
+	return value, value > rule.Threshold
+}
+
+// Sent when a rule trips. Err is set when switching the MOSFETs off failed.
+type WatchdogEvent struct {
+	Rule              string    `json:"rule"`
+	Value             float64   `json:"value"`
+	Threshold         float64   `json:"threshold"`
+	Time              time.Time `json:"time"`
+	DisabledCharge    bool      `json:"disabled_charge"`
+	DisabledDischarge bool      `json:"disabled_discharge"`
+	Err               error     `json:"-"`
+}
+
+// Watchdog switches the MOSFETs off when a critical rule trips. Feed it with poller.OnResult(watchdog.Update).
+// A tripped rule stays latched until Reset(): while latched, the watchdog switches the MOSFETs
+// off again whenever a sample shows them on. It never switches them back on.
+type Watchdog struct {
+	bms         *DalyBMSIstance
+	rules       []WatchdogRule
+	mutex       sync.Mutex
+	latched     map[string]WatchdogRule
+	subscribers []chan WatchdogEvent
+}
+
+func NewWatchdog(bms *DalyBMSIstance, rules ...WatchdogRule) *Watchdog {
+	return &Watchdog{
+		bms:     bms,
+		rules:   rules,
+		latched: make(map[string]WatchdogRule),
+	}
+}
+
+// Subscribe returns a channel receiving every event. Events are dropped when the
+// channel buffer is full. The channel is closed by Close().
+func (watchdog *Watchdog) Subscribe(bufferSize int) <-chan WatchdogEvent {
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+
+	channel := make(chan WatchdogEvent, bufferSize)
+	watchdog.subscribers = append(watchdog.subscribers, channel)
+	return channel
+}
+
+// Close closes subscriber channels
+func (watchdog *Watchdog) Close() {
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	for _, channel := range watchdog.subscribers {
+		close(channel)
+	}
+	watchdog.subscribers = nil
+}
+
+// Names of the latched rules
+func (watchdog *Watchdog) Tripped() []string {
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+
+	var names []string
+	for _, rule := range watchdog.rules {
+		if _, ok := watchdog.latched[rule.Name]; ok {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+// Reset releases the latched rules. The MOSFETs are left as they are, switch them on explicitly.
+func (watchdog *Watchdog) Reset() {
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	clear(watchdog.latched)
+}
+
+// Update checks a poll result. Failed polls are skipped.
+func (watchdog *Watchdog) Update(result PollResult) {
+	watchdog.UpdateCtx(context.Background(), result)
+}
+
+// Update with cancellation support for the MOSFET writes
+func (watchdog *Watchdog) UpdateCtx(ctx context.Context, result PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	sampleTime := result.Time
+	if sampleTime.IsZero() {
+		sampleTime = time.Now()
+	}
+
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+
+	var events []WatchdogEvent
+	for _, rule := range watchdog.rules {
+		value, tripped := rule.tripped(result.Data)
+		if !tripped {
+			continue
+		}
+		if _, ok := watchdog.latched[rule.Name]; !ok {
+			watchdog.latched[rule.Name] = rule
+			events = append(events, WatchdogEvent{
+				Rule:              rule.Name,
+				Value:             value,
+				Threshold:         rule.Threshold,
+				Time:              sampleTime,
+				DisabledCharge:    rule.DisableCharge,
+				DisabledDischarge: rule.DisableDischarge,
+			})
+		}
+	}
+
+	var disableCharge, disableDischarge bool
+	for _, rule := range watchdog.latched {
+		disableCharge = disableCharge || rule.DisableCharge
+		disableDischarge = disableDischarge || rule.DisableDischarge
+	}
+	// MOSFETs already off are not written again, unknown states are
+	mosfets := result.Data.MosfetStatus
+	if mosfets != nil {
+		disableCharge = disableCharge && mosfets.ChargingMosfet
+		disableDischarge = disableDischarge && mosfets.DischargingMosfet
+	}
+
+	// switch-offs skip WithControlRateLimit(), a user write just before can't delay the cutoff
+	var errs []error
+	if disableCharge {
+		watchdog.bms.logf("Watchdog switching charge MOSFET off")
+		errs = append(errs, watchdog.bms.EnableChargeMosfetCtx(ctx, false))
+	}
+	if disableDischarge {
+		watchdog.bms.logf("Watchdog switching discharge MOSFET off")
+		errs = append(errs, watchdog.bms.EnableDischargeMosfetCtx(ctx, false))
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		watchdog.bms.logf("Watchdog failed to switch MOSFETs off: %v", err)
+	}
+
+	for _, event := range events {
+		event.Err = err
+		watchdog.publish(event)
+	}
+}
+
+// publish delivers an event without blocking on slow subscribers. Must be called with mutex held.
+func (watchdog *Watchdog) publish(event WatchdogEvent) {
+	for _, channel := range watchdog.subscribers {
+		select {
+		case channel <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package dalybms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogTripsRightAfterUserWrite(t *testing.T) {
+	bms := newSimulatedClient(t, WithControlRateLimit(time.Minute))
+	watchdog := NewWatchdog(bms, WatchdogRule{Name: "soc", Metric: MetricSOC, Condition: AlarmAbove, Threshold: 0, DisableCharge: true})
+	events := watchdog.Subscribe(1)
+
+	if err := bms.EnableChargeMosfet(true); err != nil {
+		t.Fatalf("EnableChargeMosfet(true): %v", err)
+	}
+	data, err := bms.GetAllData()
+	if err != nil {
+		t.Fatalf("GetAllData: %v", err)
+	}
+	watchdog.Update(PollResult{Data: data, Time: time.Now()})
+
+	event := <-events
+	if event.Err != nil {
+		t.Fatalf("watchdog switch-off failed: %v", event.Err)
+	}
+	status, err := bms.GetMosfetStatus()
+	if err != nil {
+		t.Fatalf("GetMosfetStatus: %v", err)
+	}
+	if status.ChargingMosfet {
+		t.Errorf("charge MOSFET still on after the watchdog tripped")
+	}
+}
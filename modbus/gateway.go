@@ -0,0 +1,218 @@
+// Package modbus serves BMS telemetry as a Modbus RTU slave or Modbus TCP server,
+// for inverters, PLCs and SCADA systems. See the Register constants for the map.
+package modbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+const (
+	functionReadHoldingRegisters = 0x03
+	functionReadInputRegisters   = 0x04
+
+	exceptionIllegalFunction = 0x01
+	exceptionIllegalAddress  = 0x02
+	exceptionIllegalValue    = 0x03
+
+	maxRegistersPerRead = 125
+)
+
+// Gateway holds the register table. Feed it with poller.OnResult(gateway.Update).
+type Gateway struct {
+	mutex     sync.Mutex
+	registers [registerCount]uint16
+}
+
+func NewGateway() *Gateway {
+	return &Gateway{}
+}
+
+// Update maps a poll result to the registers
+func (gateway *Gateway) Update(result dalybms.PollResult) {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+	encodeRegisters(&gateway.registers, result)
+}
+
+// Register returns the current value of a register, 0 for unmapped addresses
+func (gateway *Gateway) Register(address int) uint16 {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+	if address < 0 || address >= registerCount {
+		return 0
+	}
+	return gateway.registers[address]
+}
+
+// handlePDU answers a request PDU (function code + data), exceptions included
+func (gateway *Gateway) handlePDU(request []byte) []byte {
+	function := request[0]
+	if function != functionReadHoldingRegisters && function != functionReadInputRegisters {
+		return []byte{function | 0x80, exceptionIllegalFunction}
+	}
+	if len(request) != 5 {
+		return []byte{function | 0x80, exceptionIllegalValue}
+	}
+
+	start := int(binary.BigEndian.Uint16(request[1:3]))
+	quantity := int(binary.BigEndian.Uint16(request[3:5]))
+	if quantity < 1 || quantity > maxRegistersPerRead {
+		return []byte{function | 0x80, exceptionIllegalValue}
+	}
+	if start+quantity > registerCount {
+		return []byte{function | 0x80, exceptionIllegalAddress}
+	}
+
+	response := make([]byte, 2, 2+quantity*2)
+	response[0] = function
+	response[1] = byte(quantity * 2)
+
+	gateway.mutex.Lock()
+	for _, value := range gateway.registers[start : start+quantity] {
+		response = binary.BigEndian.AppendUint16(response, value)
+	}
+	gateway.mutex.Unlock()
+	return response
+}
+
+// ListenAndServeTCP serves Modbus TCP on the given address, eg ":502"
+func (gateway *Gateway) ListenAndServeTCP(address string) error {
+	return gateway.ListenAndServeTCPCtx(context.Background(), address)
+}
+
+// ListenAndServeTCP with cancellation support
+func (gateway *Gateway) ListenAndServeTCPCtx(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return gateway.ServeTCPCtx(ctx, listener)
+}
+
+// ServeTCP serves Modbus TCP clients until the listener is closed. Any unit ID is answered.
+func (gateway *Gateway) ServeTCP(listener net.Listener) error {
+	return gateway.ServeTCPCtx(context.Background(), listener)
+}
+
+// ServeTCP with cancellation support: when ctx is done the listener and the client
+// connections are closed and nil is returned.
+func (gateway *Gateway) ServeTCPCtx(ctx context.Context, listener net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			stop := context.AfterFunc(ctx, func() { connection.Close() })
+			defer stop()
+			gateway.serveTCPConnection(connection)
+		}()
+	}
+}
+
+func (gateway *Gateway) serveTCPConnection(connection net.Conn) {
+	defer connection.Close()
+	reader := bufio.NewReader(connection)
+
+	for {
+		// MBAP header: transaction id, protocol id (0), length, unit id
+		var header [7]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		length := int(binary.BigEndian.Uint16(header[4:6]))
+		if binary.BigEndian.Uint16(header[2:4]) != 0 || length < 2 || length > 254 {
+			return
+		}
+		request := make([]byte, length-1)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return
+		}
+
+		response := gateway.handlePDU(request)
+		frame := make([]byte, 7, 7+len(response))
+		copy(frame, header[:])
+		binary.BigEndian.PutUint16(frame[4:6], uint16(len(response)+1))
+		frame = append(frame, response...)
+		if _, err := connection.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+// ServeRTU answers read requests addressed to slaveID on a serial port opened by the caller,
+// until the port fails. Other slaves on the bus are ignored.
+func (gateway *Gateway) ServeRTU(port io.ReadWriter, slaveID byte) error {
+	return gateway.ServeRTUCtx(context.Background(), port, slaveID)
+}
+
+// ServeRTU with cancellation support, returns nil when ctx is done. ctx is checked
+// after each read, so the port must have a read timeout.
+func (gateway *Gateway) ServeRTUCtx(ctx context.Context, port io.ReadWriter, slaveID byte) error {
+	// read requests are always 8 bytes: slave, function, start, quantity, CRC
+	request := make([]byte, 0, 8)
+	readBuffer := make([]byte, 8)
+
+	for {
+		for len(request) < 8 {
+			if ctx.Err() != nil {
+				return nil
+			}
+			bytesRead, err := port.Read(readBuffer[:8-len(request)])
+			request = append(request, readBuffer[:bytesRead]...)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("modbus rtu read failed: %w", err)
+			}
+			// 0 bytes or io.EOF: serial read timeout, keep waiting
+		}
+
+		if binary.LittleEndian.Uint16(request[6:8]) != crc16(request[:6]) {
+			// out of sync, shift by one byte
+			request = append(request[:0], request[1:]...)
+			continue
+		}
+		addressed := request[0] == slaveID
+		pdu := append([]byte(nil), request[1:6]...)
+		request = request[:0]
+		if !addressed {
+			continue
+		}
+
+		response := append([]byte{slaveID}, gateway.handlePDU(pdu)...)
+		response = binary.LittleEndian.AppendUint16(response, crc16(response))
+		if _, err := port.Write(response); err != nil {
+			return fmt.Errorf("modbus rtu write failed: %w", err)
+		}
+	}
+}
+
+// crc16 computes the Modbus RTU CRC16 (poly 0xA001, init 0xFFFF)
+func crc16(message []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, singleByte := range message {
+		crc ^= uint16(singleByte)
+		for bitIndex := 0; bitIndex < 8; bitIndex++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
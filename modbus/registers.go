@@ -0,0 +1,103 @@
+package modbus
+
+import (
+	"math"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Register map, served as both holding (0x03) and input (0x04) registers.
+// Signed values are two's complement int16.
+const (
+	RegisterSOC                = 0  // 0.1 %
+	RegisterVoltage            = 1  // 0.1 V
+	RegisterCurrent            = 2  // 0.1 A, signed, positive when charging, see WithCurrentSignConvention
+	RegisterRemainingCapacity  = 3  // 0.1 Ah
+	RegisterPower              = 4  // W, signed
+	RegisterHighestCellVoltage = 5  // mV
+	RegisterLowestCellVoltage  = 6  // mV
+	RegisterCellVoltageDelta   = 7  // mV
+	RegisterHighestTemperature = 8  // °C, signed
+	RegisterLowestTemperature  = 9  // °C, signed
+	RegisterMosfetStatus       = 10 // bit 0 charge MOSFET on, bit 1 discharge MOSFET on
+	RegisterChargerLoad        = 11 // bit 0 charger connected, bit 1 load connected
+	RegisterCycleCount         = 12
+	RegisterNumberOfCells      = 13
+	RegisterNumberOfSensors    = 14
+	RegisterErrorCount         = 15
+	RegisterDataValid          = 16  // 1 when the latest BMS read succeeded
+	RegisterCellVoltages       = 100 // mV, one register per cell, up to MaxCells
+	RegisterTemperatures       = 200 // °C, signed, one register per sensor, up to MaxSensors
+
+	MaxCells      = 48
+	MaxSensors    = 16
+	registerCount = RegisterTemperatures + MaxSensors
+)
+
+// encodeRegisters maps a poll result to the register table. Failed reads only clear RegisterDataValid,
+// the other registers keep the latest good values.
+func encodeRegisters(registers *[registerCount]uint16, result dalybms.PollResult) {
+	data := result.Data
+	if result.Err != nil || data == nil {
+		registers[RegisterDataValid] = 0
+		return
+	}
+	registers[RegisterDataValid] = 1
+
+	if data.SOC != nil {
+		registers[RegisterSOC] = scaled(dalybms.Widen(data.SOC.SOCPercent), 10)
+		registers[RegisterVoltage] = scaled(dalybms.Widen(data.SOC.TotalVoltage), 10)
+		registers[RegisterCurrent] = scaled(dalybms.Widen(data.SOC.Current), 10)
+		registers[RegisterPower] = scaled(data.Stats().PowerW, 1)
+	}
+	if data.MosfetStatus != nil {
+		registers[RegisterRemainingCapacity] = scaled(dalybms.Widen(data.MosfetStatus.CapacityAh), 10)
+		registers[RegisterMosfetStatus] = bits(data.MosfetStatus.ChargingMosfet, data.MosfetStatus.DischargingMosfet)
+	}
+	if data.CellVoltageRange != nil {
+		registers[RegisterHighestCellVoltage] = scaled(dalybms.Widen(data.CellVoltageRange.HighestVoltage), 1000)
+		registers[RegisterLowestCellVoltage] = scaled(dalybms.Widen(data.CellVoltageRange.LowestVoltage), 1000)
+		registers[RegisterCellVoltageDelta] = registers[RegisterHighestCellVoltage] - registers[RegisterLowestCellVoltage]
+	}
+	if data.TemperatureRange != nil {
+		registers[RegisterHighestTemperature] = scaled(data.TemperatureRange.HighestCelsius(), 1)
+		registers[RegisterLowestTemperature] = scaled(data.TemperatureRange.LowestCelsius(), 1)
+	}
+	if data.Status != nil {
+		registers[RegisterChargerLoad] = bits(data.Status.IsChargerRunning, data.Status.IsLoadRunning)
+		registers[RegisterCycleCount] = uint16(data.Status.CycleCount)
+		registers[RegisterNumberOfCells] = uint16(data.Status.NumberOfCells)
+		registers[RegisterNumberOfSensors] = uint16(data.Status.NumberOfTemperatureSensors)
+	}
+	if data.Errors != nil {
+		registers[RegisterErrorCount] = uint16(len(data.Errors))
+	}
+
+	for cellIndex, voltage := range data.CellVoltages {
+		if cellIndex >= 1 && cellIndex <= MaxCells {
+			registers[RegisterCellVoltages+cellIndex-1] = scaled(voltage, 1000)
+		}
+	}
+	for sensorIndex, temperature := range data.TemperaturesCelsius() {
+		if sensorIndex >= 1 && sensorIndex <= MaxSensors {
+			registers[RegisterTemperatures+sensorIndex-1] = scaled(temperature, 1)
+		}
+	}
+}
+
+// scaled rounds value*scale to a (possibly negative) int16 register value
+func scaled(value float64, scale float64) uint16 {
+	raw := math.Round(value * scale)
+	raw = math.Max(math.MinInt16, math.Min(math.MaxUint16, raw))
+	return uint16(int32(raw))
+}
+
+func bits(values ...bool) uint16 {
+	var result uint16
+	for bitIndex, value := range values {
+		if value {
+			result |= 1 << bitIndex
+		}
+	}
+	return result
+}
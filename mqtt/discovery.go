@@ -0,0 +1,100 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Home Assistant MQTT discovery, see https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+
+type discoveryMessage struct {
+	topic   string
+	payload []byte
+}
+
+type discoveryEntity struct {
+	component   string // sensor or binary_sensor
+	id          string
+	name        string
+	field       string // path of the value in the state, see dalybms.ChangeDetector
+	unit        string
+	deviceClass string
+}
+
+var discoveryEntities = []discoveryEntity{
+	{"sensor", "soc", "State of charge", "soc/soc_percent", "%", "battery"},
+	{"sensor", "voltage", "Voltage", "soc/total_voltage", "V", "voltage"},
+	{"sensor", "current", "Current", "soc/current", "A", "current"},
+	{"sensor", "power", "Power", "stats/power_w", "W", "power"},
+	{"sensor", "highest_cell_voltage", "Highest cell voltage", "cell_voltage_range/highest_voltage", "V", "voltage"},
+	{"sensor", "lowest_cell_voltage", "Lowest cell voltage", "cell_voltage_range/lowest_voltage", "V", "voltage"},
+	{"sensor", "cell_voltage_delta", "Cell voltage delta", "stats/cell_voltage_delta", "V", "voltage"},
+	{"sensor", "temperature", "Temperature", "stats/average_temperature", "°C", "temperature"},
+	{"sensor", "remaining_capacity", "Remaining capacity", "mosfet_status/capacity_ah", "Ah", ""},
+	{"sensor", "cycles", "Cycles", "status/cycle_count", "", ""},
+	{"binary_sensor", "charge_mosfet", "Charge MOSFET", "mosfet_status/charging_mosfet", "", "power"},
+	{"binary_sensor", "discharge_mosfet", "Discharge MOSFET", "mosfet_status/discharging_mosfet", "", "power"},
+}
+
+// entityState returns the state topic and value template of an entity: the state topic with
+// a JSON template, or the topic of the field with ChangesOnly
+func (publisher *Publisher) entityState(entity discoveryEntity) (string, string) {
+	if publisher.config.ChangesOnly {
+		topic := publisher.config.Topic + "/" + entity.field
+		if entity.component == "binary_sensor" {
+			return topic, "{{ 'ON' if value == 'true' else 'OFF' }}"
+		}
+		return topic, ""
+	}
+
+	value := "value_json." + strings.ReplaceAll(entity.field, "/", ".")
+	if entity.component == "binary_sensor" {
+		return publisher.config.Topic + "/state", "{{ 'ON' if " + value + " else 'OFF' }}"
+	}
+	return publisher.config.Topic + "/state", "{{ " + value + " }}"
+}
+
+// discoveryMessages returns the retained config messages announcing the entities of the device
+func (publisher *Publisher) discoveryMessages() []discoveryMessage {
+	device := map[string]any{
+		"identifiers":  []string{publisher.config.ClientID},
+		"name":         publisher.config.DeviceName,
+		"manufacturer": "Daly",
+	}
+
+	messages := make([]discoveryMessage, 0, len(discoveryEntities))
+	for _, entity := range discoveryEntities {
+		stateTopic, template := publisher.entityState(entity)
+		config := map[string]any{
+			"name":               entity.name,
+			"unique_id":          publisher.config.ClientID + "_" + entity.id,
+			"state_topic":        stateTopic,
+			"availability_topic": publisher.availabilityTopic(),
+			"device":             device,
+		}
+		if template != "" {
+			config["value_template"] = template
+		}
+		if entity.deviceClass == "temperature" {
+			config["unit_of_measurement"] = publisher.config.TemperatureUnit.Symbol()
+		} else if entity.unit != "" {
+			config["unit_of_measurement"] = entity.unit
+		}
+		if entity.deviceClass != "" {
+			config["device_class"] = entity.deviceClass
+		}
+		if entity.component == "sensor" {
+			config["state_class"] = "measurement"
+		}
+
+		payload, err := json.Marshal(config)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, discoveryMessage{
+			topic:   publisher.config.DiscoveryPrefix + "/" + entity.component + "/" + publisher.config.ClientID + "/" + entity.id + "/config",
+			payload: payload,
+		})
+	}
+	return messages
+}
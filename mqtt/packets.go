@@ -0,0 +1,114 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packets, only what a QoS 0 publisher needs
+
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPingreq    = 0xc0
+	packetDisconnect = 0xe0
+)
+
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// connectPacket with a clean session and a retained "offline" will on the availability topic
+func (publisher *Publisher) connectPacket() []byte {
+	flags := byte(flagCleanSession | flagWill | flagWillRetain)
+	if publisher.config.Username != "" {
+		flags |= flagUsername
+		if publisher.config.Password != "" {
+			flags |= flagPassword
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 = 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(publisher.config.KeepAlive.Seconds()))
+	body = appendString(body, publisher.config.ClientID)
+	body = appendString(body, publisher.availabilityTopic())
+	body = appendString(body, "offline")
+	if flags&flagUsername != 0 {
+		body = appendString(body, publisher.config.Username)
+	}
+	if flags&flagPassword != 0 {
+		body = appendString(body, publisher.config.Password)
+	}
+	return packet(packetConnect, body)
+}
+
+func publishPacket(topic string, payload []byte, retain bool) []byte {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	return packet(header, append(appendString(nil, topic), payload...))
+}
+
+// packet prefixes body with the fixed header
+func packet(header byte, body []byte) []byte {
+	result := []byte{header}
+	length := len(body)
+	for {
+		// remaining length: 7 bits per byte, high bit set when more bytes follow
+		encoded := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			encoded |= 0x80
+		}
+		result = append(result, encoded)
+		if length == 0 {
+			break
+		}
+	}
+	return append(result, body...)
+}
+
+func appendString(data []byte, value string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(value)))
+	return append(data, value...)
+}
+
+func readRemainingLength(reader io.Reader) (int, error) {
+	length, multiplier := 0, 1
+	var encoded [1]byte
+	for index := 0; index < 4; index++ {
+		if _, err := io.ReadFull(reader, encoded[:]); err != nil {
+			return 0, err
+		}
+		length += int(encoded[0]&0x7f) * multiplier
+		if encoded[0]&0x80 == 0 {
+			return length, nil
+		}
+		multiplier *= 128
+	}
+	return 0, fmt.Errorf("malformed remaining length")
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
@@ -0,0 +1,324 @@
+// Package mqtt publishes BMS samples to an MQTT 3.1.1 broker, optionally announcing
+// them to Home Assistant with MQTT discovery.
+//
+// Topics, with the default base topic:
+//
+//	dalybms/state          full sample with derived stats, JSON
+//	dalybms/availability   "online", or "offline" when the publisher closes or the connection is lost
+//
+// With ChangesOnly, the state topic is replaced by one retained topic per field, published
+// only when the field moved beyond its deadband, eg dalybms/soc/soc_percent or dalybms/cell_voltages/3.
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Publisher settings
+type Config struct {
+	Broker   string // eg "tcp://localhost:1883" or "ssl://broker:8883"
+	ClientID string // default "dalybms"
+	Username string // optional
+	Password string
+	Topic    string // base topic, default "dalybms"
+	Retain   bool   // retain state messages
+
+	ChangesOnly bool              // publish changed fields on their own topics instead of the state topic
+	Deadbands   dalybms.Deadbands // minimum changes with ChangesOnly, default DefaultDeadbands()
+
+	HomeAssistant   bool   // publish discovery messages on connect
+	DiscoveryPrefix string // default "homeassistant"
+	DeviceName      string // default "Daly BMS"
+
+	TemperatureUnit dalybms.TemperatureUnit // unit announced to Home Assistant, set it like WithTemperatureUnit()
+
+	KeepAlive    time.Duration // default 60s
+	DialTimeout  time.Duration // default 10s
+	WriteTimeout time.Duration // default 10s
+	TLSConfig    *tls.Config   // for ssl:// brokers, default system roots
+}
+
+// Publisher sends every sample to the broker with QoS 0.
+// Feed it with poller.OnResult(publisher.Update). The connection is opened on the first
+// sample and reopened on the next sample after a failure.
+type Publisher struct {
+	config Config
+
+	mutex      sync.Mutex
+	connection net.Conn
+	closed     chan struct{} // closed by the reader when the broker goes away
+	lastWrite  time.Time
+	lastErr    error
+	changes    *dalybms.ChangeDetector // with ChangesOnly
+}
+
+// state is the payload of the state topic
+type state struct {
+	*dalybms.AllStatusData
+	Stats *dalybms.Stats `json:"stats"`
+	Time  time.Time      `json:"time"`
+}
+
+func NewPublisher(config Config) *Publisher {
+	if config.ClientID == "" {
+		config.ClientID = "dalybms"
+	}
+	if config.Topic == "" {
+		config.Topic = "dalybms"
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = "homeassistant"
+	}
+	if config.DeviceName == "" {
+		config.DeviceName = "Daly BMS"
+	}
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = 60 * time.Second
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	publisher := &Publisher{config: config}
+	if config.ChangesOnly {
+		if config.Deadbands == (dalybms.Deadbands{}) {
+			publisher.config.Deadbands = dalybms.DefaultDeadbands()
+		}
+		publisher.changes = dalybms.NewChangeDetector(publisher.config.Deadbands)
+	}
+	return publisher
+}
+
+// Update publishes a successful poll result, failed polls are skipped. Errors are available from Err().
+func (publisher *Publisher) Update(result dalybms.PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+
+	var err error
+	if publisher.changes != nil {
+		err = publisher.publishChanges(result.Data)
+	} else {
+		var payload []byte
+		payload, err = json.Marshal(state{AllStatusData: result.Data, Stats: result.Data.Stats(), Time: result.Time})
+		if err == nil {
+			err = publisher.Publish(publisher.config.Topic+"/state", payload, publisher.config.Retain)
+		}
+	}
+
+	publisher.mutex.Lock()
+	publisher.lastErr = err
+	publisher.mutex.Unlock()
+}
+
+// publishChanges sends each changed field, retained. After a failure every field is sent
+// again with the next sample, since some changes may be lost.
+func (publisher *Publisher) publishChanges(data *dalybms.AllStatusData) error {
+	for path, value := range publisher.changes.Changes(data) {
+		payload, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		if err := publisher.Publish(publisher.config.Topic+"/"+path, payload, true); err != nil {
+			publisher.changes.Reset()
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest publish error, nil if the latest publish succeeded
+func (publisher *Publisher) Err() error {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	return publisher.lastErr
+}
+
+// Base topic, eg for Publish(publisher.Topic()+"/alarms", ...)
+func (publisher *Publisher) Topic() string {
+	return publisher.config.Topic
+}
+
+// Publish sends a message with QoS 0, connecting first if needed
+func (publisher *Publisher) Publish(topic string, payload []byte, retain bool) error {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	if publisher.connection != nil {
+		select {
+		case <-publisher.closed:
+			publisher.disconnect()
+		default:
+		}
+	}
+	if publisher.connection == nil {
+		if err := publisher.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := publisher.writePacket(publishPacket(topic, payload, retain)); err != nil {
+		publisher.disconnect()
+		return fmt.Errorf("mqtt publish failed: %w", err)
+	}
+	return nil
+}
+
+// Close marks the device offline and disconnects
+func (publisher *Publisher) Close() error {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	if publisher.connection == nil {
+		return nil
+	}
+	publisher.writePacket(publishPacket(publisher.availabilityTopic(), []byte("offline"), true))
+	publisher.writePacket([]byte{packetDisconnect, 0})
+	return publisher.disconnect()
+}
+
+// disconnect must be called with mutex held
+func (publisher *Publisher) disconnect() error {
+	err := publisher.connection.Close()
+	publisher.connection = nil
+	return err
+}
+
+func (publisher *Publisher) availabilityTopic() string {
+	return publisher.config.Topic + "/availability"
+}
+
+// connect dials the broker, sends CONNECT with an "offline" will and waits for CONNACK.
+// Must be called with mutex held.
+func (publisher *Publisher) connect() error {
+	broker, err := url.Parse(publisher.config.Broker)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: publisher.config.DialTimeout}
+	var connection net.Conn
+	switch broker.Scheme {
+	case "tcp", "mqtt":
+		connection, err = dialer.Dial("tcp", hostPort(broker, "1883"))
+	case "ssl", "tls", "mqtts":
+		tlsConfig := publisher.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: broker.Hostname()}
+		}
+		connection, err = tls.DialWithDialer(dialer, "tcp", hostPort(broker, "8883"), tlsConfig)
+	default:
+		return fmt.Errorf("unsupported MQTT broker scheme %q, use tcp:// or ssl://", broker.Scheme)
+	}
+	if err != nil {
+		return fmt.Errorf("mqtt connection failed: %w", err)
+	}
+
+	connection.SetDeadline(time.Now().Add(publisher.config.DialTimeout))
+	if _, err := connection.Write(publisher.connectPacket()); err != nil {
+		connection.Close()
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+	var connack [4]byte
+	if _, err := io.ReadFull(connection, connack[:]); err != nil {
+		connection.Close()
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+	if connack[0] != packetConnack || connack[1] != 2 {
+		connection.Close()
+		return fmt.Errorf("mqtt connect failed: unexpected packet %x", connack)
+	}
+	if connack[3] != 0 {
+		connection.Close()
+		return fmt.Errorf("mqtt connect refused: %s", connackReason(connack[3]))
+	}
+	connection.SetDeadline(time.Time{})
+
+	publisher.connection = connection
+	publisher.closed = make(chan struct{})
+	go publisher.readPackets(connection, publisher.closed)
+	go publisher.keepAlive(connection, publisher.closed)
+
+	if err := publisher.writePacket(publishPacket(publisher.availabilityTopic(), []byte("online"), true)); err != nil {
+		publisher.disconnect()
+		return fmt.Errorf("mqtt publish failed: %w", err)
+	}
+	if publisher.config.HomeAssistant {
+		for _, message := range publisher.discoveryMessages() {
+			if err := publisher.writePacket(publishPacket(message.topic, message.payload, true)); err != nil {
+				publisher.disconnect()
+				return fmt.Errorf("mqtt discovery failed: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// keepAlive pings the broker when nothing was written for half the keep alive period
+func (publisher *Publisher) keepAlive(connection net.Conn, closed chan struct{}) {
+	ticker := time.NewTicker(publisher.config.KeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			publisher.mutex.Lock()
+			if publisher.connection != connection {
+				publisher.mutex.Unlock()
+				return
+			}
+			if time.Since(publisher.lastWrite) >= publisher.config.KeepAlive/2 {
+				publisher.writePacket([]byte{packetPingreq, 0})
+			}
+			publisher.mutex.Unlock()
+		}
+	}
+}
+
+// readPackets discards broker packets (ping responses) and closes closed when the connection ends
+func (publisher *Publisher) readPackets(connection net.Conn, closed chan struct{}) {
+	defer close(closed)
+	var header [1]byte
+	for {
+		if _, err := io.ReadFull(connection, header[:]); err != nil {
+			return
+		}
+		length, err := readRemainingLength(connection)
+		if err != nil {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, connection, int64(length)); err != nil {
+			return
+		}
+	}
+}
+
+// writePacket must be called with mutex held
+func (publisher *Publisher) writePacket(packet []byte) error {
+	publisher.connection.SetWriteDeadline(time.Now().Add(publisher.config.WriteTimeout))
+	_, err := publisher.connection.Write(packet)
+	publisher.lastWrite = time.Now()
+	return err
+}
+
+func hostPort(broker *url.URL, defaultPort string) string {
+	if broker.Port() != "" {
+		return broker.Host
+	}
+	return net.JoinHostPort(broker.Hostname(), defaultPort)
+}
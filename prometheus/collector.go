@@ -0,0 +1,223 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Collector exports BMS data in the Prometheus text exposition format.
+// Feed it from a Poller (poller.OnResult(collector.Update)) or let it poll on each scrape, see NewPollingCollector.
+type Collector struct {
+	namespace string
+	bms       dalybms.BMSClient // only set for polling collectors
+	mutex     sync.Mutex
+	latest    *dalybms.PollResult
+}
+
+// NewCollector returns a collector exporting the latest result given to Update
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		namespace: namespace,
+	}
+}
+
+// NewPollingCollector returns a collector reading the BMS on every scrape
+func NewPollingCollector(bms dalybms.BMSClient, namespace string) *Collector {
+	return &Collector{
+		namespace: namespace,
+		bms:       bms,
+	}
+}
+
+// Update stores a poll result, exported on the next scrape
+func (collector *Collector) Update(result dalybms.PollResult) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.latest = &result
+}
+
+// ServeHTTP serves the metrics, eg http.Handle("/metrics", collector)
+func (collector *Collector) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if collector.bms != nil {
+		data, err := collector.bms.GetAllDataCtx(request.Context())
+		collector.Update(dalybms.PollResult{Data: data, Err: err})
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := collector.WriteTo(writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTextfile atomically writes the metrics to a file for the node_exporter textfile collector
+func (collector *Collector) WriteTextfile(path string) error {
+	temporaryFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create textfile: %w", err)
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err := collector.WriteTo(temporaryFile); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err := temporaryFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporaryFile.Name(), path)
+}
+
+// WriteTo writes the metrics of the latest result
+func (collector *Collector) WriteTo(writer io.Writer) (int64, error) {
+	collector.mutex.Lock()
+	latest := collector.latest
+	collector.mutex.Unlock()
+
+	metrics := &metricWriter{namespace: collector.namespace}
+
+	if latest == nil || latest.Err != nil || latest.Data == nil {
+		metrics.gauge("up", "Whether the latest BMS read succeeded", 0)
+		return io.Copy(writer, &metrics.buffer)
+	}
+	metrics.gauge("up", "Whether the latest BMS read succeeded", 1)
+	if !latest.Time.IsZero() {
+		metrics.gauge("last_update_timestamp_seconds", "Time of the latest BMS read", float64(latest.Time.UnixNano())/1e9)
+	}
+
+	data := latest.Data
+	if data.SOC != nil {
+		metrics.gauge("soc_percent", "State of charge", float32Value(data.SOC.SOCPercent))
+		metrics.gauge("pack_voltage_volts", "Total pack voltage", float32Value(data.SOC.TotalVoltage))
+		metrics.gauge("current_amperes", "Pack current, positive when charging unless the client uses WithCurrentSignConvention", float32Value(data.SOC.Current))
+	}
+	if data.Status != nil {
+		metrics.gauge("cycle_count", "Charge cycles", float64(data.Status.CycleCount))
+		metrics.gauge("cells", "Number of cells", float64(data.Status.NumberOfCells))
+	}
+	if data.MosfetStatus != nil {
+		metrics.gauge("remaining_capacity_ah", "Remaining capacity", float32Value(data.MosfetStatus.CapacityAh))
+		metrics.gauge("charging_mosfet", "Whether the charge MOSFET is on", boolValue(data.MosfetStatus.ChargingMosfet))
+		metrics.gauge("discharging_mosfet", "Whether the discharge MOSFET is on", boolValue(data.MosfetStatus.DischargingMosfet))
+	}
+
+	stats := data.Stats()
+	if data.SOC != nil {
+		metrics.gauge("power_watts", "Pack power, positive when charging", stats.PowerW)
+	}
+	if data.SOC != nil && data.MosfetStatus != nil {
+		metrics.gauge("remaining_energy_wh", "Remaining energy at the current pack voltage", stats.RemainingEnergyWh)
+	}
+	if len(data.CellVoltages) > 0 {
+		metrics.gauge("cell_voltage_delta_volts", "Difference between the highest and lowest cell", stats.CellVoltageDelta)
+	}
+
+	metrics.header("cell_voltage_volts", "Voltage of each cell")
+	for _, cellIndex := range sortedKeys(data.CellVoltages) {
+		cellLabels := map[string]string{"cell": strconv.Itoa(cellIndex)}
+		if label, ok := data.CellLabels[cellIndex]; ok {
+			cellLabels["label"] = label
+		}
+		metrics.sample("cell_voltage_volts", cellLabels, data.CellVoltages[cellIndex])
+	}
+
+	metrics.header("cell_balancing", "Whether each cell is balancing")
+	for _, cellIndex := range sortedKeys(data.BalancingStatus) {
+		metrics.sample("cell_balancing", map[string]string{"cell": strconv.Itoa(cellIndex)}, boolValue(data.BalancingStatus[cellIndex]))
+	}
+
+	if data.MOSTemperature != nil {
+		metrics.gauge("mos_temperature_celsius", "Temperature of the MOSFETs", data.TemperatureUnit.ToCelsius(*data.MOSTemperature))
+	}
+	metrics.header("temperature_celsius", "Temperature of each sensor")
+	temperatures := data.TemperaturesCelsius()
+	for _, sensorIndex := range sortedKeys(temperatures) {
+		metrics.sample("temperature_celsius", map[string]string{"sensor": strconv.Itoa(sensorIndex)}, temperatures[sensorIndex])
+	}
+
+	metrics.gauge("active_errors", "Number of active error flags", float64(len(data.Errors)))
+	metrics.header("error_active", "Active error flags")
+	for _, bmsError := range data.Errors {
+		metrics.sample("error_active", map[string]string{"error": bmsError.Message, "severity": string(bmsError.Severity)}, 1)
+	}
+
+	return io.Copy(writer, &metrics.buffer)
+}
+
+// metricWriter formats gauges in the text exposition format
+type metricWriter struct {
+	namespace string
+	buffer    bytes.Buffer
+}
+
+func (metrics *metricWriter) name(metric string) string {
+	if metrics.namespace == "" {
+		return metric
+	}
+	return metrics.namespace + "_" + metric
+}
+
+func (metrics *metricWriter) header(metric string, help string) {
+	fmt.Fprintf(&metrics.buffer, "# HELP %s %s\n", metrics.name(metric), help)
+	fmt.Fprintf(&metrics.buffer, "# TYPE %s gauge\n", metrics.name(metric))
+}
+
+func (metrics *metricWriter) gauge(metric string, help string, value float64) {
+	metrics.header(metric, help)
+	metrics.sample(metric, nil, value)
+}
+
+func (metrics *metricWriter) sample(metric string, labels map[string]string, value float64) {
+	metrics.buffer.WriteString(metrics.name(metric))
+	if len(labels) > 0 {
+		labelNames := make([]string, 0, len(labels))
+		for labelName := range labels {
+			labelNames = append(labelNames, labelName)
+		}
+		sort.Strings(labelNames)
+
+		pairs := make([]string, len(labelNames))
+		for pairIndex, labelName := range labelNames {
+			pairs[pairIndex] = fmt.Sprintf("%s=\"%s\"", labelName, escapeLabelValue(labels[labelName]))
+		}
+		metrics.buffer.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	metrics.buffer.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// float32Value widens a float32 without exposing its binary rounding, eg 13.2 instead of 13.199999809265137
+func float32Value(value float32) float64 {
+	widened, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return widened
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys[V any](values map[int]V) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,54 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Decode errors, returned wrapped with the details
+var (
+	ErrFrameLength = errors.New("invalid frame length")
+	ErrStartByte   = errors.New("invalid start byte")
+	ErrDataLength  = errors.New("invalid data length")
+	ErrChecksum    = errors.New("checksum mismatch")
+)
+
+// Frame is the content of a request or response frame
+type Frame struct {
+	Address byte
+	Command Command
+	Data    [DataLength]byte
+}
+
+// Decode validates the layout and checksum of a FrameLength bytes frame and returns its
+// content. It only reads frame, so it is safe on any input.
+func Decode(frame []byte) (Frame, error) {
+	if len(frame) != FrameLength {
+		return Frame{}, fmt.Errorf("%w: %d bytes, expected %d", ErrFrameLength, len(frame), FrameLength)
+	}
+	if frame[0] != StartByte {
+		return Frame{}, fmt.Errorf("%w: %02x", ErrStartByte, frame[0])
+	}
+	if frame[3] != DataLength {
+		return Frame{}, fmt.Errorf("%w: %d, expected %d", ErrDataLength, frame[3], DataLength)
+	}
+	if computed := Checksum(frame[:FrameLength-1]); computed != frame[FrameLength-1] {
+		return Frame{}, fmt.Errorf("%w: computed %02x != %02x", ErrChecksum, computed, frame[FrameLength-1])
+	}
+
+	decoded := Frame{Address: frame[1], Command: Command(frame[2])}
+	copy(decoded.Data[:], frame[HeaderLength:HeaderLength+DataLength])
+	return decoded, nil
+}
+
+// Encode returns the FrameLength bytes of the frame, with its checksum
+func (frame Frame) Encode() []byte {
+	encoded := make([]byte, FrameLength)
+	encoded[0] = StartByte
+	encoded[1] = frame.Address
+	encoded[2] = byte(frame.Command)
+	encoded[3] = DataLength
+	copy(encoded[HeaderLength:], frame.Data[:])
+	encoded[FrameLength-1] = Checksum(encoded[:FrameLength-1])
+	return encoded
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Frames of a session recorded against the simulator, requests and responses
+var seedFrames = []string{
+	"a540900800000000000000007d", // CommandSOC request
+	"a50190080084000075300258c1", // 13.2 V, 0 A, 60 %
+	"a50191080ce8040ce103000027", // cell voltage range
+	"a50192083c013c0100000000ba", // temperature range
+	"a5019308000101000000ea608d", // MOSFET status
+	"a50194080401000000000a0051", // status, 4 cells, 1 sensor
+	"a5019508010ce70ce60ce10016", // cell voltages, frame 1
+	"a5019508020ce8000000000039", // cell voltages, frame 2
+	"a5019608013c00000000000081", // temperatures
+	"a5019708000000000000000045", // balancing
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range seedFrames {
+		frame, err := hex.DecodeString(seed)
+		if err != nil {
+			f.Fatalf("seed %s: %v", seed, err)
+		}
+		f.Add(frame)
+		f.Add(frame[:FrameLength-1])
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		decoded, err := Decode(frame)
+		if err != nil {
+			return
+		}
+		// a valid frame encodes back to the same bytes
+		if encoded := decoded.Encode(); !bytes.Equal(encoded, frame) {
+			t.Errorf("Decode(%x).Encode() = %x", frame, encoded)
+		}
+	})
+}
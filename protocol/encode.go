@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPayloadTooLong is returned (wrapped) for requests with more than DataLength data bytes
+var ErrPayloadTooLong = errors.New("payload longer than 8 bytes")
+
+// ErrInvalidBoard is returned (wrapped) for board numbers outside FirstBoard..MaxBoard
+var ErrInvalidBoard = errors.New("invalid board number")
+
+// EncodeRequest builds a request frame, data is zero padded to DataLength bytes.
+// address is AddressRS485 or AddressBluetooth.
+func EncodeRequest(address byte, command Command, data []byte) ([]byte, error) {
+	if len(data) > DataLength {
+		return nil, fmt.Errorf("command %s: %w, got %d", command.Hex(), ErrPayloadTooLong, len(data))
+	}
+
+	frame := Frame{Address: address, Command: command}
+	copy(frame.Data[:], data)
+	return frame.Encode(), nil
+}
+
+// EncodeBoardRequest builds a request frame for a board of a parallel system, see BoardAddresses
+func EncodeBoardRequest(board int, command Command, data []byte) ([]byte, error) {
+	requestAddress, _, err := BoardAddresses(board)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeRequest(requestAddress, command, data)
+}
+
+// BoardAddresses returns the address requests to a board are sent to and the address it
+// answers from, AddressRS485 and AddressBMS for board 1
+func BoardAddresses(board int) (requestAddress byte, responseAddress byte, err error) {
+	if board < FirstBoard || board > MaxBoard {
+		return 0, 0, fmt.Errorf("%w: %d, expected %d to %d", ErrInvalidBoard, board, FirstBoard, MaxBoard)
+	}
+	return AddressRS485 + byte(board-FirstBoard), byte(board), nil
+}
@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	for _, address := range []byte{AddressRS485, AddressBluetooth} {
+		for _, command := range Commands() {
+			for payloadLength := 0; payloadLength <= DataLength; payloadLength++ {
+				payload := make([]byte, payloadLength)
+				for index := range payload {
+					payload[index] = byte(0xf0 + index)
+				}
+
+				t.Run(fmt.Sprintf("%02x/%s/%d", address, command.Hex(), payloadLength), func(t *testing.T) {
+					frame, err := EncodeRequest(address, command, payload)
+					if err != nil {
+						t.Fatalf("EncodeRequest: %v", err)
+					}
+					if len(frame) != FrameLength {
+						t.Fatalf("frame %x has %d bytes, want %d", frame, len(frame), FrameLength)
+					}
+					if want := []byte{StartByte, address, byte(command), DataLength}; !bytes.Equal(frame[:HeaderLength], want) {
+						t.Errorf("header %x, want %x", frame[:HeaderLength], want)
+					}
+					data := frame[HeaderLength : HeaderLength+DataLength]
+					if !bytes.Equal(data[:payloadLength], payload) {
+						t.Errorf("data %x, want payload %x first", data, payload)
+					}
+					if padding := data[payloadLength:]; !bytes.Equal(padding, make([]byte, len(padding))) {
+						t.Errorf("padding %x, want zeros", padding)
+					}
+					var sum int
+					for _, value := range frame[:FrameLength-1] {
+						sum += int(value)
+					}
+					if checksum := frame[FrameLength-1]; checksum != byte(sum%256) {
+						t.Errorf("checksum %02x, want %02x", checksum, byte(sum%256))
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestEncodeRequestPayloadTooLong(t *testing.T) {
+	for _, address := range []byte{AddressRS485, AddressBluetooth} {
+		for _, payloadLength := range []int{DataLength + 1, 2 * DataLength} {
+			frame, err := EncodeRequest(address, CommandSOC, make([]byte, payloadLength))
+			if !errors.Is(err, ErrPayloadTooLong) {
+				t.Errorf("address %02x, %d bytes: error = %v, want ErrPayloadTooLong", address, payloadLength, err)
+			}
+			if frame != nil {
+				t.Errorf("address %02x, %d bytes: frame = %x, want nil", address, payloadLength, frame)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package protocol
+
+// Number of bytes carrying error flags in the CommandErrors response
+const ErrorBytes = 7
+
+// Position of the "monomer collect drop off" flag, raised when a cell sense (balance) lead is open
+const (
+	OpenWireErrorByte = 5
+	OpenWireErrorBit  = 1
+)
+
+// An error flag of the CommandErrors response. Alarm follows the protocol layout, not the
+// message: level two flags and hardware failures are alarms, level one flags are warnings.
+type ErrorFlag struct {
+	Message string
+	Alarm   bool
+}
+
+// Error flags by byte and bit of the CommandErrors response data, ErrorFlags[byte][bit].
+// Bit 0 is the least significant. Even bits of bytes 0-3 are level one, odd bits level two,
+// whatever the message says.
+var ErrorFlags = map[int][]ErrorFlag{
+	0: {
+		{"one stage warning of unit over voltage", false},
+		{"one stage warning of unit over voltage", true},
+		{"one stage warning of unit over voltage", false},
+		{"two stage warning of unit over voltage", true},
+		{"Total voltage is too high One alarm", false},
+		{"Total voltage is too high Level two alarm", true},
+		{"Total voltage is too low One alarm", false},
+		{"Total voltage is too low Level two alarm", true},
+	},
+	1: {
+		{"Charging temperature too high. One alarm", false},
+		{"Charging temperature too high. Level two alarm", true},
+		{"Charging temperature too low. One alarm", false},
+		{"Charging temperature too low. Level two alarm", true},
+		{"Discharge temperature is too high. One alarm", false},
+		{"Discharge temperature is too high. Level two alarm", true},
+		{"Discharge temperature is too low. One alarm", false},
+		{"Discharge temperature is too low. Level two alarm", true},
+	},
+	2: {
+		{"Charge over current. Level one alarm", false},
+		{"Charge over current, level two alarm", true},
+		{"Discharge over current. Level one alarm", false},
+		{"Discharge overcurrent, level two alarm", true},
+		{"SOC is too high an alarm", false},
+		{"SOC is too high. Alarm Two", true},
+		{"SOC is too low. level one alarm", false},
+		{"SOC is too low. level two alarm", true},
+	},
+	3: {
+		{"Excessive differential pressure level one alarm", false},
+		{"Excessive differential pressure level two alarm", true},
+		{"Excessive temperature difference level one alarm", false},
+		{"Excessive temperature difference level two alarm", true},
+	},
+	4: {
+		{"charging  MOS overtemperature warning", false},
+		{"discharge MOS overtemperature warning", false},
+		{"charging MOS temperature detection sensor failure", true},
+		{"discharge MOS temperature detection sensor failure", true},
+		{"charging MOS adhesion failure", true},
+		{"discharge MOS adhesion failure", true},
+		{"charging MOS breaker failure", true},
+		{"discharge MOS breaker failure", true},
+	},
+	5: {
+		{"AFE acquisition chip malfunction", true},
+		{"monomer collect drop off", true},
+		{"Single Temperature Sensor Fault", true},
+		{"EEPROM storage failures", true},
+		{"RTC clock malfunction", true},
+		{"Precharge Failure", true},
+		{"vehicle communications malfunction", true},
+		{"intranet communication module malfunction", true},
+	},
+	6: {
+		{"Current Module Failure", true},
+		{"main pressure detection module", true},
+		{"Short circuit protection failure", true},
+		{"Low Voltage No Charging", false},
+		{"RESERVED", false},
+		{"RESERVED", false},
+		{"RESERVED", false},
+		{"RESERVED", false},
+	},
+}
+
+// Messages of the error flags, ErrorCodes[byte][bit] of the CommandErrors response data.
+// Bit 0 is the least significant.
+var ErrorCodes = errorMessages(ErrorFlags)
+
+func errorMessages(flags map[int][]ErrorFlag) map[int][]string {
+	messages := make(map[int][]string, len(flags))
+	for byteIndex, byteFlags := range flags {
+		for _, flag := range byteFlags {
+			messages[byteIndex] = append(messages[byteIndex], flag.Message)
+		}
+	}
+	return messages
+}
+
+// IsAlarm reports whether the flag at bit of byteIndex is an alarm, false for unknown and reserved bits
+func IsAlarm(byteIndex int, bit int) bool {
+	flags, ok := ErrorFlags[byteIndex]
+	return ok && bit >= 0 && bit < len(flags) && flags[bit].Alarm
+}
@@ -0,0 +1,39 @@
+package protocol
+
+import "testing"
+
+func TestIsAlarm(t *testing.T) {
+	tests := []struct {
+		name      string
+		byteIndex int
+		bit       int
+		want      bool
+	}{
+		{"cell overvoltage level one", 0, 0, false},
+		{"cell overvoltage level two", 0, 1, true},
+		{"charge overcurrent level two", 2, 1, true},
+		{"SOC too high level one", 2, 4, false},
+		{"MOS overtemperature", 4, 0, false},
+		{"MOS adhesion failure", 4, 4, true},
+		{"open wire", OpenWireErrorByte, OpenWireErrorBit, true},
+		{"low voltage no charging", 6, 3, false},
+		{"reserved", 6, 4, false},
+		{"unknown byte", ErrorBytes, 0, false},
+		{"unknown bit", 3, 7, false},
+	}
+	for _, test := range tests {
+		if got := IsAlarm(test.byteIndex, test.bit); got != test.want {
+			t.Errorf("%s: IsAlarm(%d, %d) = %v, want %v", test.name, test.byteIndex, test.bit, got, test.want)
+		}
+	}
+}
+
+func TestErrorCodesFollowErrorFlags(t *testing.T) {
+	for byteIndex, flags := range ErrorFlags {
+		for bit, flag := range flags {
+			if ErrorCodes[byteIndex][bit] != flag.Message {
+				t.Errorf("ErrorCodes[%d][%d] = %q, want %q", byteIndex, bit, ErrorCodes[byteIndex][bit], flag.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package protocol holds the constants of the Daly UART/RS485 protocol: frame layout, addresses,
+// command codes and the error flag table, for tools such as simulators, decoders and fuzzers.
+//
+// Every frame is 13 bytes: start byte 0xA5, address, command, data length (8), 8 data bytes and
+// a checksum, the low byte of the sum of the 12 preceding bytes.
+package protocol
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Frame layout
+const (
+	StartByte    = 0xa5
+	HeaderLength = 4 // start byte, address, command, data length
+	DataLength   = 8
+	FrameLength  = HeaderLength + DataLength + 1 // with the checksum
+)
+
+// Address byte of a frame
+const (
+	AddressRS485     = 0x40 // requests sent over UART/RS485
+	AddressBluetooth = 0x80 // requests sent through the Bluetooth module
+	AddressBMS       = 0x01 // responses of a board with the default board number
+)
+
+// Board numbers of packs in parallel, set in the BMS parameters, 1 for a single board.
+// Board n answers the requests sent to AddressRS485 + n - 1 from address n.
+const (
+	FirstBoard = 1
+	MaxBoard   = AddressBluetooth - AddressRS485 // request addresses stay below AddressBluetooth
+)
+
+// Command code, the third byte of a frame
+type Command byte
+
+// Telemetry
+const (
+	CommandSOC              Command = 0x90 // pack voltage, current, SOC
+	CommandCellVoltageRange Command = 0x91 // highest and lowest cell voltage
+	CommandTemperatureRange Command = 0x92 // highest and lowest temperature
+	CommandMosfetStatus     Command = 0x93 // mode, MOSFET states, remaining capacity
+	CommandStatus           Command = 0x94 // cell and sensor counts, charger/load, cycles, DIO states
+	CommandCellVoltages     Command = 0x95 // 3 cells per frame
+	CommandTemperatures     Command = 0x96 // 7 sensors per frame
+	CommandBalancingStatus  Command = 0x97 // one bit per cell
+	CommandErrors           Command = 0x98 // error flags, see ErrorCodes
+)
+
+// Device information
+const (
+	CommandBatteryCode     Command = 0x57 // 5 frames of text
+	CommandFirmwareVersion Command = 0x62 // 2 frames of text
+	CommandHardwareVersion Command = 0x63 // 2 frames of text
+)
+
+// Parameter reads, each has a write command ParameterWriteOffset lower
+const (
+	CommandRatedParams                    Command = 0x50
+	CommandBatteryConfig                  Command = 0x51
+	CommandCellVoltageThresholds          Command = 0x59
+	CommandPackVoltageThresholds          Command = 0x5a
+	CommandCurrentThresholds              Command = 0x5b
+	CommandChargeTemperatureThresholds    Command = 0x5c
+	CommandDischargeTemperatureThresholds Command = 0x5d
+	CommandDifferenceThresholds           Command = 0x5e
+	CommandBalanceSettings                Command = 0x5f
+)
+
+// Parameter writes
+const (
+	CommandSetRatedParams                    Command = 0x10
+	CommandSetBatteryConfig                  Command = 0x11
+	CommandSetCellVoltageThresholds          Command = 0x19
+	CommandSetPackVoltageThresholds          Command = 0x1a
+	CommandSetCurrentThresholds              Command = 0x1b
+	CommandSetChargeTemperatureThresholds    Command = 0x1c
+	CommandSetDischargeTemperatureThresholds Command = 0x1d
+	CommandSetDifferenceThresholds           Command = 0x1e
+	CommandSetBalanceSettings                Command = 0x1f
+)
+
+// Offset between a parameter write and its read, eg 0x10 + 0x40 = 0x50
+const ParameterWriteOffset = 0x40
+
+// Controls
+const (
+	CommandReset              Command = 0x00 // restart the board
+	CommandFactoryReset       Command = 0xd8 // restore the factory parameters
+	CommandSetSOC             Command = 0x21
+	CommandSetDischargeMosfet Command = 0xd9 // data byte 0: 1 = on, 0 = off
+	CommandSetChargeMosfet    Command = 0xda // data byte 0: 1 = on, 0 = off
+)
+
+var commandNames = map[Command]string{
+	CommandSOC:              "soc",
+	CommandCellVoltageRange: "cell_voltage_range",
+	CommandTemperatureRange: "temperature_range",
+	CommandMosfetStatus:     "mosfet_status",
+	CommandStatus:           "status",
+	CommandCellVoltages:     "cell_voltages",
+	CommandTemperatures:     "temperatures",
+	CommandBalancingStatus:  "balancing_status",
+	CommandErrors:           "errors",
+
+	CommandBatteryCode:     "battery_code",
+	CommandFirmwareVersion: "firmware_version",
+	CommandHardwareVersion: "hardware_version",
+
+	CommandRatedParams:                    "rated_params",
+	CommandBatteryConfig:                  "battery_config",
+	CommandCellVoltageThresholds:          "cell_voltage_thresholds",
+	CommandPackVoltageThresholds:          "pack_voltage_thresholds",
+	CommandCurrentThresholds:              "current_thresholds",
+	CommandChargeTemperatureThresholds:    "charge_temperature_thresholds",
+	CommandDischargeTemperatureThresholds: "discharge_temperature_thresholds",
+	CommandDifferenceThresholds:           "difference_thresholds",
+	CommandBalanceSettings:                "balance_settings",
+
+	CommandSetRatedParams:                    "set_rated_params",
+	CommandSetBatteryConfig:                  "set_battery_config",
+	CommandSetCellVoltageThresholds:          "set_cell_voltage_thresholds",
+	CommandSetPackVoltageThresholds:          "set_pack_voltage_thresholds",
+	CommandSetCurrentThresholds:              "set_current_thresholds",
+	CommandSetChargeTemperatureThresholds:    "set_charge_temperature_thresholds",
+	CommandSetDischargeTemperatureThresholds: "set_discharge_temperature_thresholds",
+	CommandSetDifferenceThresholds:           "set_difference_thresholds",
+	CommandSetBalanceSettings:                "set_balance_settings",
+
+	CommandReset:              "reset",
+	CommandFactoryReset:       "factory_reset",
+	CommandSetSOC:             "set_soc",
+	CommandSetDischargeMosfet: "set_discharge_mosfet",
+	CommandSetChargeMosfet:    "set_charge_mosfet",
+}
+
+// Commands lists the known command codes in ascending order
+func Commands() []Command {
+	return slices.Sorted(maps.Keys(commandNames))
+}
+
+// Name of the command, eg "soc", or its hex code when unknown
+func (command Command) String() string {
+	if name, ok := commandNames[command]; ok {
+		return name
+	}
+	return command.Hex()
+}
+
+// Hex code of the command, eg "90"
+func (command Command) Hex() string {
+	return fmt.Sprintf("%02x", byte(command))
+}
+
+// Checksum of the first 12 bytes of a frame, the low byte of their sum
+func Checksum(data []byte) byte {
+	var sum byte
+	for _, value := range data {
+		sum += value
+	}
+	return sum
+}
@@ -0,0 +1,112 @@
+// Package signalk sends BMS samples to a SignalK server as delta messages over WebSocket.
+package signalk
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// SignalK delta message, see https://signalk.org/specification/1.7.0/doc/data_model.html
+type Delta struct {
+	Context string   `json:"context"`
+	Updates []Update `json:"updates"`
+}
+
+type Update struct {
+	Source    Source  `json:"source"`
+	Timestamp string  `json:"timestamp"`
+	Values    []Value `json:"values"`
+}
+
+type Source struct {
+	Label string `json:"label"`
+}
+
+type Value struct {
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// NewDelta maps a sample to electrical.batteries.<batteryID>.* paths in SI units
+// (V, A, K, ratio 0-1, J). Current is positive when charging unless the client uses
+// WithCurrentSignConvention. Cells and sensors go to ...cells.<n>.voltage and
+// ...temperatures.<n>, values whose source data is missing are left out.
+func NewDelta(data *dalybms.AllStatusData, batteryID string, sourceLabel string, timestamp time.Time) *Delta {
+	prefix := "electrical.batteries." + batteryID + "."
+	var values []Value
+	add := func(path string, value any) {
+		values = append(values, Value{Path: prefix + path, Value: value})
+	}
+
+	if data.SOC != nil {
+		voltage := float32Value(data.SOC.TotalVoltage)
+		add("voltage", voltage)
+		current := data.SOC.ChargeCurrent()
+		add("current", current)
+		add("power", math.Round(current*voltage*10)/10)
+		add("capacity.stateOfCharge", math.Round(float32Value(data.SOC.SOCPercent)*10)/1000)
+		if data.MosfetStatus != nil {
+			remainingAh := float32Value(data.MosfetStatus.CapacityAh)
+			add("capacity.remaining", math.Round(remainingAh*voltage*3600))
+		}
+	}
+	if data.MosfetStatus != nil {
+		add("chargingEnabled", data.MosfetStatus.ChargingMosfet)
+		add("dischargingEnabled", data.MosfetStatus.DischargingMosfet)
+	}
+	if data.Status != nil {
+		add("lifetimeCycles", data.Status.CycleCount)
+	}
+	if data.TemperatureRange != nil {
+		add("temperature", kelvin(data.TemperatureRange.HighestCelsius()))
+	}
+	if data.CellVoltageRange != nil {
+		add("cellVoltageMax", float32Value(data.CellVoltageRange.HighestVoltage))
+		add("cellVoltageMin", float32Value(data.CellVoltageRange.LowestVoltage))
+	}
+
+	for _, cellIndex := range sortedKeys(data.CellVoltages) {
+		add("cells."+strconv.Itoa(cellIndex)+".voltage", data.CellVoltages[cellIndex])
+	}
+	temperatures := data.TemperaturesCelsius()
+	for _, sensorIndex := range sortedKeys(temperatures) {
+		add("temperatures."+strconv.Itoa(sensorIndex), kelvin(temperatures[sensorIndex]))
+	}
+
+	return &Delta{
+		Context: "vessels.self",
+		Updates: []Update{{
+			Source:    Source{Label: sourceLabel},
+			Timestamp: timestamp.UTC().Format("2006-01-02T15:04:05.000Z"),
+			Values:    values,
+		}},
+	}
+}
+
+func (delta *Delta) Marshal() ([]byte, error) {
+	return json.Marshal(delta)
+}
+
+func kelvin(celsius float64) float64 {
+	return math.Round((celsius+273.15)*100) / 100
+}
+
+func sortedKeys(values map[int]float64) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// float32Value converts a reading to float64 without float32 rounding noise
+func float32Value(value float32) float64 {
+	widened, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return widened
+}
@@ -0,0 +1,290 @@
+package signalk
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+)
+
+// Emitter settings
+type Config struct {
+	URL         string // stream endpoint, eg "ws://localhost:3000/signalk/v1/stream?subscribe=none"
+	Token       string // access token of a device with write permission, optional
+	BatteryID   string // default "daly", eg "house" for electrical.batteries.house.*
+	SourceLabel string // default "dalybms"
+
+	DialTimeout  time.Duration // default 10s
+	WriteTimeout time.Duration // default 10s
+	TLSConfig    *tls.Config   // for wss:// URLs, default system roots
+}
+
+// Emitter pushes every sample to a SignalK server as a delta message.
+// Feed it with poller.OnResult(emitter.Update). The connection is opened on the first
+// sample and reopened on the next sample after a failure.
+type Emitter struct {
+	config Config
+
+	mutex      sync.Mutex
+	connection net.Conn
+	closed     chan struct{} // closed by the reader when the server goes away
+	lastErr    error
+}
+
+func NewEmitter(config Config) *Emitter {
+	if config.BatteryID == "" {
+		config.BatteryID = "daly"
+	}
+	if config.SourceLabel == "" {
+		config.SourceLabel = "dalybms"
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	return &Emitter{config: config}
+}
+
+// Update sends a successful poll result, failed polls are skipped. Errors are available from Err().
+func (emitter *Emitter) Update(result dalybms.PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+	err := emitter.Send(NewDelta(result.Data, emitter.config.BatteryID, emitter.config.SourceLabel, result.Time))
+
+	emitter.mutex.Lock()
+	emitter.lastErr = err
+	emitter.mutex.Unlock()
+}
+
+// Latest send error, nil if the latest send succeeded
+func (emitter *Emitter) Err() error {
+	emitter.mutex.Lock()
+	defer emitter.mutex.Unlock()
+	return emitter.lastErr
+}
+
+// Send writes a delta, connecting first if needed
+func (emitter *Emitter) Send(delta *Delta) error {
+	message, err := delta.Marshal()
+	if err != nil {
+		return err
+	}
+
+	emitter.mutex.Lock()
+	defer emitter.mutex.Unlock()
+
+	if emitter.connection != nil {
+		select {
+		case <-emitter.closed:
+			emitter.disconnect()
+		default:
+		}
+	}
+	if emitter.connection == nil {
+		if err := emitter.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := emitter.writeFrame(opcodeText, message); err != nil {
+		emitter.disconnect()
+		return fmt.Errorf("signalk send failed: %w", err)
+	}
+	return nil
+}
+
+// Close sends a close frame and closes the connection
+func (emitter *Emitter) Close() error {
+	emitter.mutex.Lock()
+	defer emitter.mutex.Unlock()
+
+	if emitter.connection == nil {
+		return nil
+	}
+	emitter.writeFrame(opcodeClose, nil)
+	return emitter.disconnect()
+}
+
+// disconnect must be called with mutex held
+func (emitter *Emitter) disconnect() error {
+	err := emitter.connection.Close()
+	emitter.connection = nil
+	return err
+}
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opcodeText  = 0x1
+	opcodeClose = 0x8
+	opcodePing  = 0x9
+	opcodePong  = 0xa
+)
+
+// connect dials the server and performs the RFC 6455 handshake. Must be called with mutex held.
+func (emitter *Emitter) connect() error {
+	endpoint, err := url.Parse(emitter.config.URL)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: emitter.config.DialTimeout}
+	var connection net.Conn
+	switch endpoint.Scheme {
+	case "ws":
+		connection, err = dialer.Dial("tcp", hostPort(endpoint, "80"))
+	case "wss":
+		tlsConfig := emitter.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: endpoint.Hostname()}
+		}
+		connection, err = tls.DialWithDialer(dialer, "tcp", hostPort(endpoint, "443"), tlsConfig)
+	default:
+		return fmt.Errorf("unsupported SignalK URL scheme %q, use ws:// or wss://", endpoint.Scheme)
+	}
+	if err != nil {
+		return fmt.Errorf("signalk connection failed: %w", err)
+	}
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	request := "GET " + endpoint.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + endpoint.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	if emitter.config.Token != "" {
+		request += "Authorization: Bearer " + emitter.config.Token + "\r\n"
+	}
+	request += "\r\n"
+
+	connection.SetDeadline(time.Now().Add(emitter.config.DialTimeout))
+	if _, err := connection.Write([]byte(request)); err != nil {
+		connection.Close()
+		return fmt.Errorf("signalk handshake failed: %w", err)
+	}
+	reader := bufio.NewReader(connection)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		connection.Close()
+		return fmt.Errorf("signalk handshake failed: %w", err)
+	}
+	response.Body.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	if response.StatusCode != http.StatusSwitchingProtocols ||
+		response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		connection.Close()
+		return fmt.Errorf("signalk handshake failed: server responded %s", response.Status)
+	}
+	connection.SetDeadline(time.Time{})
+
+	emitter.connection = connection
+	emitter.closed = make(chan struct{})
+	go emitter.readFrames(connection, reader, emitter.closed)
+	return nil
+}
+
+// readFrames discards server messages (hello, deltas), answers pings and
+// closes closed when the connection ends
+func (emitter *Emitter) readFrames(connection net.Conn, reader *bufio.Reader, closed chan struct{}) {
+	defer close(closed)
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		opcode := header[0] & 0x0f
+		length := uint64(header[1] & 0x7f)
+
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+
+		switch opcode {
+		case opcodePing:
+			if length > 125 {
+				return // control frames are limited to 125 bytes
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			emitter.mutex.Lock()
+			if emitter.connection == connection {
+				emitter.writeFrame(opcodePong, payload)
+			}
+			emitter.mutex.Unlock()
+		case opcodeClose:
+			return
+		default:
+			if _, err := io.CopyN(io.Discard, reader, int64(length)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeFrame writes a single masked frame, as required from clients. Must be called with mutex held.
+func (emitter *Emitter) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame := append(header, mask[:]...)
+	for index, value := range payload {
+		frame = append(frame, value^mask[index%4])
+	}
+
+	emitter.connection.SetWriteDeadline(time.Now().Add(emitter.config.WriteTimeout))
+	_, err := emitter.connection.Write(frame)
+	return err
+}
+
+func hostPort(endpoint *url.URL, defaultPort string) string {
+	if endpoint.Port() != "" {
+		return endpoint.Host
+	}
+	return net.JoinHostPort(endpoint.Hostname(), defaultPort)
+}
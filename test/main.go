@@ -10,65 +10,46 @@ import (
 const SAMPLE_INTERVAL = 5
 const BMS_PORT = "/dev/ttyUSB0"
 
-var bmsClient *bms.DalyBMSIstance
-
 func main() {
 	fmt.Println("Starting...")
 
-	for {
-		if bmsClient != nil {
-			err := bmsClient.Disconnect()
-			if err != nil {
-				fmt.Println("Error disconnecting from BMS: ", err)
-			}
-		}
-		bmsClient = bms.DalyBMS()
-		err := bmsClient.Connect(BMS_PORT)
-		if err != nil {
-			fmt.Printf("Error connecting to BMS: %v", err)
-			time.Sleep(1 * time.Second)
-			continue
-		}
-		defer bmsClient.Disconnect()
-
-		for {
-			data, err := bmsClient.GetAllData()
-			if err != nil {
-				fmt.Println("Error getting data: ", err)
-				break
-			} else {
-				fmt.Println("Balancing status: ", data.BalancingStatus)
-				fmt.Println("Highest cell: ", data.CellVoltageRange.HighestCell)
-				fmt.Println("Lowest cell: ", data.CellVoltageRange.LowestCell)
-				fmt.Println("Highest voltage: ", data.CellVoltageRange.HighestVoltage)
-				fmt.Println("Lowest voltage: ", data.CellVoltageRange.LowestVoltage)
-				fmt.Println("Cell voltages: ", data.CellVoltages)
-				fmt.Println("Errors: ", data.Errors)
-				fmt.Println("Capacity Ah: ", data.MosfetStatus.CapacityAh)
-				fmt.Println("Charging mosfet: ", data.MosfetStatus.ChargingMosfet)
-				fmt.Println("Discharging mosfet: ", data.MosfetStatus.DischargingMosfet)
-				fmt.Println("Mode: ", data.MosfetStatus.Mode)
-				fmt.Println("Current: ", data.SOC.Current)
-				fmt.Println("SOC percent: ", data.SOC.SOCPercent)
-				fmt.Println("Total voltage: ", data.SOC.TotalVoltage)
-				fmt.Println("Cycle count: ", data.Status.CycleCount)
-				fmt.Println("Is charger running: ", data.Status.IsChargerRunning)
-				fmt.Println("Is load running: ", data.Status.IsLoadRunning)
-				fmt.Println("Number of cells: ", data.Status.NumberOfCells)
-				fmt.Println("Number of temperature sensors: ", data.Status.NumberOfTemperatureSensors)
-				fmt.Println("States: ", data.Status.States)
-				fmt.Println("Highest sensor: ", data.TemperatureRange.HighestSensor)
-				fmt.Println("Lowest sensor: ", data.TemperatureRange.LowestSensor)
-				fmt.Println("Highest temperature: ", data.TemperatureRange.HighestTemperature)
-				fmt.Println("Lowest temperature: ", data.TemperatureRange.LowestTemperature)
-			}
+	bmsClient := bms.NewClient()
+	poller := bms.NewPoller(bmsClient, bms.SerialConnector(BMS_PORT), SAMPLE_INTERVAL*time.Second)
+	results := poller.Subscribe(1)
+	poller.Start()
+	defer poller.Stop()
 
-			// delay before next sample
-			time.Sleep(SAMPLE_INTERVAL * time.Second)
+	for result := range results {
+		if result.Err != nil {
+			fmt.Println("Error getting data: ", result.Err)
+			continue
 		}
 
-		// delay before reconnecting
-		time.Sleep(1 * time.Second)
+		data := result.Data
+		fmt.Println("Balancing status: ", data.BalancingStatus)
+		fmt.Println("Highest cell: ", data.CellVoltageRange.HighestCell, data.CellVoltageRange.HighestCellLabel)
+		fmt.Println("Lowest cell: ", data.CellVoltageRange.LowestCell, data.CellVoltageRange.LowestCellLabel)
+		fmt.Println("Highest voltage: ", data.CellVoltageRange.HighestVoltage)
+		fmt.Println("Lowest voltage: ", data.CellVoltageRange.LowestVoltage)
+		fmt.Println("Cell voltages: ", data.CellVoltages)
+		fmt.Println("Errors: ", data.Errors)
+		fmt.Println("Capacity Ah: ", data.MosfetStatus.CapacityAh)
+		fmt.Println("Charging mosfet: ", data.MosfetStatus.ChargingMosfet)
+		fmt.Println("Discharging mosfet: ", data.MosfetStatus.DischargingMosfet)
+		fmt.Println("Mode: ", data.MosfetStatus.Mode)
+		fmt.Println("Current: ", data.SOC.Current)
+		fmt.Println("SOC percent: ", data.SOC.SOCPercent)
+		fmt.Println("Total voltage: ", data.SOC.TotalVoltage)
+		fmt.Println("Cycle count: ", data.Status.CycleCount)
+		fmt.Println("Is charger running: ", data.Status.IsChargerRunning)
+		fmt.Println("Is load running: ", data.Status.IsLoadRunning)
+		fmt.Println("Number of cells: ", data.Status.NumberOfCells)
+		fmt.Println("Number of temperature sensors: ", data.Status.NumberOfTemperatureSensors)
+		fmt.Println("States: ", data.Status.States)
+		fmt.Println("Highest sensor: ", data.TemperatureRange.HighestSensor)
+		fmt.Println("Lowest sensor: ", data.TemperatureRange.LowestSensor)
+		fmt.Println("Highest temperature: ", data.TemperatureRange.HighestTemperature)
+		fmt.Println("Lowest temperature: ", data.TemperatureRange.LowestTemperature)
 	}
 }
 
@@ -76,8 +57,8 @@ func main() {
 	Output example:
 	Starting...
 	Balancing status:  map[1:false 2:false 3:false 4:false]
-	Highest cell:  3
-	Lowest cell:  1
+	Highest cell:  3 cell 3
+	Lowest cell:  1 cell 1
 	Highest voltage:  3.279
 	Lowest voltage:  3.255
 	Cell voltages:  map[1:3.255 2:3.279 3:3.279 4:3.259]
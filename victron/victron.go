@@ -0,0 +1,245 @@
+// Package victron publishes BMS data on the CAN bus in the BMS protocol understood by
+// Victron GX devices (Venus OS), like dbus-serialbattery does for its CAN output.
+package victron
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	dalybms "github.com/jonamat/go-daly-bms"
+	"github.com/jonamat/go-daly-bms/actuator"
+)
+
+// CAN identifiers of the frames sent by Publish
+const (
+	FrameLimits       = 0x351 // charge voltage, CCL, DCL, discharge voltage
+	FrameSOC          = 0x355 // SOC, SOH
+	FrameMeasurements = 0x356 // voltage, current, temperature
+	FrameAlarms       = 0x35A // alarm and warning flags
+	FrameRequests     = 0x35C // charge/discharge enable
+	FrameManufacturer = 0x35E // manufacturer name
+	FrameCellExtremes = 0x373 // lowest/highest cell voltage and temperature
+)
+
+// Publisher settings
+type Config struct {
+	Limits        actuator.LimitsFunc // CVL/CCL/DCL, eg actuator.DefaultLimits(...)
+	Manufacturer  string              // up to 8 ASCII chars, default "DALY"
+	StateOfHealth uint16              // %, the BMS does not report it. Default 100
+}
+
+// Publisher sends a full set of frames for every sample. Venus OS expects an update at least
+// every few seconds, so poll at 1-5s: poller.OnResult(publisher.Update).
+type Publisher struct {
+	sender  actuator.CANSender
+	config  Config
+	lastErr error
+}
+
+// NewPublisher creates a publisher sending through sender, eg a SocketCAN socket
+func NewPublisher(sender actuator.CANSender, config Config) *Publisher {
+	if config.Manufacturer == "" {
+		config.Manufacturer = "DALY"
+	}
+	if config.StateOfHealth == 0 {
+		config.StateOfHealth = 100
+	}
+	return &Publisher{sender: sender, config: config}
+}
+
+// Update publishes a successful poll result, failed polls are skipped so the GX device times out
+// instead of acting on stale data. Errors are available from Err().
+func (publisher *Publisher) Update(result dalybms.PollResult) {
+	if result.Err != nil || result.Data == nil {
+		return
+	}
+	publisher.lastErr = publisher.Publish(result.Data)
+}
+
+// Latest publish error, nil if the latest publish succeeded
+func (publisher *Publisher) Err() error {
+	return publisher.lastErr
+}
+
+// Publish sends all frames for a sample
+func (publisher *Publisher) Publish(data *dalybms.AllStatusData) error {
+	for _, frame := range publisher.Frames(data) {
+		if err := publisher.sender.SendFrame(frame.ID, frame.Data); err != nil {
+			return fmt.Errorf("failed to send CAN frame %03x: %w", frame.ID, err)
+		}
+	}
+	return nil
+}
+
+// CAN frame as sent to the CANSender
+type Frame struct {
+	ID   uint32
+	Data []byte
+}
+
+// Frames encodes a sample, frames whose source data is missing are left out
+func (publisher *Publisher) Frames(data *dalybms.AllStatusData) []Frame {
+	var frames []Frame
+
+	if publisher.config.Limits != nil {
+		limits := publisher.config.Limits(data)
+		frames = append(frames, Frame{FrameLimits, littleEndian(
+			uint16(math.Round(dalybms.Widen(limits.ChargeVoltage)*10)),
+			uint16(int16(math.Round(dalybms.Widen(limits.ChargeCurrentLimit)*10))),
+			uint16(int16(math.Round(dalybms.Widen(limits.DischargeCurrentLimit)*10))),
+			uint16(math.Round(dalybms.Widen(limits.DischargeVoltage)*10)),
+		)})
+	}
+
+	if data.SOC != nil {
+		frames = append(frames, Frame{FrameSOC, littleEndian(
+			uint16(math.Round(dalybms.Widen(data.SOC.SOCPercent))),
+			publisher.config.StateOfHealth,
+		)})
+
+		temperature := 0.0
+		if data.TemperatureRange != nil {
+			temperature = data.TemperatureRange.HighestCelsius()
+		}
+		frames = append(frames, Frame{FrameMeasurements, littleEndian(
+			uint16(int16(math.Round(dalybms.Widen(data.SOC.TotalVoltage)*100))),
+			uint16(int16(math.Round(data.SOC.ChargeCurrent()*10))),
+			uint16(int16(math.Round(temperature*10))),
+		)})
+	}
+
+	if data.Errors != nil {
+		frames = append(frames, Frame{FrameAlarms, alarmFlags(data.Errors)})
+	}
+
+	if data.MosfetStatus != nil {
+		var requests byte
+		if data.MosfetStatus.ChargingMosfet {
+			requests |= 0x80
+		}
+		if data.MosfetStatus.DischargingMosfet {
+			requests |= 0x40
+		}
+		frames = append(frames, Frame{FrameRequests, []byte{requests, 0}})
+	}
+
+	manufacturer := make([]byte, 8)
+	copy(manufacturer, publisher.config.Manufacturer)
+	frames = append(frames, Frame{FrameManufacturer, manufacturer})
+
+	if data.CellVoltageRange != nil && data.TemperatureRange != nil {
+		frames = append(frames, Frame{FrameCellExtremes, littleEndian(
+			uint16(math.Round(dalybms.Widen(data.CellVoltageRange.LowestVoltage)*1000)),
+			uint16(math.Round(dalybms.Widen(data.CellVoltageRange.HighestVoltage)*1000)),
+			uint16(math.Round(data.TemperatureRange.LowestCelsius()+273.15)),
+			uint16(math.Round(data.TemperatureRange.HighestCelsius()+273.15)),
+		)})
+	}
+
+	return frames
+}
+
+// Positions of the 2-bit flags in 0x35A. Alarms use bytes 0-3, warnings the same positions in bytes 4-7.
+// Each flag is 01 when active and 10 when ok.
+const (
+	flagGeneral = iota
+	flagHighVoltage
+	flagLowVoltage
+	flagHighTemperature
+	flagLowTemperature
+	flagHighChargeTemperature
+	flagLowChargeTemperature
+	flagHighDischargeCurrent
+	flagHighChargeCurrent
+	flagInternalFailure = 11
+	flagCellImbalance   = 12
+)
+
+var mappedFlags = []int{
+	flagGeneral, flagHighVoltage, flagLowVoltage, flagHighTemperature, flagLowTemperature,
+	flagHighChargeTemperature, flagLowChargeTemperature, flagHighDischargeCurrent,
+	flagHighChargeCurrent, flagInternalFailure, flagCellImbalance,
+}
+
+// alarmFlags maps the Daly error texts to the 0x35A flags. Level two errors and failures are
+// alarms, level one errors are warnings.
+func alarmFlags(errors dalybms.BMSErrors) []byte {
+	alarms := make(map[int]bool)
+	warnings := make(map[int]bool)
+	for _, bmsError := range errors {
+		flag, ok := classify(englishMessage(bmsError))
+		if !ok {
+			continue
+		}
+		if bmsError.Severity == dalybms.SeverityAlarm {
+			alarms[flag] = true
+			alarms[flagGeneral] = true
+		} else {
+			warnings[flag] = true
+			warnings[flagGeneral] = true
+		}
+	}
+
+	data := make([]byte, 8)
+	for _, flag := range mappedFlags {
+		setFlag(data[0:4], flag, alarms[flag])
+		setFlag(data[4:8], flag, warnings[flag])
+	}
+	return data
+}
+
+func setFlag(flags []byte, flag int, active bool) {
+	value := byte(0b10)
+	if active {
+		value = 0b01
+	}
+	flags[flag/4] |= value << (2 * (flag % 4))
+}
+
+// englishMessage returns the text of DalyErrorCodes, Message may be translated
+func englishMessage(bmsError dalybms.BMSError) string {
+	if messages, ok := dalybms.DalyErrorCodes[bmsError.Byte]; ok && bmsError.Bit < len(messages) {
+		return messages[bmsError.Bit]
+	}
+	return bmsError.Message
+}
+
+// classify returns the flag of a Daly error text
+func classify(errorText string) (int, bool) {
+	text := strings.ToLower(errorText)
+
+	switch {
+	case strings.Contains(text, "charging temperature too high"):
+		return flagHighChargeTemperature, true
+	case strings.Contains(text, "charging temperature too low"):
+		return flagLowChargeTemperature, true
+	case strings.Contains(text, "discharge temperature is too high"), strings.Contains(text, "overtemperature"):
+		return flagHighTemperature, true
+	case strings.Contains(text, "discharge temperature is too low"):
+		return flagLowTemperature, true
+	case strings.Contains(text, "over voltage"), strings.Contains(text, "voltage is too high"):
+		return flagHighVoltage, true
+	case strings.Contains(text, "voltage is too low"), strings.Contains(text, "low voltage"):
+		return flagLowVoltage, true
+	case strings.Contains(text, "discharge over current"), strings.Contains(text, "discharge overcurrent"):
+		return flagHighDischargeCurrent, true
+	case strings.Contains(text, "charge over current"):
+		return flagHighChargeCurrent, true
+	case strings.Contains(text, "differential pressure"):
+		return flagCellImbalance, true
+	case strings.Contains(text, "failure"), strings.Contains(text, "malfunction"), strings.Contains(text, "fault"),
+		strings.Contains(text, "drop off"):
+		return flagInternalFailure, true
+	}
+	return 0, false
+}
+
+func littleEndian(values ...uint16) []byte {
+	data := make([]byte, 0, len(values)*2)
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint16(data, value)
+	}
+	return data
+}